@@ -4,14 +4,52 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
+	"time"
 
+	"pre-commit-hooks/internal/cliargs"
+	"pre-commit-hooks/internal/config"
+	"pre-commit-hooks/internal/hookutil"
 	"pre-commit-hooks/internal/output"
+	"pre-commit-hooks/internal/runner"
+	"pre-commit-hooks/internal/tofubin"
 	tofufmt "pre-commit-hooks/internal/tofufmt"
+	"pre-commit-hooks/internal/tofuversion"
+)
+
+// hookFlags lists the flags consumed by the hook itself rather than forwarded
+// to tofu fmt, and whether each takes a value.
+var hookFlags = map[string]bool{
+	"check-only":         false,
+	"fail-on-fix":        false,
+	"recursive":          false,
+	"report-json":        true,
+	"report-sarif":       true,
+	"binary":             true,
+	"github-annotations": false,
+	"tofu-version":       true,
+	"timeout":            true,
+	"exclude":            true,
+}
+
+// Environment variables that select a mode when set to a true or false value,
+// for CI environments where changing the hook args is inconvenient. Explicit
+// flags take precedence.
+const (
+	checkOnlyEnv = "TOFU_FMT_CHECK_ONLY"
+	failOnFixEnv = "TOFU_FMT_FAIL_ON_FIX"
 )
 
 func main() {
-	err := RunTofuFmtCLI(
+	runner.HandleInterrupts()
+	hook, err := loadConfig()
+	if err != nil {
+		output.PrintStatus(output.Error, err.Error(), output.Red)
+		os.Exit(1)
+	}
+	err = RunTofuFmtCLI(
 		os.Args[1:],
+		hook,
 		os.Getwd,
 		tofufmt.RunTofuFmt,
 		tofufmt.FormatFiles,
@@ -22,47 +60,374 @@ func main() {
 }
 
 // RunTofuFmtCLI runs the tofu fmt CLI logic. Returns error if any step fails.
+//
+// Filenames in args (as passed by pre-commit) are grouped by directory and only
+// those files are checked and formatted. With -recursive the whole working
+// directory tree is processed instead. With -check-only (or TOFU_FMT_CHECK_ONLY
+// set to a true value) unformatted files are reported and the command fails
+// without rewriting anything. Otherwise the files are rewritten and, like
+// pre-commit's own fixers, the command fails so the changes can be reviewed and
+// staged; -fail-on-fix=false (or TOFU_FMT_FAIL_ON_FIX=false) makes a successful
+// fix exit zero instead. -report-json PATH writes a machine-readable report of
+// every file checked and -report-sarif PATH writes each unformatted hunk as a
+// SARIF result. With -github-annotations (the default under GitHub Actions)
+// each directory's diff is wrapped in a log group and every unformatted file
+// is annotated. -binary NAME (or TOFU_BINARY) runs terraform or another
+// binary instead of auto-detecting tofu, then terraform. The binary's version
+// must satisfy -tofu-version and the required_version of the formatted
+// directories. -timeout DURATION stops each tofu fmt run that takes longer.
+// All other flags are forwarded to tofu fmt.
+//
+// hook holds the fmt settings of the repository configuration file. Its flags
+// and args come before args, so the command line wins; its include and skip
+// globs and the -exclude globs, its exclude settings included, filter the
+// staged files, and its per-directory args are added when formatting each
+// directory.
 func RunTofuFmtCLI(
-	extraArgs []string,
+	args []string,
+	hook config.Hook,
 	getwd func() (string, error),
-	runTofuFmt func(string, []string) (string, error),
-	formatFiles func(string, []string) error,
-) error {
+	runTofuFmt func(string, []string, []string) (string, error),
+	formatFiles func(string, []string, []string) ([]string, error),
+) (err error) {
+	parsed := cliargs.Parse(hook.Merge(args), hookFlags, nil)
+	bin := tofubin.Resolve(parsed.String("binary", ""))
+	tofubin.Set(bin)
+	timeouts, err := runner.ParseTimeouts(parsed.List(runner.Flag), "fmt")
+	if err != nil {
+		output.PrintStatus(output.Error, err.Error(), output.Red)
+		return err
+	}
+	runner.SetTimeouts(timeouts)
 	if !tofufmt.CheckOpenTofuInstalled() {
-		fmt.Println("OpenTofu is not installed or not in PATH.")
-		return fmt.Errorf("OpenTofu not installed")
+		output.PrintStatus(output.Error, fmt.Sprintf("%s is not installed or not in PATH.", bin.DisplayName()), output.Red)
+		return fmt.Errorf("%s not installed", bin.DisplayName())
 	}
 	wd, err := getwd()
 	if err != nil {
-		fmt.Println("Error getting current directory:", err)
+		output.PrintStatus(output.Error, fmt.Sprintf("Error getting current directory: %v", err), output.Red)
 		return err
 	}
+	extraArgs := parsed.Extra
 	baseDir := filepath.Base(wd)
-	printStatus(output.Running, fmt.Sprintf("Running tofu fmt recursively in: %s", baseDir))
 
-	outputStr, err := runTofuFmt(wd, extraArgs)
+	var results []output.Result
+	if reportPath := parsed.String("report-json", ""); reportPath != "" {
+		defer func() {
+			if reportErr := output.WriteJSONReport(reportPath, output.NewReport("tofufmt", results)); reportErr != nil {
+				output.PrintStatus(output.Error, fmt.Sprintf("Could not write JSON report: %v", reportErr), output.Red)
+				if err == nil {
+					err = reportErr
+				}
+			}
+		}()
+	}
+	annotate := parsed.BoolEnv("github-annotations", output.GitHubActionsEnv, false)
+	if annotate {
+		defer func() { output.PrintAnnotations(fileAnnotations(results)) }()
+	}
+	if sarifPath := parsed.String("report-sarif", ""); sarifPath != "" {
+		defer func() {
+			if reportErr := output.WriteSARIF(sarifPath, output.NewSARIFLog("tofufmt", results)); reportErr != nil {
+				output.PrintStatus(output.Error, fmt.Sprintf("Could not write SARIF report: %v", reportErr), output.Red)
+				if err == nil {
+					err = reportErr
+				}
+			}
+		}()
+	}
+
+	var groups []tofufmt.FileGroup
+	if parsed.Bool("recursive") {
+		groups = []tofufmt.FileGroup{{Dir: "."}}
+		printStatus(output.Running, fmt.Sprintf("Running %s fmt recursively in: %s", bin.Name, baseDir))
+	} else {
+		// The exclude settings reach the hook as -exclude flags, like those on the command line
+		hook.Exclude = parsed.List("exclude")
+		// Files below the working directory are matched and grouped by their
+		// relative path, whether they were given relative or absolute
+		files := hookutil.AbsPaths(wd, parsed.Files)
+		for i, file := range files {
+			files[i] = hookutil.RelPath(wd, file)
+		}
+		groups = tofufmt.GroupFilesByDir(configuredFiles(hook, supportedFiles(bin, files)))
+		if len(groups) == 0 {
+			printStatus(output.ThumbsUp, "No OpenTofu files to check.")
+			fmt.Println()
+			return nil
+		}
+		printStatus(output.Running, fmt.Sprintf("Running %s fmt on %d file(s) in: %s", bin.Name, countFiles(groups), baseDir))
+	}
+
+	groupDirs := make([]string, len(groups))
+	for i, group := range groups {
+		groupDirs[i] = group.Dir
+	}
+	dirs := hookutil.AbsPaths(wd, groupDirs)
+	if err := tofuversion.Check(parsed.String(tofuversion.Flag, ""), dirs); err != nil {
+		output.PrintStatus(output.Error, err.Error(), output.Red)
+		return err
+	}
+
+	var unformatted []int // indexes into groups
+	var diffs []tofufmt.FileDiff
+	diffResults := map[string]int{} // diff path -> index into results
+	for i, group := range groups {
+		start := time.Now()
+		outputStr, err := runTofuFmt(dirs[i], group.Files, hook.ArgsFor(group.Dir, extraArgs))
+		elapsed := time.Since(start)
+		if err == nil {
+			results = append(results, groupResults(group, output.StatusPassed, elapsed)...)
+		} else {
+			if len(unformatted) == 0 {
+				fmt.Println()
+				output.PrintStatus(output.Warning, "Found unformatted OpenTofu files:", output.Yellow)
+			}
+			unformatted = append(unformatted, i)
+			if annotate {
+				fmt.Println(output.GroupStart(filepath.ToSlash(group.Dir)))
+			}
+			groupDiffs := tofufmt.ParseDiff(outputStr)
+			if len(groupDiffs) == 0 {
+				// Not a diff, e.g. a syntax error; show tofu's output as is
+				fmt.Println(outputStr)
+				limit, timedOut := runner.TimedOut(err)
+				if timedOut {
+					output.PrintStatus(output.Error, fmt.Sprintf("%s fmt timed out after %s in: %s", bin.Name, limit, filepath.ToSlash(group.Dir)), output.Red)
+				}
+				if annotate {
+					fmt.Println(output.GroupEnd)
+				}
+				for _, result := range groupResults(group, output.StatusFailed, elapsed) {
+					result.Output = outputStr
+					if timedOut {
+						result.Timeout = limit.String()
+					}
+					results = append(results, result)
+				}
+				continue
+			}
+			for _, diff := range groupDiffs {
+				diff.Path = filepath.Join(group.Dir, diff.Path)
+				printDiff(diff)
+				diffs = append(diffs, diff)
+				diffResults[diff.Path] = len(results)
+				results = append(results, output.Result{
+					TofuMessage: output.TofuMessage{Step: "fmt", RelPath: filepath.ToSlash(diff.Path), Diagnostics: diff.Diagnostics(output.SeverityError)},
+					Status:      output.StatusFailed,
+					Duration:    elapsed,
+				})
+			}
+			if annotate {
+				fmt.Println(output.GroupEnd)
+			}
+		}
+	}
 	fmt.Println()
-	if err != nil {
-		fmt.Println(output.EmojiColorText(output.Warning, "Found unformatted OpenTofu files:", output.Yellow))
-		fmt.Println(outputStr)
-		printStatus(output.Running, "Formatting files with tofu fmt...")
-		fmtErr := formatFiles(wd, extraArgs)
+
+	if len(unformatted) == 0 {
+		printStatus(output.ThumbsUp, "All OpenTofu files are formatted.")
 		fmt.Println()
+		return nil
+	}
+	if len(diffs) > 0 {
+		added, removed := 0, 0
+		for _, diff := range diffs {
+			added += diff.Added()
+			removed += diff.Removed()
+		}
+		fmt.Printf("%d file(s) need formatting, %d line(s) added and %d removed.\n\n", len(diffs), added, removed)
+	}
+
+	if parsed.BoolEnv("check-only", checkOnlyEnv, false) {
+		output.PrintStatus(output.Error, "Unformatted OpenTofu files (check-only mode, nothing was changed):", output.Red)
+		for _, diff := range diffs {
+			fmt.Printf("    %s (+%d/-%d)\n", filepath.ToSlash(diff.Path), diff.Added(), diff.Removed())
+		}
+		fmt.Println()
+		return fmt.Errorf("found unformatted files")
+	}
+
+	printStatus(output.Running, fmt.Sprintf("Formatting files with %s fmt...", bin.Name))
+	var rewritten []tofufmt.FileGroup
+	for _, i := range unformatted {
+		group := groups[i]
+		files, fmtErr := formatFiles(dirs[i], group.Files, hook.ArgsFor(group.Dir, extraArgs))
+		if len(files) > 0 {
+			rewritten = append(rewritten, tofufmt.FileGroup{Dir: group.Dir, Files: files})
+		}
+		for _, file := range files {
+			if i, ok := diffResults[filepath.Join(group.Dir, file)]; ok {
+				results[i].Status = output.StatusFixed
+				for d := range results[i].Diagnostics {
+					results[i].Diagnostics[d].Severity = output.SeverityWarning
+				}
+			}
+		}
 		if fmtErr != nil {
-			fmt.Println(output.EmojiColorText(output.Error, "Error running tofu fmt:", output.Red))
+			printRewritten(rewritten)
+			fmt.Println()
+			output.PrintStatus(output.Error, "Error running tofu fmt:", output.Red)
 			fmt.Println(fmtErr)
 			return fmtErr
 		}
-		printStatus(output.ThumbsUp, "Files formatted successfully with tofu fmt.")
-		fmt.Println()
-	} else {
-		printStatus(output.ThumbsUp, "All OpenTofu files are formatted.")
+	}
+	printRewritten(rewritten)
+	fmt.Println()
+
+	if parsed.BoolEnv("fail-on-fix", failOnFixEnv, true) {
+		output.PrintStatus(output.Warning, "Files were rewritten by tofu fmt; review and stage the changes.", output.Yellow)
 		fmt.Println()
+		return fmt.Errorf("files were reformatted")
 	}
+	printStatus(output.ThumbsUp, "Files formatted successfully with tofu fmt.")
+	fmt.Println()
 	return nil
 }
 
+// supportedFiles drops the files the binary cannot format, such as .tofu files
+// under terraform, printing how many were skipped
+func supportedFiles(bin tofubin.Binary, files []string) []string {
+	var supported []string
+	for _, file := range files {
+		if bin.Supports(file) {
+			supported = append(supported, file)
+		}
+	}
+	if skipped := len(files) - len(supported); skipped > 0 {
+		printStatus(output.Warning, fmt.Sprintf("Skipping %d file(s) not supported by %s.", skipped, bin.Name))
+	}
+	return supported
+}
+
+// configuredFiles drops the files outside the include globs, the excluded
+// files and those listed to skip, printing how many were excluded or skipped
+func configuredFiles(hook config.Hook, files []string) []string {
+	var selected []string
+	excluded, skipped := 0, 0
+	for _, file := range files {
+		name := filepath.ToSlash(file)
+		switch {
+		case !(config.Hook{Include: hook.Include}).Selected(name):
+		case !hook.Selected(name):
+			excluded++
+		case hook.Skipped(name):
+			skipped++
+		default:
+			selected = append(selected, file)
+		}
+	}
+	if excluded > 0 {
+		printStatus(output.Warning, fmt.Sprintf("Skipping %d file(s) matching -exclude.", excluded))
+	}
+	if skipped > 0 {
+		printStatus(output.Warning, fmt.Sprintf("Skipping %d file(s) listed in the skip settings.", skipped))
+	}
+	return selected
+}
+
+// loadConfig loads the fmt settings of the configuration file in the working
+// directory, the repository root under pre-commit
+func loadConfig() (config.Hook, error) {
+	wd, err := os.Getwd()
+	if err != nil {
+		return config.Hook{}, err
+	}
+	return config.LoadHook(wd, "fmt")
+}
+
+// fileAnnotations returns one GitHub Actions annotation per unformatted file,
+// at the first line tofu fmt changes: an error when the file was left
+// unformatted and a warning when it was rewritten
+func fileAnnotations(results []output.Result) []output.Annotation {
+	var annotations []output.Annotation
+	for _, result := range results {
+		annotation := output.Annotation{Level: "error", File: result.RelPath, Title: "File is not formatted"}
+		switch {
+		case result.Status == output.StatusFixed:
+			annotation.Level = "warning"
+			annotation.Title = "File was reformatted"
+			annotation.Message = fmt.Sprintf("tofu fmt rewrote %d hunk(s) in this file; review and stage the changes.", len(result.Diagnostics))
+		case result.Status == output.StatusFailed && len(result.Diagnostics) > 0:
+			annotation.Message = fmt.Sprintf("tofu fmt would change %d hunk(s) in this file.", len(result.Diagnostics))
+		case result.Status == output.StatusFailed:
+			annotation.Title = "tofu fmt failed"
+			annotation.Message = strings.TrimSpace(result.Output)
+		default:
+			continue
+		}
+		if len(result.Diagnostics) > 0 && result.Diagnostics[0].Range != nil {
+			annotation.Line = result.Diagnostics[0].Range.Start.Line
+		}
+		annotations = append(annotations, annotation)
+	}
+	return annotations
+}
+
+// printDiff prints a file's hunks with added lines in green and removed lines in red
+func printDiff(diff tofufmt.FileDiff) {
+	fmt.Printf("    %s\n", filepath.ToSlash(diff.Path))
+	for _, hunk := range diff.Hunks {
+		fmt.Printf("    @@ -%d,%d +%d,%d @@\n", hunk.OldStart, hunk.OldLines, hunk.NewStart, hunk.NewLines)
+		for _, line := range hunk.Lines {
+			switch line.Kind {
+			case tofufmt.Added:
+				fmt.Printf("    %s\n", output.Paint("+"+line.Text, output.Green))
+			case tofufmt.Removed:
+				fmt.Printf("    %s\n", output.Paint("-"+line.Text, output.Red))
+			default:
+				fmt.Printf("     %s\n", line.Text)
+			}
+		}
+	}
+}
+
+// printRewritten prints the rewritten files grouped by directory
+func printRewritten(groups []tofufmt.FileGroup) {
+	if len(groups) == 0 {
+		return
+	}
+	fmt.Println()
+	output.PrintStatus(output.Running, "Rewrote the following files:", output.Green)
+	for _, group := range groups {
+		fmt.Printf("    %s/\n", filepath.ToSlash(group.Dir))
+		for _, file := range group.Files {
+			fmt.Printf("        %s\n", filepath.ToSlash(file))
+		}
+	}
+}
+
+// groupResults returns one result per file in the group, or a single result
+// for the directory when it was checked recursively
+func groupResults(group tofufmt.FileGroup, status string, elapsed time.Duration) []output.Result {
+	paths := []string{group.Dir}
+	if len(group.Files) > 0 {
+		paths = paths[:0]
+		for _, file := range group.Files {
+			paths = append(paths, filepath.Join(group.Dir, file))
+		}
+	}
+	results := make([]output.Result, 0, len(paths))
+	for _, path := range paths {
+		results = append(results, output.Result{
+			TofuMessage: output.TofuMessage{Step: "fmt", RelPath: filepath.ToSlash(path)},
+			Status:      status,
+			Duration:    elapsed,
+		})
+	}
+	return results
+}
+
+// countFiles returns the total number of files across all groups
+func countFiles(groups []tofufmt.FileGroup) int {
+	total := 0
+	for _, group := range groups {
+		total += len(group.Files)
+	}
+	return total
+}
+
 // printStatus prints a colored emoji status message
 func printStatus(emoji, msg string) {
-	fmt.Println(output.EmojiColorText(emoji, msg, output.Green))
+	output.PrintStatus(emoji, msg, output.Green)
 }
@@ -2,14 +2,20 @@ package main
 
 import (
 	"bytes"
+	"encoding/json"
 	"fmt"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"strings"
 	"testing"
+	"time"
 
+	"pre-commit-hooks/internal/config"
 	"pre-commit-hooks/internal/output"
+	"pre-commit-hooks/internal/runner"
 	"pre-commit-hooks/internal/testutil"
+	"pre-commit-hooks/internal/tofubin"
 	tofu_fmt "pre-commit-hooks/internal/tofufmt"
 )
 
@@ -29,7 +35,7 @@ func TestRunTofuFmtCLI_AllBranches(t *testing.T) {
 		{"not installed", mockArgs{checkInstalled: false}, true},
 		{"getwd error", mockArgs{checkInstalled: true, getwdErr: fmt.Errorf("fail")}, true},
 		{"all formatted", mockArgs{checkInstalled: true}, false},
-		{"unformatted, format ok", mockArgs{checkInstalled: true, runFmtErr: fmt.Errorf("unformatted"), runFmtOut: "needs format"}, false},
+		{"unformatted, format ok", mockArgs{checkInstalled: true, runFmtErr: fmt.Errorf("unformatted"), runFmtOut: "needs format"}, true},
 		{"unformatted, format fails", mockArgs{checkInstalled: true, runFmtErr: fmt.Errorf("unformatted"), runFmtOut: "needs format", formatErr: fmt.Errorf("format fail")}, true},
 	}
 	for _, tc := range cases {
@@ -40,17 +46,17 @@ func TestRunTofuFmtCLI_AllBranches(t *testing.T) {
 				}
 				return "/tmp/mock", nil
 			}
-			runFmt := func(dir string, args []string) (string, error) {
+			runFmt := func(dir string, files []string, args []string) (string, error) {
 				return tc.args.runFmtOut, tc.args.runFmtErr
 			}
-			format := func(dir string, args []string) error {
-				return tc.args.formatErr
+			format := func(dir string, files []string, args []string) ([]string, error) {
+				return files, tc.args.formatErr
 			}
 			// Patch tofu_fmt.CheckOpenTofuInstalled for this test
 			origCheck := tofu_fmt.CheckOpenTofuInstalled
 			tofu_fmt.CheckOpenTofuInstalled = func() bool { return tc.args.checkInstalled }
 			defer func() { tofu_fmt.CheckOpenTofuInstalled = origCheck }()
-			err := RunTofuFmtCLI([]string{}, getwd, runFmt, format)
+			err := RunTofuFmtCLI([]string{"main.tf"}, config.Hook{}, getwd, runFmt, format)
 			if tc.wantErr && err == nil {
 				t.Errorf("Expected error for case %q, got nil", tc.name)
 			}
@@ -61,11 +67,541 @@ func TestRunTofuFmtCLI_AllBranches(t *testing.T) {
 	}
 }
 
+func TestRunTofuFmtCLI_FileSelection(t *testing.T) {
+	origCheck := tofu_fmt.CheckOpenTofuInstalled
+	tofu_fmt.CheckOpenTofuInstalled = func() bool { return true }
+	defer func() { tofu_fmt.CheckOpenTofuInstalled = origCheck }()
+	getwd := func() (string, error) { return "/repo", nil }
+
+	cases := []struct {
+		name      string
+		args      []string
+		wantCalls []string
+		wantExtra []string
+	}{
+		{"no files", []string{}, nil, nil},
+		{"non-tofu files ignored", []string{"README.md"}, nil, nil},
+		{
+			"staged files grouped by directory",
+			[]string{"-diff", "modules/net/main.tf", "main.tf", "modules/net/variables.tf"},
+			[]string{"/repo [main.tf]", "/repo/modules/net [main.tf variables.tf]"},
+			[]string{"-diff"},
+		},
+		{
+			"absolute paths",
+			[]string{"/repo/modules/a/main.tf", "/other/b/main.tf", "modules/a/outputs.tf"},
+			[]string{"/other/b [main.tf]", "/repo/modules/a [main.tf outputs.tf]"},
+			nil,
+		},
+		{"recursive opt-in", []string{"-recursive", "main.tf"}, []string{"/repo []"}, []string{}},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			var calls []string
+			var gotExtra []string
+			runFmt := func(dir string, files []string, args []string) (string, error) {
+				calls = append(calls, fmt.Sprintf("%s %v", dir, files))
+				gotExtra = args
+				return "", nil
+			}
+			format := func(dir string, files []string, args []string) ([]string, error) {
+				t.Errorf("formatFiles should not be called when everything is formatted")
+				return nil, nil
+			}
+			if err := RunTofuFmtCLI(tc.args, config.Hook{}, getwd, runFmt, format); err != nil {
+				t.Fatalf("Did not expect error, got: %v", err)
+			}
+			if fmt.Sprint(calls) != fmt.Sprint(tc.wantCalls) {
+				t.Errorf("runTofuFmt calls = %v, want %v", calls, tc.wantCalls)
+			}
+			if tc.wantExtra != nil && fmt.Sprint(gotExtra) != fmt.Sprint(tc.wantExtra) {
+				t.Errorf("extra args = %v, want %v", gotExtra, tc.wantExtra)
+			}
+		})
+	}
+}
+
+func TestRunTofuFmtCLI_FormatsOnlyUnformattedGroups(t *testing.T) {
+	origCheck := tofu_fmt.CheckOpenTofuInstalled
+	tofu_fmt.CheckOpenTofuInstalled = func() bool { return true }
+	defer func() { tofu_fmt.CheckOpenTofuInstalled = origCheck }()
+
+	runFmt := func(dir string, files []string, args []string) (string, error) {
+		if dir == "/repo/dirty" {
+			return "main.tf", fmt.Errorf("exit status 3")
+		}
+		return "", nil
+	}
+	var formatted []string
+	format := func(dir string, files []string, args []string) ([]string, error) {
+		formatted = append(formatted, fmt.Sprintf("%s %v", dir, files))
+		return files, nil
+	}
+	args := []string{"-fail-on-fix=false", "clean/main.tf", "dirty/main.tf"}
+	if err := RunTofuFmtCLI(args, config.Hook{}, func() (string, error) { return "/repo", nil }, runFmt, format); err != nil {
+		t.Fatalf("Did not expect error, got: %v", err)
+	}
+	if want := "[/repo/dirty [main.tf]]"; fmt.Sprint(formatted) != want {
+		t.Errorf("formatFiles calls = %v, want %v", formatted, want)
+	}
+}
+
+func TestRunTofuFmtCLI_Config(t *testing.T) {
+	origCheck := tofu_fmt.CheckOpenTofuInstalled
+	tofu_fmt.CheckOpenTofuInstalled = func() bool { return true }
+	defer func() { tofu_fmt.CheckOpenTofuInstalled = origCheck }()
+
+	hook := config.Hook{
+		Exclude:     []string{"vendor/**"},
+		Skip:        []string{"legacy"},
+		Args:        []string{"-check-only", "-list=true"},
+		Directories: map[string]config.Directory{"net": {Args: []string{"-write=false"}}},
+	}
+	calls := map[string][]string{}
+	runFmt := func(dir string, files []string, args []string) (string, error) {
+		calls[dir] = args
+		if dir == "/repo/net" {
+			return "main.tf", fmt.Errorf("exit status 3")
+		}
+		return "", nil
+	}
+	var formatted []string
+	format := func(dir string, files []string, args []string) ([]string, error) {
+		formatted = append(formatted, dir)
+		return files, nil
+	}
+	// -check-only=false on the command line overrides -check-only in the file
+	args := []string{"-check-only=false", "-fail-on-fix=false", "main.tf", "net/main.tf", "vendor/mod/main.tf", "legacy/main.tf"}
+	out := testutil.CaptureStdout(t, func() {
+		if err := RunTofuFmtCLI(args, hook, func() (string, error) { return "/repo", nil }, runFmt, format); err != nil {
+			t.Errorf("Did not expect error, got: %v", err)
+		}
+	})
+	if fmt.Sprint(formatted) != "[/repo/net]" {
+		t.Errorf("formatFiles calls = %v, want [/repo/net]", formatted)
+	}
+	want := map[string][]string{"/repo": {"-list=true"}, "/repo/net": {"-list=true", "-write=false"}}
+	if fmt.Sprint(calls) != fmt.Sprint(want) {
+		t.Errorf("tofu fmt calls = %v, want %v", calls, want)
+	}
+	for _, note := range []string{"Skipping 1 file(s) matching -exclude.", "Skipping 1 file(s) listed in the skip settings."} {
+		if !strings.Contains(out, note) {
+			t.Errorf("Expected note %q, got:\n%s", note, out)
+		}
+	}
+}
+
+func TestRunTofuFmtCLI_Timeout(t *testing.T) {
+	origCheck := tofu_fmt.CheckOpenTofuInstalled
+	tofu_fmt.CheckOpenTofuInstalled = func() bool { return true }
+	defer func() { tofu_fmt.CheckOpenTofuInstalled = origCheck }()
+	defer runner.SetTimeouts(runner.SetTimeouts(nil))
+
+	reportPath := filepath.Join(t.TempDir(), "report.json")
+	runFmt := func(dir string, files []string, args []string) (string, error) {
+		return "", &runner.TimeoutError{Step: "fmt", Timeout: 30 * time.Second}
+	}
+	format := func(string, []string, []string) ([]string, error) { return nil, nil }
+	var err error
+	out := testutil.CaptureStdout(t, func() {
+		err = RunTofuFmtCLI([]string{"-timeout=30s", "-report-json=" + reportPath, "net/main.tf"}, config.Hook{}, func() (string, error) { return "/repo", nil }, runFmt, format)
+	})
+	if err == nil {
+		t.Error("Expected error when tofu fmt times out")
+	}
+	if !strings.Contains(out, "tofu fmt timed out after 30s in: net") {
+		t.Errorf("Expected timeout status, got:\n%s", out)
+	}
+	data, _ := os.ReadFile(reportPath)
+	if !strings.Contains(string(data), `"timeout": "30s"`) {
+		t.Errorf("Expected the timeout in the JSON report, got:\n%s", data)
+	}
+
+	if err := RunTofuFmtCLI([]string{"-timeout=init=1m"}, config.Hook{}, func() (string, error) { return "/repo", nil }, runFmt, format); err == nil {
+		t.Error("Expected error for a timeout on a step fmt does not run")
+	}
+}
+
+func TestRunTofuFmtCLI_CheckOnly(t *testing.T) {
+	origCheck := tofu_fmt.CheckOpenTofuInstalled
+	tofu_fmt.CheckOpenTofuInstalled = func() bool { return true }
+	defer func() { tofu_fmt.CheckOpenTofuInstalled = origCheck }()
+
+	cases := []struct {
+		name        string
+		args        []string
+		env         string
+		unformatted bool
+		wantErr     bool
+		wantFormat  bool
+	}{
+		{"flag, unformatted", []string{"--check-only", "main.tf"}, "", true, true, false},
+		{"flag, formatted", []string{"--check-only", "main.tf"}, "", false, false, false},
+		{"env, unformatted", []string{"main.tf"}, "true", true, true, false},
+		{"flag overrides env", []string{"--check-only=false", "main.tf"}, "1", true, false, true},
+		{"invalid env ignored", []string{"main.tf"}, "maybe", true, false, true},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Setenv(checkOnlyEnv, tc.env)
+			t.Setenv(failOnFixEnv, "false")
+			runFmt := func(dir string, files []string, args []string) (string, error) {
+				if tc.unformatted {
+					return "main.tf\n--- old/main.tf\n+++ new/main.tf\n", fmt.Errorf("exit status 3")
+				}
+				return "", nil
+			}
+			formatCalled := false
+			format := func(dir string, files []string, args []string) ([]string, error) {
+				formatCalled = true
+				return files, nil
+			}
+			err := RunTofuFmtCLI(tc.args, config.Hook{}, func() (string, error) { return "/repo", nil }, runFmt, format)
+			if tc.wantErr != (err != nil) {
+				t.Errorf("RunTofuFmtCLI() error = %v, wantErr %v", err, tc.wantErr)
+			}
+			if formatCalled != tc.wantFormat {
+				t.Errorf("formatFiles called = %v, want %v", formatCalled, tc.wantFormat)
+			}
+		})
+	}
+}
+
+func TestRunTofuFmtCLI_FailOnFixPolicy(t *testing.T) {
+	origCheck := tofu_fmt.CheckOpenTofuInstalled
+	tofu_fmt.CheckOpenTofuInstalled = func() bool { return true }
+	defer func() { tofu_fmt.CheckOpenTofuInstalled = origCheck }()
+
+	cases := []struct {
+		name      string
+		args      []string
+		env       string
+		rewritten []string
+		wantErr   bool
+	}{
+		{"default fails when files are fixed", []string{"main.tf"}, "", []string{"main.tf"}, true},
+		{"flag disables failure", []string{"-fail-on-fix=false", "main.tf"}, "", []string{"main.tf"}, false},
+		{"env disables failure", []string{"main.tf"}, "false", []string{"main.tf"}, false},
+		{"flag overrides env", []string{"-fail-on-fix", "main.tf"}, "false", []string{"main.tf"}, true},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Setenv(checkOnlyEnv, "")
+			t.Setenv(failOnFixEnv, tc.env)
+			runFmt := func(dir string, files []string, args []string) (string, error) {
+				return "main.tf", fmt.Errorf("exit status 3")
+			}
+			format := func(dir string, files []string, args []string) ([]string, error) {
+				return tc.rewritten, nil
+			}
+			err := RunTofuFmtCLI(tc.args, config.Hook{}, func() (string, error) { return "/repo", nil }, runFmt, format)
+			if tc.wantErr != (err != nil) {
+				t.Errorf("RunTofuFmtCLI() error = %v, wantErr %v", err, tc.wantErr)
+			}
+		})
+	}
+}
+
+func TestPrintRewritten(t *testing.T) {
+	out := testutil.CaptureStdout(t, func() {
+		printRewritten([]tofu_fmt.FileGroup{
+			{Dir: ".", Files: []string{"main.tf"}},
+			{Dir: filepath.Join("modules", "net"), Files: []string{"outputs.tf", "variables.tf"}},
+		})
+	})
+	for _, want := range []string{"Rewrote the following files:", "    ./\n        main.tf", "    modules/net/\n        outputs.tf\n        variables.tf"} {
+		if !strings.Contains(string(out), want) {
+			t.Errorf("printRewritten output missing %q, got:\n%s", want, out)
+		}
+	}
+}
+
+func TestPrintDiff(t *testing.T) {
+	defer output.SetRenderer(output.SetRenderer(output.Renderer{Color: true}))
+	out := testutil.CaptureStdout(t, func() {
+		printDiff(tofu_fmt.FileDiff{Path: "main.tf", Hunks: []tofu_fmt.Hunk{{
+			OldStart: 1, OldLines: 2, NewStart: 1, NewLines: 2,
+			Lines: []tofu_fmt.DiffLine{
+				{Kind: tofu_fmt.Removed, Text: "a=1"},
+				{Kind: tofu_fmt.Added, Text: "a = 1"},
+				{Kind: tofu_fmt.Context, Text: "b = 2"},
+			},
+		}}})
+	})
+	for _, want := range []string{
+		"    main.tf\n",
+		"@@ -1,2 +1,2 @@",
+		output.Colorize("-a=1", output.Red),
+		output.Colorize("+a = 1", output.Green),
+		"     b = 2\n",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("printDiff output missing %q, got:\n%s", want, out)
+		}
+	}
+
+	output.SetRenderer(output.Renderer{ASCII: true})
+	out = testutil.CaptureStdout(t, func() {
+		printDiff(tofu_fmt.FileDiff{Path: "main.tf", Hunks: []tofu_fmt.Hunk{{Lines: []tofu_fmt.DiffLine{{Kind: tofu_fmt.Removed, Text: "a=1"}}}}})
+	})
+	if strings.Contains(out, "\033[") || !strings.Contains(out, "    -a=1\n") {
+		t.Errorf("Expected plain diff without color codes, got %q", out)
+	}
+}
+
+func TestRunTofuFmtCLI_CheckOnlyListsChangedLines(t *testing.T) {
+	origCheck := tofu_fmt.CheckOpenTofuInstalled
+	tofu_fmt.CheckOpenTofuInstalled = func() bool { return true }
+	defer func() { tofu_fmt.CheckOpenTofuInstalled = origCheck }()
+
+	runFmt := func(dir string, files []string, args []string) (string, error) {
+		return "main.tf\n--- old/main.tf\n+++ new/main.tf\n@@ -1 +1 @@\n-a=1\n+a = 1\n", fmt.Errorf("exit status 3")
+	}
+	format := func(dir string, files []string, args []string) ([]string, error) {
+		t.Error("formatFiles should not be called in check-only mode")
+		return nil, nil
+	}
+	var err error
+	out := testutil.CaptureStdout(t, func() {
+		err = RunTofuFmtCLI([]string{"-check-only", "net/main.tf"}, config.Hook{}, func() (string, error) { return "/repo", nil }, runFmt, format)
+	})
+	if err == nil {
+		t.Error("Expected error in check-only mode with unformatted files")
+	}
+	for _, want := range []string{"1 file(s) need formatting, 1 line(s) added and 1 removed.", "    net/main.tf (+1/-1)"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output missing %q, got:\n%s", want, out)
+		}
+	}
+}
+
+func TestRunTofuFmtCLI_ReportJSON(t *testing.T) {
+	origCheck := tofu_fmt.CheckOpenTofuInstalled
+	tofu_fmt.CheckOpenTofuInstalled = func() bool { return true }
+	defer func() { tofu_fmt.CheckOpenTofuInstalled = origCheck }()
+	t.Setenv(checkOnlyEnv, "")
+	t.Setenv(failOnFixEnv, "")
+
+	runFmt := func(dir string, files []string, args []string) (string, error) {
+		if dir == "/repo/dirty" {
+			return "main.tf\n--- old/main.tf\n+++ new/main.tf\n@@ -3 +3 @@\n-a=1\n+a = 1\n", fmt.Errorf("exit status 3")
+		}
+		return "", nil
+	}
+	format := func(dir string, files []string, args []string) ([]string, error) {
+		return files, nil
+	}
+
+	cases := []struct {
+		name         string
+		extra        []string
+		wantStatus   string
+		wantDirty    string
+		wantSeverity string
+	}{
+		{"check-only", []string{"-check-only"}, output.StatusFailed, output.StatusFailed, output.SeverityError},
+		{"fixed", nil, output.StatusFixed, output.StatusFixed, output.SeverityWarning},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			reportPath := filepath.Join(t.TempDir(), "fmt.json")
+			args := append([]string{"--report-json", reportPath, "clean/main.tf", "dirty/main.tf"}, tc.extra...)
+			RunTofuFmtCLI(args, config.Hook{}, func() (string, error) { return "/repo", nil }, runFmt, format)
+
+			data, err := os.ReadFile(reportPath)
+			if err != nil {
+				t.Fatalf("Expected report to be written: %v", err)
+			}
+			var report output.Report
+			if err := json.Unmarshal(data, &report); err != nil {
+				t.Fatalf("Invalid report JSON: %v", err)
+			}
+			if report.Hook != "tofufmt" || report.Status != tc.wantStatus || len(report.Results) != 2 {
+				t.Fatalf("Unexpected report: %s", data)
+			}
+			clean, dirty := report.Results[0], report.Results[1]
+			if clean.RelPath != "clean/main.tf" || clean.Status != output.StatusPassed {
+				t.Errorf("clean result = %+v", clean)
+			}
+			if dirty.RelPath != "dirty/main.tf" || dirty.Status != tc.wantDirty {
+				t.Errorf("dirty result = %+v", dirty)
+			}
+			if len(dirty.Diagnostics) != 1 || dirty.Diagnostics[0].Severity != tc.wantSeverity || dirty.Diagnostics[0].Range.Start.Line != 3 {
+				t.Errorf("dirty diagnostics = %+v", dirty.Diagnostics)
+			}
+		})
+	}
+}
+
+func TestRunTofuFmtCLI_ReportSARIF(t *testing.T) {
+	origCheck := tofu_fmt.CheckOpenTofuInstalled
+	tofu_fmt.CheckOpenTofuInstalled = func() bool { return true }
+	defer func() { tofu_fmt.CheckOpenTofuInstalled = origCheck }()
+	t.Setenv(checkOnlyEnv, "")
+	t.Setenv(failOnFixEnv, "")
+
+	runFmt := func(dir string, files []string, args []string) (string, error) {
+		if dir == "/repo/dirty" {
+			return "main.tf\n--- old/main.tf\n+++ new/main.tf\n@@ -3 +3 @@\n-a=1\n+a = 1\n@@ -9,2 +9,2 @@\n-b=2\n-c=3\n+b = 2\n+c = 3\n", fmt.Errorf("exit status 3")
+		}
+		return "", nil
+	}
+	sarifPath := filepath.Join(t.TempDir(), "fmt.sarif")
+	args := []string{"-check-only", "--report-sarif=" + sarifPath, "clean/main.tf", "dirty/main.tf"}
+	RunTofuFmtCLI(args, config.Hook{}, func() (string, error) { return "/repo", nil }, runFmt, nil)
+
+	data, err := os.ReadFile(sarifPath)
+	if err != nil {
+		t.Fatalf("Expected SARIF log to be written: %v", err)
+	}
+	var log output.SARIFLog
+	if err := json.Unmarshal(data, &log); err != nil {
+		t.Fatalf("Invalid SARIF JSON: %v", err)
+	}
+	if len(log.Runs) != 1 || log.Runs[0].Tool.Driver.Name != "tofufmt" {
+		t.Fatalf("Unexpected SARIF log: %s", data)
+	}
+	results := log.Runs[0].Results
+	if len(results) != 2 {
+		t.Fatalf("Expected one result per hunk, got %d", len(results))
+	}
+	for i, wantLine := range []int{3, 9} {
+		location := results[i].Locations[0].PhysicalLocation
+		if results[i].RuleID != "fmt/file-is-not-formatted" || location.ArtifactLocation.URI != "dirty/main.tf" || location.Region.StartLine != wantLine {
+			t.Errorf("result %d = %+v, want dirty/main.tf line %d", i, results[i], wantLine)
+		}
+	}
+}
+
+func TestRunTofuFmtCLI_GitHubAnnotations(t *testing.T) {
+	origCheck := tofu_fmt.CheckOpenTofuInstalled
+	tofu_fmt.CheckOpenTofuInstalled = func() bool { return true }
+	defer func() { tofu_fmt.CheckOpenTofuInstalled = origCheck }()
+	t.Setenv(checkOnlyEnv, "")
+	t.Setenv(failOnFixEnv, "")
+	t.Setenv(output.GitHubActionsEnv, "true")
+
+	runFmt := func(dir string, files []string, args []string) (string, error) {
+		if dir == "/repo/dirty" {
+			return "main.tf\n--- old/main.tf\n+++ new/main.tf\n@@ -3 +3 @@\n-a=1\n+a = 1\n@@ -9 +9 @@\n-b=2\n+b = 2\n", fmt.Errorf("exit status 3")
+		}
+		return "", nil
+	}
+	format := func(dir string, files []string, args []string) ([]string, error) {
+		return files, nil
+	}
+
+	out := testutil.CaptureStdout(t, func() {
+		RunTofuFmtCLI([]string{"-check-only", "clean/main.tf", "dirty/main.tf"}, config.Hook{}, func() (string, error) { return "/repo", nil }, runFmt, format)
+	})
+	for _, want := range []string{
+		"::group::dirty\n",
+		"::endgroup::\n",
+		"::error file=dirty/main.tf,line=3,title=File is not formatted::tofu fmt would change 2 hunk(s) in this file.\n",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("Expected output to contain %q, got:\n%s", want, out)
+		}
+	}
+	if strings.Count(out, "::error") != 1 || strings.Contains(out, "clean/main.tf,") {
+		t.Errorf("Expected a single annotation for the unformatted file, got:\n%s", out)
+	}
+
+	out = testutil.CaptureStdout(t, func() {
+		RunTofuFmtCLI([]string{"-fail-on-fix=false", "dirty/main.tf"}, config.Hook{}, func() (string, error) { return "/repo", nil }, runFmt, format)
+	})
+	if !strings.Contains(out, "::warning file=dirty/main.tf,line=3,title=File was reformatted::") {
+		t.Errorf("Expected a warning annotation for the rewritten file, got:\n%s", out)
+	}
+
+	out = testutil.CaptureStdout(t, func() {
+		RunTofuFmtCLI([]string{"--github-annotations=false", "-check-only", "dirty/main.tf"}, config.Hook{}, func() (string, error) { return "/repo", nil }, runFmt, format)
+	})
+	if strings.Contains(out, "::") {
+		t.Errorf("Expected the flag to disable annotations, got:\n%s", out)
+	}
+}
+
+func TestRunTofuFmtCLI_TerraformBinary(t *testing.T) {
+	origCheck := tofu_fmt.CheckOpenTofuInstalled
+	tofu_fmt.CheckOpenTofuInstalled = func() bool { return true }
+	defer func() { tofu_fmt.CheckOpenTofuInstalled = origCheck }()
+	defer tofubin.Set(tofubin.Current())
+
+	var checked []string
+	runFmt := func(dir string, files []string, args []string) (string, error) {
+		checked = append(checked, files...)
+		return "", nil
+	}
+	out := testutil.CaptureStdout(t, func() {
+		RunTofuFmtCLI([]string{"--binary=terraform", "main.tf", "main.tofu"}, config.Hook{}, func() (string, error) { return "/repo", nil }, runFmt, nil)
+	})
+	if tofubin.Current().Name != "terraform" {
+		t.Errorf("Expected terraform to be selected, got %+v", tofubin.Current())
+	}
+	if len(checked) != 1 || checked[0] != "main.tf" {
+		t.Errorf("Expected only main.tf to be checked, got %v", checked)
+	}
+	for _, want := range []string{"Skipping 1 file(s) not supported by terraform.", "Running terraform fmt on 1 file(s) in: repo"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("Expected output to contain %q, got:\n%s", want, out)
+		}
+	}
+}
+
+func TestRunTofuFmtCLI_TofuVersion(t *testing.T) {
+	origCheck := tofu_fmt.CheckOpenTofuInstalled
+	tofu_fmt.CheckOpenTofuInstalled = func() bool { return true }
+	defer func() { tofu_fmt.CheckOpenTofuInstalled = origCheck }()
+	defer tofubin.Set(tofubin.Current())
+
+	root := t.TempDir()
+	if err := os.WriteFile(filepath.Join(root, "versions.tf"), []byte("terraform {\n  required_version = \">= 1.6.0\"\n}\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	cases := []struct {
+		name    string
+		version string
+		extra   []string
+		wantErr string
+	}{
+		{"required_version satisfied", "1.8.2", nil, ""},
+		{"required_version not satisfied", "1.5.7", nil, "OpenTofu 1.5.7 is installed, but " + root + " requires >= 1.6.0"},
+		{"flag constraint", "1.8.2", []string{"--tofu-version=~> 1.9"}, "OpenTofu 1.8.2 is installed, but --tofu-version requires ~> 1.9"},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			ran := false
+			runFmt := func(dir string, files []string, args []string) (string, error) {
+				ran = true
+				return "", nil
+			}
+			args := append([]string{"--binary=" + testutil.FakeTofu(t, c.version), "versions.tf"}, c.extra...)
+			var err error
+			out := testutil.CaptureStdout(t, func() {
+				err = RunTofuFmtCLI(args, config.Hook{}, func() (string, error) { return root, nil }, runFmt, nil)
+			})
+			if c.wantErr == "" {
+				if err != nil || !ran {
+					t.Errorf("Expected fmt to run without error, got %v (ran=%v)", err, ran)
+				}
+				return
+			}
+			if err == nil || err.Error() != c.wantErr || ran {
+				t.Errorf("Expected %q before running fmt, got %v (ran=%v)", c.wantErr, err, ran)
+			}
+			if !strings.Contains(out, c.wantErr) {
+				t.Errorf("Expected output to contain %q, got:\n%s", c.wantErr, out)
+			}
+		})
+	}
+}
+
 func TestRunTofuFmtCLI_NotInstalled(t *testing.T) {
 	origCheck := tofu_fmt.CheckOpenTofuInstalled
 	tofu_fmt.CheckOpenTofuInstalled = func() bool { return false }
 	defer func() { tofu_fmt.CheckOpenTofuInstalled = origCheck }()
-	err := RunTofuFmtCLI([]string{}, os.Getwd, tofu_fmt.RunTofuFmt, tofu_fmt.FormatFiles)
+	err := RunTofuFmtCLI([]string{}, config.Hook{}, os.Getwd, tofu_fmt.RunTofuFmt, tofu_fmt.FormatFiles)
 	if err == nil {
 		t.Error("Expected error when OpenTofu is not installed")
 	}
@@ -73,7 +609,7 @@ func TestRunTofuFmtCLI_NotInstalled(t *testing.T) {
 
 func TestRunTofuFmtCLI_BadDir(t *testing.T) {
 	// Simulate error getting working directory
-	err := RunTofuFmtCLI([]string{}, func() (string, error) { return "", fmt.Errorf("fail") }, tofu_fmt.RunTofuFmt, tofu_fmt.FormatFiles)
+	err := RunTofuFmtCLI([]string{}, config.Hook{}, func() (string, error) { return "", fmt.Errorf("fail") }, tofu_fmt.RunTofuFmt, tofu_fmt.FormatFiles)
 	if err == nil {
 		t.Error("Expected error when failing to get working directory")
 	}
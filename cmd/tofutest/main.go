@@ -1,20 +1,110 @@
 package main
 
 import (
+	"errors"
 	"fmt"
 	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strconv"
 	"strings"
+	"time"
 
+	"pre-commit-hooks/internal/cliargs"
+	"pre-commit-hooks/internal/config"
+	"pre-commit-hooks/internal/discover"
+	"pre-commit-hooks/internal/hookutil"
+	"pre-commit-hooks/internal/modules"
 	"pre-commit-hooks/internal/output"
+	"pre-commit-hooks/internal/parallel"
+	"pre-commit-hooks/internal/runner"
+	"pre-commit-hooks/internal/tofubin"
+	"pre-commit-hooks/internal/tofuenv"
 	tofutest "pre-commit-hooks/internal/tofutest"
+	"pre-commit-hooks/internal/tofuversion"
 )
 
+// hookFlags lists the flags consumed by the hook itself rather than forwarded
+// to tofu test, and whether each takes a value.
+var hookFlags = map[string]bool{
+	"report-json":        true,
+	"report-junit":       true,
+	"github-annotations": false,
+	"binary":             true,
+	"tofu-version":       true,
+	"exclude":            true,
+	"git-discovery":      true,
+	"timeout":            true,
+	"all":                false,
+	"jobs":               true,
+	"per-file":           false,
+	"no-plugin-cache":    false,
+	"plugin-cache-dir":   true,
+}
+
+// prePushEnv is set by pre-commit when hooks run in the pre-push stage, where
+// the full test suite runs before anything is shared.
+const prePushEnv = "PRE_COMMIT_REMOTE_NAME"
+
+// knownValueFlags lists tofu test flags that accept a value in split form.
+var knownValueFlags = map[string]bool{
+	"-filter":         true,
+	"-test-directory": true,
+	"-var":            true,
+	"-var-file":       true,
+}
+
+// options holds the parsed command line.
+type options struct {
+	ExtraArgs   []string
+	ReportJSON  string
+	ReportJUnit string
+	// GitHubAnnotations groups the test output and annotates failing run
+	// blocks with GitHub Actions workflow commands.
+	GitHubAnnotations bool
+	// Binary selects tofu, terraform or a path; see tofubin.Resolve.
+	Binary string
+	// TofuVersion is a version constraint checked in addition to the
+	// required_version of every tested module.
+	TofuVersion string
+	// Discover holds the -exclude globs and the -git-discovery mode, applied
+	// to test files in addition to .tofuignore files.
+	Discover discover.Options
+	// Timeouts holds the -timeout values limiting how long tofu test may run.
+	Timeouts []string
+	// Files are the staged filenames passed by pre-commit. When set, only the
+	// modules affected by these files are tested.
+	Files []string
+	// All tests every module even when Files is set, as -all does and as the
+	// pre-push stage does.
+	All bool
+	// Jobs is the number of suites tested concurrently, one by default.
+	Jobs int
+	// PerFile makes each test file a suite of its own, selected with -filter.
+	PerFile bool
+	// PluginCache shares a provider plugin cache across every tofu init.
+	PluginCache bool
+	// PluginCacheDir overrides the plugin cache location.
+	PluginCacheDir string
+	// Config holds the test settings of the configuration file; its flags
+	// and args are already merged into the fields above.
+	Config config.Hook
+}
+
 func main() {
-	err := RunTofuTestCLI(
-		parseExtraArgs(os.Args[1:]),
+	runner.HandleInterrupts()
+	opts, err := loadOptions(os.Args[1:])
+	if err != nil {
+		output.PrintStatus(output.Error, err.Error(), output.Red)
+		os.Exit(1)
+	}
+	err = RunTofuTestCLI(
+		opts,
 		tofutest.CheckOpenTofuInstalled,
 		os.Getwd,
-		tofutest.HasTestFiles,
+		tofutest.FindTestFiles,
+		hookutil.RunCmdInDir,
 		tofutest.RunTofuTest,
 		printStatus,
 		os.Exit,
@@ -25,114 +115,623 @@ func main() {
 }
 
 // RunTofuTestCLI runs the tofu test CLI logic. Returns error if any step fails.
+// Every module directory that owns test files, in the module itself or in its
+// tests directory, is tested on its own, and the test summary lists the
+// outcome per module. Modules that are not initialized yet, e.g. in a fresh
+// clone, first run tofu init -backend=false with a shared plugin cache, and a
+// failed init is reported as its own step. Up to opts.Jobs
+// suites, modules or with opts.PerFile single test files, run at once, each
+// initialized in its own data directory, and their buffered output is printed
+// in order.
+// With -report-json PATH a machine-readable report of the run is written, and
+// with -report-junit PATH a JUnit XML report with one testsuite per test file
+// and one testcase per run block. With -github-annotations (the default under
+// GitHub Actions) failing run blocks are annotated in the workflow run.
+// Test files ignored by .tofuignore or matching -exclude are left out through
+// -filter arguments and listed in a skipped summary. tofu test is stopped after
+// the -timeout limit and reported as timed out. When opts.Files is set and
+// opts.All is not, only the modules owning those files and the modules that
+// call them as local modules are tested. The include, exclude and skip globs
+// of opts.Config filter the modules, and its per-directory args are added to
+// the tofu test arguments of each module.
 func RunTofuTestCLI(
-	extraArgs []string,
+	opts options,
 	checkInstalled func() bool,
 	getwd func() (string, error),
-	hasTestFiles func(string) (bool, error),
-	runTest func(string, []string) (string, error),
+	findTests func(string, discover.Options) (discover.Result, error),
+	runCmd func(string, []string, []string) (string, error),
+	runTest func(string, []string, []string) (tofutest.TestResult, error),
 	printStatus func(string, string),
 	exit func(int),
 ) error {
+	bin := tofubin.Resolve(opts.Binary)
+	tofubin.Set(bin)
+	timeouts, err := runner.ParseTimeouts(opts.Timeouts, "init", "test")
+	if err != nil {
+		output.PrintStatus(output.Error, err.Error(), output.Red)
+		exit(1)
+		return err
+	}
+	runner.SetTimeouts(timeouts)
 	if !checkInstalled() {
-		fmt.Println("OpenTofu is not installed or not in PATH.")
+		output.PrintStatus(output.Error, fmt.Sprintf("%s is not installed or not in PATH.", bin.DisplayName()), output.Red)
 		exit(1)
-		return fmt.Errorf("OpenTofu not installed")
+		return fmt.Errorf("%s not installed", bin.DisplayName())
 	}
 
 	rootDir, err := getwd()
 	if err != nil {
-		fmt.Println("Could not get working directory.")
+		output.PrintStatus(output.Error, "Could not get working directory.", output.Red)
 		exit(1)
 		return err
 	}
 
-	hasTests, err := hasTestFiles(rootDir)
+	// Like tofu-validate, a directory that cannot be read or a failure to
+	// list the git files is reported and the walk goes on without it
+	found, err := findTests(rootDir, opts.Discover)
 	if err != nil {
-		fmt.Printf("Error checking for test files: %v\n", err)
+		fmt.Fprintf(os.Stderr, "Warning: Error scanning directories: %v\n", err)
+	}
+	skipped := make([]output.Result, len(found.Skipped))
+	for i, s := range found.Skipped {
+		skipped[i] = output.Result{TofuMessage: output.TofuMessage{Step: "test", RelPath: hookutil.RelPath(rootDir, s.Path), Output: s.Reason}, Status: output.StatusSkipped}
+	}
+
+	if len(found.Files) == 0 {
+		printStatus(output.Running, "No OpenTofu test files (.tftest.hcl) found, skipping tests.")
+		return finishEarly(opts, skipped, exit)
+	}
+
+	testModules := tofutest.Modules(found.Files, testDirectory(opts.ExtraArgs))
+	if len(opts.Files) > 0 && !opts.All {
+		testModules = affectedModules(rootDir, opts.Discover, testModules, opts.Files)
+		if len(testModules) == 0 {
+			printStatus(output.ThumbsUp, "No modules with tests affected by the staged files.")
+			return finishEarly(opts, skipped, exit)
+		}
+	}
+	baseDir := filepath.Base(rootDir)
+	testModules, skippedModules := configuredModules(opts.Config, rootDir, testModules)
+	for _, module := range skippedModules {
+		skipped = append(skipped, output.Result{TofuMessage: output.TofuMessage{Step: "test", RelPath: hookutil.DisplayPath(rootDir, baseDir, module.Dir), Output: "listed in the skip settings"}, Status: output.StatusSkipped})
+	}
+	if len(testModules) == 0 {
+		printStatus(output.ThumbsUp, "No modules left to test after the configured filters.")
+		return finishEarly(opts, skipped, exit)
+	}
+	dirs := make([]string, len(testModules))
+	for i, module := range testModules {
+		dirs[i] = module.Dir
+	}
+	if err := tofuversion.Check(opts.TofuVersion, dirs); err != nil {
+		output.PrintStatus(output.Error, err.Error(), output.Red)
 		exit(1)
 		return err
 	}
 
-	if !hasTests {
-		printStatus(output.Running, "No OpenTofu test files (.tftest.hcl) found, skipping tests.")
-		exit(0)
-		return nil
+	var cache *tofuenv.PluginCache
+	if opts.PluginCache {
+		if cache, err = tofuenv.NewPluginCache(opts.PluginCacheDir); err != nil {
+			output.PrintStatus(output.Warning, fmt.Sprintf("Could not set up plugin cache, continuing without it: %v", err), output.Yellow)
+			cache = nil
+		}
+	}
+
+	suites := testSuites(rootDir, baseDir, testModules, opts, len(found.Skipped) > 0)
+	// Suites tested side by side get their own data directories. Their inits
+	// may change the lock file of a module, so it is snapshotted once for all
+	// of the module's suites and restored after them.
+	isolate := opts.Jobs > 1 && len(suites) > 1
+	setups := make([]suiteInit, len(suites))
+	locks := make([]*tofuenv.LockSnapshot, len(testModules))
+	if isolate {
+		for m, module := range testModules {
+			lock, err := tofuenv.SnapshotLock(module.Dir)
+			if err != nil {
+				for i, s := range suites {
+					if s.module == m {
+						setups[i].out, setups[i].err = fmt.Sprintf("could not snapshot lock file: %v", err), err
+					}
+				}
+				continue
+			}
+			locks[m] = lock
+		}
+	}
+	tested := make([]suiteResult, len(suites))
+	results := append([]output.Result(nil), skipped...)
+	var files []tofutest.FileResult
+	var annotations []output.Annotation
+	var errorMessages []output.TofuMessage
+	var summaries []suiteSummary
+	var errs []error
+	initFailures := 0
+	parallel.Ordered(len(suites), opts.Jobs, func(i int) {
+		s := suites[i]
+		module, setup := testModules[s.module], &setups[i]
+		if setup.err == nil {
+			setup.run(module.Dir, isolate, cache, runCmd)
+		}
+		args := testArgs(opts.Config.ArgsFor(hookutil.RelPath(rootDir, module.Dir), opts.ExtraArgs), s)
+		tested[i] = testSuite(s, module, setup, args, runTest)
+	}, func(i int) {
+		if opts.GitHubAnnotations {
+			fmt.Println(output.GroupStart(suites[i].display))
+		}
+		tested[i].Replay(printStatus)
+		if opts.GitHubAnnotations {
+			fmt.Println(output.GroupEnd)
+		}
+
+		// Paths in the reports are relative to the repository root
+		rel := hookutil.RelPath(rootDir, testModules[suites[i].module].Dir)
+		annotations = append(annotations, runAnnotations(rel, tested[i].files)...)
+		for _, file := range tested[i].files {
+			file.Path = path.Join(rel, file.Path)
+			files = append(files, file)
+		}
+		results = append(results, tested[i].steps...)
+		errorMessages = append(errorMessages, tested[i].errors...)
+		summaries = append(summaries, tested[i].summary)
+		if tested[i].err != nil {
+			errs = append(errs, tested[i].err)
+		}
+		if tested[i].initFailed {
+			initFailures++
+		}
+	})
+	cleanupFailed := func(display string, err error) {
+		errorMessages = append(errorMessages, output.TofuMessage{Step: "cleanup", RelPath: display, Output: err.Error()})
+		errs = append(errs, fmt.Errorf("%s: cleanup failed: %w", display, err))
+	}
+	for i := range setups {
+		if err := setups[i].restore(); err != nil {
+			cleanupFailed(suites[i].display, err)
+		}
+	}
+	for m, lock := range locks {
+		if lock == nil {
+			continue
+		}
+		if err := lock.Restore(); err != nil {
+			cleanupFailed(hookutil.DisplayPath(rootDir, baseDir, testModules[m].Dir), err)
+		}
+	}
+	if opts.GitHubAnnotations {
+		output.PrintAnnotations(annotations)
 	}
 
-	printStatus(output.Running, "Running tofu test...")
-	testOutput, err := runTest(rootDir, extraArgs)
+	output.PrintSkippedSummary(hookutil.SkippedMessages(skipped))
+	output.PrintErrorSummary(errorMessages, hookutil.PrintIndentedOutput)
+	printSuiteSummary(summaries)
+	if cache != nil {
+		hits, misses := cache.Stats()
+		printStatus(output.Running, fmt.Sprintf("Plugin cache %s: %d hit(s), %d miss(es)", cache.Dir, hits, misses))
+		fmt.Println()
+	}
 
-	// Print the output
-	printIndentedOutput(testOutput, true)
+	// Write the report before any exit, which does not return in production
+	reportErr := writeReports(opts, files, results...)
 
-	if err != nil {
-		printStatus(output.Error, "OpenTofu test failed.")
+	if len(errs) > 0 {
+		msg := fmt.Sprintf("%s test failed in %d of %d suite(s).", bin.DisplayName(), len(errs), len(suites))
+		if initFailures > 0 {
+			msg = fmt.Sprintf("%s init failed in %d and test failed in %d of %d suite(s).", bin.DisplayName(), initFailures, len(errs)-initFailures, len(suites))
+		}
+		printStatus(output.Error, msg)
 		fmt.Println()
 		exit(1)
-		return fmt.Errorf("test failed: %w", err)
+		return fmt.Errorf("test failed: %w", errors.Join(errs...))
+	}
+
+	if reportErr != nil {
+		exit(1)
+		return reportErr
 	}
 
-	printStatus(output.ThumbsUp, "OpenTofu test completed successfully.")
+	printStatus(output.ThumbsUp, fmt.Sprintf("%s test completed successfully for all modules.", bin.DisplayName()))
 	fmt.Println()
 	return nil
 }
 
-// printIndentedOutput prints each line of output indented for better readability
-func printIndentedOutput(output string, addNewline bool) {
-	lines := strings.Split(output, "\n")
-	lastNonEmpty := -1
-	for idx := range lines {
-		if strings.TrimSpace(lines[idx]) != "" {
-			lastNonEmpty = idx
+// suite is one tofu test process: a module, or with -per-file one test file
+// of a module
+type suite struct {
+	// module indexes the suite's module
+	module  int
+	display string
+	// files are the test files of the suite, relative to the module
+	files []string
+	// filter selects files with -filter arguments
+	filter bool
+}
+
+// testSuites splits the modules into suites: one per module or, with
+// -per-file and no -filter in the args, one per test file. filter is set when
+// discovery skipped test files, which only -filter arguments leave out.
+func testSuites(rootDir, baseDir string, testModules []tofutest.Module, opts options, filter bool) []suite {
+	perFile := opts.PerFile && !hasFilter(opts.ExtraArgs)
+	var suites []suite
+	for i, module := range testModules {
+		display := hookutil.DisplayPath(rootDir, baseDir, module.Dir)
+		if !perFile {
+			suites = append(suites, suite{module: i, display: display, files: module.Files, filter: filter})
+			continue
+		}
+		for _, file := range module.Files {
+			suites = append(suites, suite{module: i, display: path.Join(display, file), files: []string{file}, filter: true})
 		}
 	}
-	for _, line := range lines {
-		if strings.TrimSpace(line) != "" {
-			fmt.Printf("    %s\n", line)
+	return suites
+}
+
+// suiteInit is the tofu init of a suite's module, in the suite's own data
+// directory when suites run side by side
+type suiteInit struct {
+	// env holds TF_DATA_DIR when the suite is isolated
+	env []string
+	iso *tofuenv.Isolation
+	// skipped is set when the module was already initialized
+	skipped bool
+	out     string
+	err     error
+	elapsed time.Duration
+}
+
+// run initializes dir unless it already is, always in a temporary data
+// directory when isolate is set
+func (m *suiteInit) run(dir string, isolate bool, cache *tofuenv.PluginCache, runCmd func(string, []string, []string) (string, error)) {
+	if isolate {
+		iso, err := tofuenv.IsolateData()
+		if err != nil {
+			m.out, m.err = fmt.Sprintf("could not isolate data directory: %v", err), err
+			return
 		}
+		m.iso, m.env = iso, iso.Env()
+	} else if tofuenv.InitReason(dir) == "" {
+		m.skipped = true
+		return
 	}
-	// Only add newline if not already present at the end
-	if addNewline && lastNonEmpty != len(lines)-1 {
-		fmt.Println()
+	env, release := m.env, func() {}
+	if cache != nil {
+		// Inits may only share the cache while none of them downloads into it
+		env = append(cache.Env(), env...)
+		release = cache.Acquire(dir)
+	}
+	started := time.Now()
+	m.out, m.err = runCmd(dir, env, []string{"init", "-input=false", "-backend=false"})
+	m.elapsed = time.Since(started)
+	release()
+	if cache != nil {
+		cache.Record(m.out)
 	}
 }
 
-// printStatus prints a colored emoji status message
-func printStatus(emoji, msg string) {
-	fmt.Println(output.EmojiColorText(emoji, msg, output.Green))
-}
-
-// parseExtraArgs filters os.Args tokens, keeping only flags (tokens starting
-// with '-') and their values. Equals-form flags (-flag=value) are kept as a
-// single token. Split-form flags (-flag value) are kept as two tokens, but
-// only for flags known to accept a value argument — boolean flags will not
-// accidentally consume the next token. A "--" token ends flag processing.
-func parseExtraArgs(args []string) []string {
-	// knownValueFlags lists tofu test flags that accept a value in split form.
-	knownValueFlags := map[string]bool{
-		"-filter":         true,
-		"-test-directory": true,
-		"-var":            true,
-		"-var-file":       true,
-	}
-
-	extraArgs := []string{}
-	for i := 0; i < len(args); i++ {
-		arg := args[i]
-		if arg == "--" {
-			break
-		}
-		if strings.HasPrefix(arg, "-") {
-			extraArgs = append(extraArgs, arg)
-			// Only consume the next token as a value for flags known to accept
-			// one, and only when no value is already embedded via '='.
-			if !strings.Contains(arg, "=") && knownValueFlags[arg] &&
-				i+1 < len(args) && !strings.HasPrefix(args[i+1], "-") {
-				extraArgs = append(extraArgs, args[i+1])
-				i++ // skip the value token
+// restore removes the temporary data directory of an isolated suite
+func (m *suiteInit) restore() error {
+	if m.iso == nil {
+		return nil
+	}
+	return m.iso.Restore()
+}
+
+// suiteResult holds the buffered output, messages and outcome of one suite
+type suiteResult struct {
+	hookutil.Log
+	steps   []output.Result
+	files   []tofutest.FileResult
+	errors  []output.TofuMessage
+	summary suiteSummary
+	err     error
+	// initFailed is set when the suite's module could not be initialized
+	initFailed bool
+}
+
+// suiteSummary is one line of the test summary
+type suiteSummary struct {
+	display string
+	status  string
+	// duration is how long tofu test ran, when it finished
+	duration time.Duration
+}
+
+// testSuite reports the init of the suite and runs tofu test for it once its
+// module is initialized, buffering all output.
+func testSuite(
+	s suite,
+	module tofutest.Module,
+	setup *suiteInit,
+	args []string,
+	runTest func(string, []string, []string) (tofutest.TestResult, error),
+) (result suiteResult) {
+	bin := tofubin.Current()
+	result.summary.display = s.display
+
+	initMsg := output.TofuMessage{Step: "init", RelPath: s.display, Output: setup.out, Timeout: hookutil.Timeout(setup.err)}
+	if setup.skipped {
+		result.AddStatus(output.Running, fmt.Sprintf("Skipping %s init in: %s, already initialized.", bin.Name, s.display))
+		initMsg.Output = "already initialized"
+	} else {
+		result.AddStatus(output.Running, fmt.Sprintf("Running %s init in: %s...", bin.Name, s.display))
+		result.AddOutput(setup.out)
+	}
+	if setup.err != nil {
+		result.steps = append(result.steps, output.Result{TofuMessage: initMsg, Status: output.StatusFailed, Duration: setup.elapsed})
+		result.errors = append(result.errors, initMsg)
+		result.steps = append(result.steps, output.Result{TofuMessage: output.TofuMessage{Step: "test", RelPath: s.display}, Status: output.StatusSkipped})
+		result.summary.status = "init failed"
+		if initMsg.Timeout != "" {
+			result.summary.status = "init timed out after " + initMsg.Timeout
+		}
+		result.err = fmt.Errorf("%s: init failed: %w", s.display, setup.err)
+		result.initFailed = true
+		return result
+	}
+	if setup.skipped {
+		result.steps = append(result.steps, output.Result{TofuMessage: initMsg, Status: output.StatusSkipped})
+	} else {
+		result.steps = append(result.steps, output.Result{TofuMessage: initMsg, Status: output.StatusPassed, Duration: setup.elapsed})
+	}
+
+	result.AddStatus(output.Running, fmt.Sprintf("Running %s test in: %s...", bin.Name, s.display))
+	started := time.Now()
+	tested, err := runTest(module.Dir, setup.env, args)
+	elapsed := time.Since(started)
+	testMsg := output.TofuMessage{Step: "test", RelPath: s.display, Output: tested.Output, Timeout: hookutil.Timeout(err)}
+	if len(tested.Events) == 0 {
+		// The -json output could not be decoded; fall back to scraping text
+		result.files = tofutest.ParseTestOutput(tested.Output)
+	} else {
+		result.files = tested.Files
+		testMsg.Output = strings.TrimSpace(tofutest.RenderTree(tested.Files) + "\n" + output.FormatDiagnostics(tested.Diagnostics))
+	}
+	result.AddOutput(testMsg.Output)
+	status := output.StatusPassed
+	result.summary.status = "passed " + countRuns(result.files)
+	result.summary.duration = elapsed
+	if err != nil {
+		status = output.StatusFailed
+		result.errors = append(result.errors, testMsg)
+		result.summary.status = "failed " + countRuns(result.files)
+		if testMsg.Timeout != "" {
+			result.summary.status = "timed out after " + testMsg.Timeout
+			result.summary.duration = 0
+		}
+		result.err = fmt.Errorf("%s: %w", s.display, err)
+	}
+	result.steps = append(result.steps, output.Result{TofuMessage: testMsg, Status: status, Duration: elapsed})
+	return result
+}
+
+// countRuns describes how many run blocks passed, failed and were skipped,
+// and how many assertions failed when tofu reported them
+func countRuns(files []tofutest.FileResult) string {
+	passed, failed, skipped, assertions := 0, 0, 0, 0
+	for _, file := range files {
+		for _, run := range file.Runs {
+			assertions += len(run.Assertions)
+			switch run.Status {
+			case tofutest.StatusPass:
+				passed++
+			case tofutest.StatusSkip:
+				skipped++
+			default:
+				failed++
 			}
 		}
 	}
-	return extraArgs
+	if assertions > 0 {
+		return fmt.Sprintf("(%d passed, %d failed, %d skipped; %d assertion(s) failed)", passed, failed, skipped, assertions)
+	}
+	return fmt.Sprintf("(%d passed, %d failed, %d skipped)", passed, failed, skipped)
+}
+
+// printSuiteSummary lists each suite with its outcome and how long it ran
+func printSuiteSummary(summaries []suiteSummary) {
+	output.PrintStatus(output.Running, "Test Summary:", output.Green)
+	fmt.Println()
+	for _, summary := range summaries {
+		if summary.duration > 0 {
+			fmt.Printf("    %s: %s in %s\n", summary.display, summary.status, summary.duration.Round(time.Millisecond))
+			continue
+		}
+		fmt.Printf("    %s: %s\n", summary.display, summary.status)
+	}
+	fmt.Println()
+}
+
+// testDirectory returns the -test-directory given in args, or the default
+func testDirectory(args []string) string {
+	for i, arg := range args {
+		if value, ok := strings.CutPrefix(arg, "-test-directory="); ok {
+			return value
+		}
+		if arg == "-test-directory" && i+1 < len(args) {
+			return args[i+1]
+		}
+	}
+	return tofutest.DefaultTestDirectory
+}
+
+// affectedModules returns the test modules affected by the staged files: the
+// modules owning a file, and those calling an affected module through a local
+// module source, transitively
+func affectedModules(rootDir string, opts discover.Options, testModules []tofutest.Module, files []string) []tofutest.Module {
+	// Modules without tests can still pass a change on to their callers
+	found, err := discover.Walk(rootDir, opts, tofubin.Current().IsConfigFile)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: Error scanning directories: %v\n", err)
+	}
+	dirs := found.Dirs
+	for _, module := range testModules {
+		if !slices.Contains(dirs, module.Dir) {
+			dirs = append(dirs, module.Dir)
+		}
+	}
+	affected := modules.Affected(dirs, hookutil.AbsPaths(rootDir, files))
+	var result []tofutest.Module
+	for _, module := range testModules {
+		if slices.Contains(affected, module.Dir) {
+			result = append(result, module)
+		}
+	}
+	return result
+}
+
+// configuredModules splits testModules into those passing the include and
+// exclude globs of the configuration file and those it lists to skip;
+// excluded modules are dropped
+func configuredModules(hook config.Hook, rootDir string, testModules []tofutest.Module) (selected, skipped []tofutest.Module) {
+	for _, module := range testModules {
+		rel := hookutil.RelPath(rootDir, module.Dir)
+		switch {
+		case !hook.Selected(rel):
+		case hook.Skipped(rel):
+			skipped = append(skipped, module)
+		default:
+			selected = append(selected, module)
+		}
+	}
+	return selected, skipped
+}
+
+// testArgs returns the args for tofu test in the suite. When the suite selects
+// its files and the args select none themselves, a -filter is added for each
+// of the suite's files, as tofu test would otherwise run every file.
+func testArgs(extraArgs []string, s suite) []string {
+	if !s.filter || hasFilter(extraArgs) {
+		return extraArgs
+	}
+	args := append([]string(nil), extraArgs...)
+	for _, file := range s.files {
+		args = append(args, "-filter="+file)
+	}
+	return args
+}
+
+// hasFilter reports whether args include a -filter
+func hasFilter(args []string) bool {
+	for _, arg := range args {
+		if arg == "-filter" || strings.HasPrefix(arg, "-filter=") {
+			return true
+		}
+	}
+	return false
+}
+
+// finishEarly lists the skipped paths and writes the reports, with the test
+// step as skipped, when there is nothing to test
+func finishEarly(opts options, skipped []output.Result, exit func(int)) error {
+	results := append([]output.Result{{TofuMessage: output.TofuMessage{Step: "test", RelPath: "."}, Status: output.StatusSkipped}}, skipped...)
+	return hookutil.FinishEarly(skipped, func() error { return writeReports(opts, nil, results...) }, exit)
+}
+
+// runAnnotations returns an error annotation for each failed run block, and
+// for each test file that failed outside its run blocks, located at the first
+// diagnostic when tofu printed one. Paths in files are relative to the module
+// directory, moduleDir relative to the repository root.
+func runAnnotations(moduleDir string, files []tofutest.FileResult) []output.Annotation {
+	var annotations []output.Annotation
+	annotate := func(file, title, status, text string) {
+		annotation := output.Annotation{Level: "error", File: path.Join(moduleDir, file), Title: title, Message: strings.TrimSpace(text)}
+		if file, line := tofutest.Location(text); file != "" {
+			annotation.File, annotation.Line = path.Join(moduleDir, file), line
+		}
+		if annotation.Message == "" {
+			annotation.Message = title + ": " + status
+		}
+		annotations = append(annotations, annotation)
+	}
+	for _, file := range files {
+		failedRuns := 0
+		for _, run := range file.Runs {
+			if run.Status == tofutest.StatusFail || run.Status == tofutest.StatusError {
+				annotate(file.Path, fmt.Sprintf("run %q", run.Name), run.Status, run.Output)
+				failedRuns++
+			}
+		}
+		if failedRuns == 0 && (file.Status == tofutest.StatusFail || file.Status == tofutest.StatusError) {
+			annotate(file.Path, file.Path, file.Status, file.Output)
+		}
+	}
+	return annotations
+}
+
+// writeReports writes the JSON and JUnit reports requested in opts
+func writeReports(opts options, files []tofutest.FileResult, results ...output.Result) error {
+	if opts.ReportJSON != "" {
+		if err := output.WriteJSONReport(opts.ReportJSON, output.NewReport("tofutest", results)); err != nil {
+			output.PrintStatus(output.Error, fmt.Sprintf("Could not write JSON report: %v", err), output.Red)
+			return err
+		}
+	}
+	if opts.ReportJUnit != "" {
+		if err := tofutest.WriteJUnit(opts.ReportJUnit, tofutest.NewJUnitReport(files)); err != nil {
+			output.PrintStatus(output.Error, fmt.Sprintf("Could not write JUnit report: %v", err), output.Red)
+			return err
+		}
+	}
+	return nil
+}
+
+// printStatus prints a colored emoji status message
+func printStatus(emoji, msg string) {
+	output.PrintStatus(emoji, msg, output.Green)
+}
+
+// loadOptions parses args merged with the test settings of the configuration
+// file in the working directory, the repository root under pre-commit
+func loadOptions(args []string) (options, error) {
+	wd, err := os.Getwd()
+	if err != nil {
+		return options{}, err
+	}
+	hook, err := config.LoadHook(wd, "test")
+	if err != nil {
+		return options{}, err
+	}
+	opts := parseArgs(hook.Merge(args))
+	opts.Config = hook
+	if opts.Jobs < 1 {
+		return opts, fmt.Errorf("invalid -jobs value: must be a positive integer")
+	}
+	opts.Discover.Git, err = discover.ParseGitMode(string(opts.Discover.Git))
+	return opts, err
+}
+
+// parseArgs splits the command line into hook options and the flags forwarded
+// to tofu test. Equals-form flags (-flag=value) are kept as a single token.
+// Split-form flags (-flag value) are kept as two tokens, but only for flags
+// known to accept a value argument — boolean flags will not accidentally
+// consume the next token. Other tokens, and everything after "--", are the
+// staged files passed by pre-commit, which restrict the run to the modules
+// they affect unless -all is given.
+func parseArgs(args []string) options {
+	parsed := cliargs.Parse(args, hookFlags, knownValueFlags)
+	opts := options{
+		ExtraArgs:   parsed.Extra,
+		ReportJSON:  parsed.String("report-json", ""),
+		ReportJUnit: parsed.String("report-junit", ""),
+
+		GitHubAnnotations: parsed.BoolEnv("github-annotations", output.GitHubActionsEnv, false),
+		Binary:            parsed.String("binary", ""),
+		TofuVersion:       parsed.String(tofuversion.Flag, ""),
+		Discover: discover.Options{
+			Exclude: parsed.List("exclude"),
+			Git:     discover.GitMode(parsed.String("git-discovery", "")),
+		},
+		Timeouts: parsed.List(runner.Flag),
+		Files:    parsed.Files,
+		All:      parsed.Bool("all") || os.Getenv(prePushEnv) != "",
+		Jobs:     1,
+		PerFile:  parsed.Bool("per-file"),
+
+		PluginCache:    !parsed.Bool("no-plugin-cache"),
+		PluginCacheDir: parsed.String("plugin-cache-dir", ""),
+	}
+	if parsed.Has("jobs") {
+		// An invalid value is left below one for loadOptions to reject
+		opts.Jobs, _ = strconv.Atoi(parsed.String("jobs", ""))
+	}
+	return opts
 }
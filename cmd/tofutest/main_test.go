@@ -1,8 +1,28 @@
 package main
 
 import (
+	"encoding/json"
+	"encoding/xml"
 	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
+
+	"pre-commit-hooks/internal/config"
+	"pre-commit-hooks/internal/discover"
+	"pre-commit-hooks/internal/hookutil"
+	"pre-commit-hooks/internal/output"
+	"pre-commit-hooks/internal/runner"
+	"pre-commit-hooks/internal/testutil"
+	"pre-commit-hooks/internal/tofubin"
+	"pre-commit-hooks/internal/tofuenv"
+	tofutest "pre-commit-hooks/internal/tofutest"
 )
 
 func TestRunTofuTestCLI_TofuNotInstalled(t *testing.T) {
@@ -11,15 +31,15 @@ func TestRunTofuTestCLI_TofuNotInstalled(t *testing.T) {
 	
 	checkInstalled := func() bool { return false }
 	getwd := func() (string, error) { return "/fake", nil }
-	hasTestFiles := func(string) (bool, error) { return true, nil }
-	runTest := func(string, []string) (string, error) { return "", nil }
+	hasTestFiles := foundTests(true, nil)
+	runTest := textResult("", nil)
 	printStatus := func(string, string) {}
 	exit := func(code int) { 
 		exitCode = code
 		called = true
 	}
 
-	err := RunTofuTestCLI(nil, checkInstalled, getwd, hasTestFiles, runTest, printStatus, exit)
+	err := RunTofuTestCLI(options{}, checkInstalled, getwd, hasTestFiles, initOK, runTest, printStatus, exit)
 	
 	if err == nil {
 		t.Error("Expected error when tofu not installed, got nil")
@@ -38,15 +58,15 @@ func TestRunTofuTestCLI_GetwdError(t *testing.T) {
 	
 	checkInstalled := func() bool { return true }
 	getwd := func() (string, error) { return "", errors.New("getwd failed") }
-	hasTestFiles := func(string) (bool, error) { return true, nil }
-	runTest := func(string, []string) (string, error) { return "", nil }
+	hasTestFiles := foundTests(true, nil)
+	runTest := textResult("", nil)
 	printStatus := func(string, string) {}
 	exit := func(code int) { 
 		exitCode = code
 		called = true
 	}
 
-	err := RunTofuTestCLI(nil, checkInstalled, getwd, hasTestFiles, runTest, printStatus, exit)
+	err := RunTofuTestCLI(options{}, checkInstalled, getwd, hasTestFiles, initOK, runTest, printStatus, exit)
 	
 	if err == nil {
 		t.Error("Expected error when getwd fails, got nil")
@@ -65,15 +85,15 @@ func TestRunTofuTestCLI_NoTestFiles(t *testing.T) {
 	
 	checkInstalled := func() bool { return true }
 	getwd := func() (string, error) { return "/fake", nil }
-	hasTestFiles := func(string) (bool, error) { return false, nil }
-	runTest := func(string, []string) (string, error) { return "", nil }
+	hasTestFiles := foundTests(false, nil)
+	runTest := textResult("", nil)
 	printStatus := func(string, string) {}
 	exit := func(code int) { 
 		exitCode = code
 		called = true
 	}
 
-	err := RunTofuTestCLI(nil, checkInstalled, getwd, hasTestFiles, runTest, printStatus, exit)
+	err := RunTofuTestCLI(options{}, checkInstalled, getwd, hasTestFiles, initOK, runTest, printStatus, exit)
 	
 	if err != nil {
 		t.Errorf("Expected no error when no test files, got %v", err)
@@ -86,44 +106,40 @@ func TestRunTofuTestCLI_NoTestFiles(t *testing.T) {
 	}
 }
 
-func TestRunTofuTestCLI_HasTestFilesError(t *testing.T) {
-	called := false
-	exitCode := 0
-	
+func TestRunTofuTestCLI_DiscoveryErrorWarns(t *testing.T) {
 	checkInstalled := func() bool { return true }
 	getwd := func() (string, error) { return "/fake", nil }
-	hasTestFiles := func(string) (bool, error) { return false, errors.New("walk error") }
-	runTest := func(string, []string) (string, error) { return "", nil }
 	printStatus := func(string, string) {}
-	exit := func(code int) { 
-		exitCode = code
-		called = true
-	}
 
-	err := RunTofuTestCLI(nil, checkInstalled, getwd, hasTestFiles, runTest, printStatus, exit)
-	
-	if err == nil {
-		t.Error("Expected error when hasTestFiles fails, got nil")
-	}
-	if !called {
-		t.Error("Expected exit to be called")
-	}
-	if exitCode != 1 {
-		t.Errorf("Expected exit code 1, got %d", exitCode)
+	for _, found := range []bool{false, true} {
+		exitCode := 0
+		exit := func(code int) { exitCode = code }
+		tested := false
+		runTest := func(string, []string, []string) (tofutest.TestResult, error) {
+			tested = true
+			return tofutest.TestResult{}, nil
+		}
+		err := RunTofuTestCLI(options{}, checkInstalled, getwd, foundTests(found, errors.New("not a git repository")), initOK, runTest, printStatus, exit)
+		if err != nil || exitCode != 0 {
+			t.Errorf("found=%v: err = %v, exit code %d; want the discovery error to be only a warning", found, err, exitCode)
+		}
+		if tested != found {
+			t.Errorf("found=%v: tofu test ran = %v", found, tested)
+		}
 	}
 }
 
 func TestRunTofuTestCLI_TestSuccess(t *testing.T) {
 	checkInstalled := func() bool { return true }
 	getwd := func() (string, error) { return "/fake", nil }
-	hasTestFiles := func(string) (bool, error) { return true, nil }
-	runTest := func(string, []string) (string, error) { return "All tests passed", nil }
+	hasTestFiles := foundTests(true, nil)
+	runTest := textResult("All tests passed", nil)
 	printStatus := func(string, string) {}
 	exit := func(code int) { 
 		t.Error("Exit should not be called on success")
 	}
 
-	err := RunTofuTestCLI(nil, checkInstalled, getwd, hasTestFiles, runTest, printStatus, exit)
+	err := RunTofuTestCLI(options{}, checkInstalled, getwd, hasTestFiles, initOK, runTest, printStatus, exit)
 	
 	if err != nil {
 		t.Errorf("Expected no error when tests pass, got %v", err)
@@ -137,15 +153,15 @@ func TestRunTofuTestCLI_TestFailure(t *testing.T) {
 
 	checkInstalled := func() bool { return true }
 	getwd := func() (string, error) { return "/fake", nil }
-	hasTestFiles := func(string) (bool, error) { return true, nil }
-	runTest := func(string, []string) (string, error) { return "Test failed", rootCause }
+	hasTestFiles := foundTests(true, nil)
+	runTest := textResult("Test failed", rootCause)
 	printStatus := func(string, string) {}
 	exit := func(code int) {
 		exitCode = code
 		called = true
 	}
 
-	err := RunTofuTestCLI(nil, checkInstalled, getwd, hasTestFiles, runTest, printStatus, exit)
+	err := RunTofuTestCLI(options{}, checkInstalled, getwd, hasTestFiles, initOK, runTest, printStatus, exit)
 
 	if err == nil {
 		t.Error("Expected error when tests fail, got nil")
@@ -161,21 +177,57 @@ func TestRunTofuTestCLI_TestFailure(t *testing.T) {
 	}
 }
 
+func TestRunTofuTestCLI_Timeout(t *testing.T) {
+	defer runner.SetTimeouts(runner.SetTimeouts(nil))
+	reportPath := filepath.Join(t.TempDir(), "report.json")
+	exitCode := 0
+	var err error
+	out := testutil.CaptureStdout(t, func() {
+		err = RunTofuTestCLI(
+			options{Timeouts: []string{"2m"}, ReportJSON: reportPath},
+			func() bool { return true },
+			func() (string, error) { return "/fake", nil },
+			foundTests(true, nil),
+			initOK,
+			textResult("main.tftest.hcl... in progress", &runner.TimeoutError{Step: "test", Timeout: 2 * time.Minute}),
+			func(string, string) {},
+			func(code int) { exitCode = code },
+		)
+	})
+	if _, ok := runner.TimedOut(err); !ok || exitCode != 1 {
+		t.Errorf("RunTofuTestCLI() = %v, exit %d; want a timeout error and exit 1", err, exitCode)
+	}
+	for _, want := range []string{"OpenTofu test timed out after 2m0s in: fake", "    fake: timed out after 2m0s\n"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("Expected output to contain %q, got:\n%s", want, out)
+		}
+	}
+	if data, _ := os.ReadFile(reportPath); !strings.Contains(string(data), `"timeout": "2m0s"`) {
+		t.Errorf("Expected the timeout in the JSON report, got:\n%s", data)
+	}
+
+	exitCode = 0
+	err = RunTofuTestCLI(options{Timeouts: []string{"validate=1m"}}, func() bool { return true }, func() (string, error) { return "/fake", nil }, foundTests(true, nil), initOK, nil, func(string, string) {}, func(code int) { exitCode = code })
+	if err == nil || exitCode != 1 {
+		t.Errorf("RunTofuTestCLI() = %v, exit %d; want an error for a step tofu-test does not run", err, exitCode)
+	}
+}
+
 func TestRunTofuTestCLI_ExtraArgs(t *testing.T) {
 	var receivedArgs []string
 	
 	checkInstalled := func() bool { return true }
 	getwd := func() (string, error) { return "/fake", nil }
-	hasTestFiles := func(string) (bool, error) { return true, nil }
-	runTest := func(dir string, args []string) (string, error) { 
+	hasTestFiles := foundTests(true, nil)
+	runTest := func(dir string, _ []string, args []string) (tofutest.TestResult, error) { 
 		receivedArgs = args
-		return "Tests passed", nil 
+		return tofutest.TestResult{Output: "Tests passed"}, nil 
 	}
 	printStatus := func(string, string) {}
 	exit := func(code int) {}
 
 	extraArgs := []string{"-verbose", "-filter=TestFoo"}
-	err := RunTofuTestCLI(extraArgs, checkInstalled, getwd, hasTestFiles, runTest, printStatus, exit)
+	err := RunTofuTestCLI(options{ExtraArgs: extraArgs}, checkInstalled, getwd, hasTestFiles, initOK, runTest, printStatus, exit)
 	
 	if err != nil {
 		t.Errorf("Expected no error, got %v", err)
@@ -191,72 +243,922 @@ func TestRunTofuTestCLI_ExtraArgs(t *testing.T) {
 	}
 }
 
+func TestRunTofuTestCLI_ReportJSON(t *testing.T) {
+	cases := []struct {
+		name       string
+		hasTests   bool
+		testErr    error
+		wantStatus string
+		wantSteps  string
+	}{
+		{"passed", true, nil, "passed", "init test"},
+		{"failed", true, errors.New("test error"), "failed", "init test"},
+		{"no test files", false, nil, "skipped", "test"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			reportPath := filepath.Join(t.TempDir(), "report.json")
+			err := RunTofuTestCLI(
+				options{ReportJSON: reportPath},
+				func() bool { return true },
+				func() (string, error) { return "/fake", nil },
+				foundTests(tc.hasTests, nil),
+				initOK,
+				textResult("run output", tc.testErr),
+				func(string, string) {},
+				func(int) {},
+			)
+			if (err != nil) != (tc.testErr != nil) {
+				t.Fatalf("RunTofuTestCLI() error = %v, want error %v", err, tc.testErr != nil)
+			}
+			data, err := os.ReadFile(reportPath)
+			if err != nil {
+				t.Fatalf("report not written: %v", err)
+			}
+			var report struct {
+				Hook    string `json:"hook"`
+				Status  string `json:"status"`
+				Results []struct {
+					Step   string `json:"step"`
+					Status string `json:"status"`
+				} `json:"results"`
+			}
+			if err := json.Unmarshal(data, &report); err != nil {
+				t.Fatalf("invalid report JSON: %v", err)
+			}
+			if report.Hook != "tofutest" || report.Status != tc.wantStatus {
+				t.Errorf("report = %s/%s, want tofutest/%s", report.Hook, report.Status, tc.wantStatus)
+			}
+			var steps []string
+			for _, result := range report.Results {
+				steps = append(steps, result.Step)
+			}
+			if strings.Join(steps, " ") != tc.wantSteps {
+				t.Errorf("results = %+v, want steps %s", report.Results, tc.wantSteps)
+			}
+		})
+	}
+}
+
+func TestRunTofuTestCLI_ReportJUnit(t *testing.T) {
+	reportPath := filepath.Join(t.TempDir(), "junit.xml")
+	testOutput := "main.tftest.hcl... in progress\n  run \"ok\"... pass\n  run \"bad\"... fail\n\nError: Test assertion failed\n\nmain.tftest.hcl... tearing down\nmain.tftest.hcl... fail\n\nFailure! 1 passed, 1 failed.\n"
+	err := RunTofuTestCLI(
+		options{ReportJUnit: reportPath},
+		func() bool { return true },
+		func() (string, error) { return "/fake", nil },
+		foundTests(true, nil),
+		initOK,
+		textResult(testOutput, errors.New("exit status 1")),
+		func(string, string) {},
+		func(int) {},
+	)
+	if err == nil {
+		t.Fatal("Expected error for failed tests")
+	}
+	data, err := os.ReadFile(reportPath)
+	if err != nil {
+		t.Fatalf("JUnit report not written: %v", err)
+	}
+	var report tofutest.JUnitTestSuites
+	if err := xml.Unmarshal(data, &report); err != nil {
+		t.Fatalf("invalid JUnit XML: %v", err)
+	}
+	if report.Tests != 2 || report.Failures != 1 || len(report.Suites) != 1 || report.Suites[0].Name != "main.tftest.hcl" {
+		t.Errorf("Unexpected JUnit report:\n%s", data)
+	}
+}
+
+func TestRunTofuTestCLI_Modules(t *testing.T) {
+	root := filepath.FromSlash("/repo")
+	findTests := func(string, discover.Options) (discover.Result, error) {
+		return discover.Result{Files: []string{
+			filepath.Join(root, "tests", "main.tftest.hcl"),
+			filepath.Join(root, "modules", "net", "net.tftest.hcl"),
+			filepath.Join(root, "modules", "db", "tests", "db.tftest.hcl"),
+		}}, nil
+	}
+	var initDirs, testDirs []string
+	runCmd := func(dir string, _ []string, args []string) (string, error) {
+		initDirs = append(initDirs, dir)
+		if filepath.Base(dir) == "db" {
+			return "Error: Failed to query available provider packages", errors.New("exit status 1")
+		}
+		return "", nil
+	}
+	runTest := func(dir string, _ []string, args []string) (tofutest.TestResult, error) {
+		testDirs = append(testDirs, dir)
+		if filepath.Base(dir) == "net" {
+			return tofutest.TestResult{Output: "net.tftest.hcl... in progress\n  run \"cidr\"... fail\nnet.tftest.hcl... fail\n"}, errors.New("exit status 1")
+		}
+		return tofutest.TestResult{Output: "tests/main.tftest.hcl... in progress\n  run \"ok\"... pass\ntests/main.tftest.hcl... pass\n"}, nil
+	}
+	reportPath := filepath.Join(t.TempDir(), "junit.xml")
+	exitCode := 0
+	var err error
+	out := testutil.CaptureStdout(t, func() {
+		err = RunTofuTestCLI(
+			options{ReportJUnit: reportPath},
+			func() bool { return true },
+			func() (string, error) { return root, nil },
+			findTests,
+			runCmd,
+			runTest,
+			func(string, string) {},
+			func(code int) { exitCode = code },
+		)
+	})
+	if err == nil || exitCode != 1 {
+		t.Fatalf("RunTofuTestCLI() = %v, exit %d; want failure", err, exitCode)
+	}
+
+	wantDirs := []string{root, filepath.Join(root, "modules", "db"), filepath.Join(root, "modules", "net")}
+	if strings.Join(initDirs, ",") != strings.Join(wantDirs, ",") {
+		t.Errorf("init ran in %v, want %v", initDirs, wantDirs)
+	}
+	if strings.Join(testDirs, ",") != strings.Join([]string{wantDirs[0], wantDirs[2]}, ",") {
+		t.Errorf("test ran in %v, want every module whose init passed", testDirs)
+	}
+	for _, want := range []string{
+		"    repo/modules/db: init failed\n",
+		"    repo/modules/net: failed (0 passed, 1 failed, 0 skipped) in ",
+		"    repo: passed (1 passed, 0 failed, 0 skipped) in ",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("Expected output to contain %q, got:\n%s", want, out)
+		}
+	}
+
+	data, err := os.ReadFile(reportPath)
+	if err != nil {
+		t.Fatalf("JUnit report not written: %v", err)
+	}
+	var report tofutest.JUnitTestSuites
+	if err := xml.Unmarshal(data, &report); err != nil {
+		t.Fatalf("invalid JUnit XML: %v", err)
+	}
+	var suites []string
+	for _, suite := range report.Suites {
+		suites = append(suites, suite.Name)
+	}
+	if strings.Join(suites, ",") != "tests/main.tftest.hcl,modules/net/net.tftest.hcl" {
+		t.Errorf("JUnit suites = %v, want paths relative to the repository root", suites)
+	}
+}
+
+func TestRunTofuTestCLI_InitWhenNeeded(t *testing.T) {
+	t.Setenv("TF_DATA_DIR", "")
+	root := t.TempDir()
+	cacheDir := filepath.Join(t.TempDir(), "cache")
+	for _, dir := range []string{"ready/.terraform", "fresh", "broken"} {
+		if err := os.MkdirAll(filepath.Join(root, filepath.FromSlash(dir)), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	findTests := func(string, discover.Options) (discover.Result, error) {
+		return discover.Result{Files: []string{
+			filepath.Join(root, "broken", "main.tftest.hcl"),
+			filepath.Join(root, "fresh", "main.tftest.hcl"),
+			filepath.Join(root, "ready", "main.tftest.hcl"),
+		}}, nil
+	}
+	var initDirs []string
+	runCmd := func(dir string, env []string, args []string) (string, error) {
+		initDirs = append(initDirs, filepath.Base(dir))
+		if strings.Join(env, " ") != "TF_PLUGIN_CACHE_DIR="+cacheDir {
+			t.Errorf("init env = %v, want the plugin cache", env)
+		}
+		if filepath.Base(dir) == "broken" {
+			return "Error: Module not installed", errors.New("exit status 1")
+		}
+		return "- Installing hashicorp/null v3.2.2...", nil
+	}
+	var statuses []string
+	reportPath := filepath.Join(t.TempDir(), "report.json")
+	var err error
+	out := testutil.CaptureStdout(t, func() {
+		err = RunTofuTestCLI(
+			options{ReportJSON: reportPath, PluginCache: true, PluginCacheDir: cacheDir},
+			func() bool { return true },
+			func() (string, error) { return root, nil },
+			findTests,
+			runCmd,
+			textResult("main.tftest.hcl... in progress\n  run \"ok\"... pass\nmain.tftest.hcl... pass\n", nil),
+			func(_, msg string) { statuses = append(statuses, msg) },
+			func(int) {},
+		)
+	})
+	if err == nil {
+		t.Fatal("RunTofuTestCLI() succeeded, want the failed init reported")
+	}
+	if strings.Join(initDirs, ",") != "broken,fresh" {
+		t.Errorf("init ran in %v, want only the uninitialized modules", initDirs)
+	}
+	base := filepath.Base(root)
+	for _, want := range []string{
+		"Skipping tofu init in: " + base + "/ready, already initialized.",
+		"Plugin cache " + cacheDir + ": 0 hit(s), 1 miss(es)",
+		"OpenTofu init failed in 1 and test failed in 0 of 3 suite(s).",
+	} {
+		if !strings.Contains(strings.Join(statuses, "\n"), want) {
+			t.Errorf("Expected status %q, got %v", want, statuses)
+		}
+	}
+	if want := "    " + base + "/broken: init failed\n"; !strings.Contains(out, want) {
+		t.Errorf("Expected output to contain %q, got:\n%s", want, out)
+	}
+
+	data, err := os.ReadFile(reportPath)
+	if err != nil {
+		t.Fatalf("report not written: %v", err)
+	}
+	var report struct {
+		Results []struct {
+			Step   string `json:"step"`
+			Status string `json:"status"`
+		} `json:"results"`
+	}
+	if err := json.Unmarshal(data, &report); err != nil {
+		t.Fatalf("invalid report JSON: %v", err)
+	}
+	var steps []string
+	for _, result := range report.Results {
+		steps = append(steps, result.Step+":"+result.Status)
+	}
+	want := "init:failed test:skipped init:passed test:passed init:skipped test:passed"
+	if strings.Join(steps, " ") != want {
+		t.Errorf("results = %v, want %s", steps, want)
+	}
+}
+
+func TestRunTofuTestCLI_PluginCacheSerialized(t *testing.T) {
+	root := t.TempDir()
+	cacheDir := filepath.Join(t.TempDir(), "cache")
+	findTests := func(string, discover.Options) (discover.Result, error) {
+		return discover.Result{Files: []string{
+			filepath.Join(root, "a", "main.tftest.hcl"),
+			filepath.Join(root, "b", "main.tftest.hcl"),
+			filepath.Join(root, "c", "main.tftest.hcl"),
+		}}, nil
+	}
+	var running, overlapped atomic.Int32
+	runCmd := func(dir string, env []string, args []string) (string, error) {
+		if running.Add(1) > 1 {
+			overlapped.Store(1)
+		}
+		defer running.Add(-1)
+		time.Sleep(20 * time.Millisecond)
+		if len(env) == 0 || env[0] != "TF_PLUGIN_CACHE_DIR="+cacheDir {
+			t.Errorf("init env = %v, want the plugin cache first", env)
+		}
+		return "", nil
+	}
+	var err error
+	testutil.CaptureStdout(t, func() {
+		err = RunTofuTestCLI(
+			options{Jobs: 3, PluginCache: true, PluginCacheDir: cacheDir},
+			func() bool { return true },
+			func() (string, error) { return root, nil },
+			findTests,
+			runCmd,
+			textResult("", nil),
+			func(string, string) {},
+			func(int) {},
+		)
+	})
+	if err != nil {
+		t.Fatalf("RunTofuTestCLI() error: %v", err)
+	}
+	if overlapped.Load() != 0 {
+		t.Error("init runs that may download into the plugin cache overlapped, want them serialized")
+	}
+}
+
+func TestRunTofuTestCLI_PluginCacheWarm(t *testing.T) {
+	root := t.TempDir()
+	cacheDir := filepath.Join(t.TempDir(), "cache")
+	lock := "provider \"registry.opentofu.org/hashicorp/null\" {\n  version = \"3.2.2\"\n}\n"
+	for _, dir := range []string{"a", "b"} {
+		if err := os.Mkdir(filepath.Join(root, dir), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(root, dir, tofuenv.LockFile), []byte(lock), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.MkdirAll(filepath.Join(cacheDir, "registry.opentofu.org", "hashicorp", "null", "3.2.2", runtime.GOOS+"_"+runtime.GOARCH), 0755); err != nil {
+		t.Fatal(err)
+	}
+	findTests := func(string, discover.Options) (discover.Result, error) {
+		return discover.Result{Files: []string{filepath.Join(root, "a", "main.tftest.hcl"), filepath.Join(root, "b", "main.tftest.hcl")}}, nil
+	}
+
+	// Each init waits for the other, which only works when they run together
+	var started sync.WaitGroup
+	started.Add(2)
+	both := make(chan struct{})
+	go func() { started.Wait(); close(both) }()
+	runCmd := func(dir string, env []string, args []string) (string, error) {
+		started.Done()
+		select {
+		case <-both:
+		case <-time.After(2 * time.Second):
+			return "", fmt.Errorf("init in %s ran alone", dir)
+		}
+		return "- Using hashicorp/null v3.2.2 from the shared cache directory", nil
+	}
+	var err error
+	testutil.CaptureStdout(t, func() {
+		err = RunTofuTestCLI(
+			options{Jobs: 2, PluginCache: true, PluginCacheDir: cacheDir},
+			func() bool { return true },
+			func() (string, error) { return root, nil },
+			findTests,
+			runCmd,
+			textResult("", nil),
+			func(string, string) {},
+			func(int) {},
+		)
+	})
+	if err != nil {
+		t.Errorf("Expected the inits reading from a warm plugin cache to run concurrently, got: %v", err)
+	}
+}
+
+func TestRunTofuTestCLI_ConfigFilters(t *testing.T) {
+	root := filepath.FromSlash("/repo")
+	findTests := func(string, discover.Options) (discover.Result, error) {
+		return discover.Result{Files: []string{
+			filepath.Join(root, "main.tftest.hcl"),
+			filepath.Join(root, "modules", "a", "a.tftest.hcl"),
+			filepath.Join(root, "modules", "b", "b.tftest.hcl"),
+			filepath.Join(root, "modules", "c", "c.tftest.hcl"),
+		}}, nil
+	}
+	hook := config.Hook{
+		Include:     []string{"modules/**"},
+		Skip:        []string{"modules/c"},
+		Directories: map[string]config.Directory{"modules/b": {Args: []string{"-var=name=b"}}},
+	}
+	tested := map[string]string{}
+	var err error
+	out := testutil.CaptureStdout(t, func() {
+		err = RunTofuTestCLI(
+			options{ExtraArgs: []string{"-verbose"}, Config: hook},
+			func() bool { return true },
+			func() (string, error) { return root, nil },
+			findTests,
+			initOK,
+			func(dir string, _ []string, args []string) (tofutest.TestResult, error) {
+				tested[hookutil.RelPath(root, dir)] = strings.Join(args, " ")
+				return tofutest.TestResult{}, nil
+			},
+			func(string, string) {},
+			func(int) {},
+		)
+	})
+	if err != nil {
+		t.Fatalf("RunTofuTestCLI() error: %v", err)
+	}
+	want := map[string]string{"modules/a": "-verbose", "modules/b": "-verbose -var=name=b"}
+	if fmt.Sprint(tested) != fmt.Sprint(want) {
+		t.Errorf("tested %v, want %v", tested, want)
+	}
+	if want := "repo/modules/c (listed in the skip settings)"; !strings.Contains(out, want) {
+		t.Errorf("Expected output to contain %q, got:\n%s", want, out)
+	}
+}
+
+// textResult returns a runTest stand-in printing text instead of -json output,
+// as a tofu test that could not be decoded does
+func textResult(text string, err error) func(string, []string, []string) (tofutest.TestResult, error) {
+	return func(string, []string, []string) (tofutest.TestResult, error) {
+		return tofutest.TestResult{Output: text}, err
+	}
+}
+
+// initOK stands in for tofu init, succeeding without output
+func initOK(string, []string, []string) (string, error) {
+	return "", nil
+}
+
+// foundTests returns a findTests stand-in that finds one test file, or none
+func foundTests(found bool, err error) func(string, discover.Options) (discover.Result, error) {
+	return func(root string, _ discover.Options) (discover.Result, error) {
+		if !found {
+			return discover.Result{}, err
+		}
+		return discover.Result{Files: []string{filepath.Join(root, "main.tftest.hcl")}}, err
+	}
+}
+
+func TestRunTofuTestCLI_IgnoreAndExclude(t *testing.T) {
+	root := t.TempDir()
+	for _, rel := range []string{"tests/main.tftest.hcl", "tests/slow.tftest.hcl", "vendor/mod/mod.tftest.hcl"} {
+		path := filepath.Join(root, rel)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(""), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.WriteFile(filepath.Join(root, "tests", ".tofuignore"), []byte("slow.tftest.hcl\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	opts := parseArgs([]string{"--exclude", "vendor/**", "-verbose"})
+
+	var receivedArgs []string
+	run := func(opts options) string {
+		return testutil.CaptureStdout(t, func() {
+			RunTofuTestCLI(
+				opts,
+				func() bool { return true },
+				func() (string, error) { return root, nil },
+				tofutest.FindTestFiles,
+				initOK,
+				func(dir string, _ []string, args []string) (tofutest.TestResult, error) {
+					receivedArgs = args
+					return tofutest.TestResult{Output: "Success! 1 passed, 0 failed."}, nil
+				},
+				func(string, string) {},
+				func(int) {},
+			)
+		})
+	}
+
+	out := run(opts)
+	if want := []string{"-verbose", "-filter=tests/main.tftest.hcl"}; strings.Join(receivedArgs, " ") != strings.Join(want, " ") {
+		t.Errorf("tofu test args = %v, want %v", receivedArgs, want)
+	}
+	for _, want := range []string{
+		"Skipped Summary:",
+		"tests/slow.tftest.hcl (ignored by tests/.tofuignore)",
+		"vendor (excluded by --exclude vendor/**)",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("Expected output to contain %q, got:\n%s", want, out)
+		}
+	}
+
+	opts.ExtraArgs = []string{"-filter=tests/other.tftest.hcl"}
+	run(opts)
+	if len(receivedArgs) != 1 || receivedArgs[0] != "-filter=tests/other.tftest.hcl" {
+		t.Errorf("tofu test args = %v, want the user's -filter kept as is", receivedArgs)
+	}
+}
+
+func TestRunTofuTestCLI_JSONEvents(t *testing.T) {
+	stream := `{"type":"test_file","test_file":{"path":"tests/main.tftest.hcl","progress":"starting"}}
+{"type":"test_run","test_run":{"path":"tests/main.tftest.hcl","run":"ok","progress":"complete","status":"pass"}}
+{"type":"test_run","test_run":{"path":"tests/main.tftest.hcl","run":"bad","progress":"complete","status":"fail"}}
+{"@testfile":"tests/main.tftest.hcl","@testrun":"bad","type":"diagnostic","diagnostic":{"severity":"error","summary":"Test assertion failed","detail":"Name did not match.","range":{"filename":"tests/main.tftest.hcl","start":{"line":12}}}}
+{"type":"test_file","test_file":{"path":"tests/main.tftest.hcl","progress":"complete","status":"fail"}}
+`
+	events, err := tofutest.ParseTestJSON([]byte(stream))
+	if err != nil {
+		t.Fatal(err)
+	}
+	result := tofutest.TestResult{Events: events, Output: stream}
+	result.Files, result.Diagnostics = tofutest.BuildResults(events)
+
+	reportPath := filepath.Join(t.TempDir(), "junit.xml")
+	out := testutil.CaptureStdout(t, func() {
+		err = RunTofuTestCLI(
+			options{ReportJUnit: reportPath, GitHubAnnotations: true},
+			func() bool { return true },
+			func() (string, error) { return "/fake", nil },
+			foundTests(true, nil),
+			initOK,
+			func(string, []string, []string) (tofutest.TestResult, error) { return result, errors.New("exit status 1") },
+			func(string, string) {},
+			func(int) {},
+		)
+	})
+	if err == nil {
+		t.Fatal("Expected error for failed tests")
+	}
+	for _, want := range []string{
+		"    tests/main.tftest.hcl: fail\n      run \"ok\": pass\n      run \"bad\": fail\n          Assertion failed: Name did not match.\n",
+		"::error file=tests/main.tftest.hcl,line=12,title=run \"bad\"::Error: Test assertion failed",
+		"    fake: failed (1 passed, 1 failed, 0 skipped; 1 assertion(s) failed) in ",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("Expected output to contain %q, got:\n%s", want, out)
+		}
+	}
+	if strings.Contains(out, `"test_run"`) {
+		t.Errorf("Expected the raw JSON messages not to be printed, got:\n%s", out)
+	}
+	data, err := os.ReadFile(reportPath)
+	if err != nil {
+		t.Fatalf("JUnit report not written: %v", err)
+	}
+	if !strings.Contains(string(data), `<failure message="Test assertion failed">`) {
+		t.Errorf("Expected the assertion failure in the JUnit report, got:\n%s", data)
+	}
+}
+
+func TestRunTofuTestCLI_Incremental(t *testing.T) {
+	root := t.TempDir()
+	for rel, content := range map[string]string{
+		"main.tf":                          "module \"net\" {\n  source = \"./modules/net\"\n}\n",
+		"modules/net/main.tf":              "",
+		"modules/net/tests/net.tftest.hcl": "",
+		"modules/db/main.tf":               "module \"vpc\" {\n  source = \"../vpc\"\n}\n",
+		"modules/db/db.tftest.hcl":         "",
+		"modules/vpc/main.tf":              "",
+	} {
+		path := filepath.Join(root, filepath.FromSlash(rel))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	cases := []struct {
+		name  string
+		files []string
+		all   bool
+		want  []string
+	}{
+		{"module", []string{"modules/net/main.tf"}, false, []string{"modules/net"}},
+		{"test file", []string{"modules/net/tests/net.tftest.hcl"}, false, []string{"modules/net"}},
+		{"module without tests", []string{"modules/vpc/variables.tf"}, false, []string{"modules/db"}},
+		{"module without tests calling one", []string{"main.tf"}, false, nil},
+		{"all", []string{"main.tf"}, true, []string{"modules/db", "modules/net"}},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			var tested []string
+			exitCode := -1
+			testutil.CaptureStdout(t, func() {
+				RunTofuTestCLI(
+					options{Files: tc.files, All: tc.all},
+					func() bool { return true },
+					func() (string, error) { return root, nil },
+					tofutest.FindTestFiles,
+					initOK,
+					func(dir string, _ []string, args []string) (tofutest.TestResult, error) {
+						tested = append(tested, hookutil.RelPath(root, dir))
+						return tofutest.TestResult{}, nil
+					},
+					func(string, string) {},
+					func(code int) { exitCode = code },
+				)
+			})
+			if strings.Join(tested, ",") != strings.Join(tc.want, ",") {
+				t.Errorf("tested %v, want %v", tested, tc.want)
+			}
+			if tc.want == nil && exitCode != 0 {
+				t.Errorf("exit code = %d, want 0 when no module is affected", exitCode)
+			}
+		})
+	}
+}
+
+func TestRunTofuTestCLI_JobsPerFile(t *testing.T) {
+	root := t.TempDir()
+	findTests := func(string, discover.Options) (discover.Result, error) {
+		return discover.Result{Files: []string{
+			filepath.Join(root, "tests", "a.tftest.hcl"),
+			filepath.Join(root, "tests", "b.tftest.hcl"),
+			filepath.Join(root, "net", "c.tftest.hcl"),
+		}}, nil
+	}
+	var mu sync.Mutex
+	inits := map[string]string{} // data directory env -> module
+	dataDirs := map[string]string{}
+	runCmd := func(dir string, env []string, args []string) (string, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		key := strings.Join(env, " ")
+		if _, ok := inits[key]; ok {
+			t.Errorf("init ran twice with %s", key)
+		}
+		inits[key] = dir
+		// Each init writes the lock file, which the snapshot must remove again
+		os.WriteFile(filepath.Join(dir, tofuenv.LockFile), []byte("# "+key+"\n"), 0644)
+		return "init " + filepath.Base(dir), nil
+	}
+	// The suites of the root module each wait for the other, which only
+	// works when they run together
+	var rootStarted sync.WaitGroup
+	rootStarted.Add(2)
+	bothRunning := make(chan struct{})
+	go func() { rootStarted.Wait(); close(bothRunning) }()
+	runTest := func(dir string, env []string, args []string) (tofutest.TestResult, error) {
+		filter := strings.TrimPrefix(strings.Join(args, " "), "-filter=")
+		mu.Lock()
+		dataDirs[filter] = strings.Join(env, " ")
+		mu.Unlock()
+		if dir == root {
+			rootStarted.Done()
+			select {
+			case <-bothRunning:
+			case <-time.After(2 * time.Second):
+				t.Errorf("%s ran alone, want the suites of a module to run in parallel", filter)
+			}
+		}
+		// Earlier suites finish last, to check the output order
+		if filter == "tests/a.tftest.hcl" {
+			time.Sleep(30 * time.Millisecond)
+		}
+		return tofutest.TestResult{Output: filter + "... in progress\n  run \"x\"... pass\n" + filter + "... pass\n"}, nil
+	}
+	var err error
+	out := testutil.CaptureStdout(t, func() {
+		err = RunTofuTestCLI(
+			options{Jobs: 3, PerFile: true},
+			func() bool { return true },
+			func() (string, error) { return root, nil },
+			findTests,
+			runCmd,
+			runTest,
+			func(emoji, msg string) { fmt.Println(msg) },
+			func(code int) { t.Errorf("exit(%d) called on success", code) },
+		)
+	})
+	if err != nil {
+		t.Fatalf("RunTofuTestCLI() error: %v", err)
+	}
+
+	base := filepath.Base(root)
+	if len(inits) != 3 {
+		t.Errorf("init ran with %v, want once per suite, each in its own data directory", inits)
+	}
+	wantModules := map[string]string{"tests/a.tftest.hcl": root, "tests/b.tftest.hcl": root, "c.tftest.hcl": filepath.Join(root, "net")}
+	for file, module := range wantModules {
+		env := dataDirs[file]
+		if !strings.HasPrefix(env, "TF_DATA_DIR=") || inits[env] != module {
+			t.Errorf("%s tested with %q, want the data directory its own init used in %s", file, env, module)
+		}
+		if _, err := os.Stat(strings.TrimPrefix(env, "TF_DATA_DIR=")); !os.IsNotExist(err) {
+			t.Errorf("Expected the data directory of %s to be removed, stat error: %v", file, err)
+		}
+	}
+	for _, dir := range []string{root, filepath.Join(root, "net")} {
+		if _, err := os.Stat(filepath.Join(dir, tofuenv.LockFile)); !os.IsNotExist(err) {
+			t.Errorf("Expected the lock file created in %s to be removed, stat error: %v", dir, err)
+		}
+	}
+
+	// Output is printed in suite order, each suite with its own init
+	order := []string{
+		"Running tofu init in: " + base + "/tests/a.tftest.hcl...",
+		"Running tofu test in: " + base + "/tests/a.tftest.hcl...",
+		"Running tofu init in: " + base + "/tests/b.tftest.hcl...",
+		"Running tofu test in: " + base + "/tests/b.tftest.hcl...",
+		"Running tofu init in: " + base + "/net/c.tftest.hcl...",
+		"Running tofu test in: " + base + "/net/c.tftest.hcl...",
+		"    " + base + "/tests/a.tftest.hcl: passed (1 passed, 0 failed, 0 skipped) in ",
+		"    " + base + "/tests/b.tftest.hcl: passed",
+		"    " + base + "/net/c.tftest.hcl: passed",
+	}
+	last := -1
+	for _, want := range order {
+		i := strings.Index(out, want)
+		if i <= last {
+			t.Fatalf("Expected %q after the previous lines, got:\n%s", want, out)
+		}
+		last = i
+	}
+}
+
+func TestRunTofuTestCLI_GitHubAnnotations(t *testing.T) {
+	testOutput := "tests/main.tftest.hcl... in progress\n  run \"ok\"... pass\n  run \"bad\"... fail\n\u2577\n\u2502 Error: Test assertion failed\n\u2502\n\u2502   on tests/main.tftest.hcl line 12, in run \"bad\":\n\u2575\ntests/main.tftest.hcl... fail\n"
+	out := testutil.CaptureStdout(t, func() {
+		RunTofuTestCLI(
+			options{GitHubAnnotations: true},
+			func() bool { return true },
+			func() (string, error) { return "/fake", nil },
+			foundTests(true, nil),
+			initOK,
+			textResult(testOutput, errors.New("exit status 1")),
+			func(string, string) {},
+			func(int) {},
+		)
+	})
+	for _, want := range []string{
+		"::group::fake\n",
+		"::endgroup::\n",
+		"::error file=tests/main.tftest.hcl,line=12,title=run \"bad\"::Error: Test assertion failed%0A%0A  on tests/main.tftest.hcl line 12, in run \"bad\":\n",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("Expected output to contain %q, got:\n%s", want, out)
+		}
+	}
+	if strings.Count(out, "::error") != 1 {
+		t.Errorf("Expected only the failing run to be annotated, got:\n%s", out)
+	}
+}
+
+func TestRunTofuTestCLI_TofuVersion(t *testing.T) {
+	defer tofubin.Set(tofubin.Current())
+	root := t.TempDir()
+	if err := os.WriteFile(filepath.Join(root, "versions.tf"), []byte("terraform {\n  required_version = \">= 1.6.0\"\n}\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	ran := false
+	exitCode := 0
+	var err error
+	out := testutil.CaptureStdout(t, func() {
+		err = RunTofuTestCLI(
+			options{Binary: testutil.FakeTofu(t, "1.5.7")},
+			func() bool { return true },
+			func() (string, error) { return root, nil },
+			foundTests(true, nil),
+			initOK,
+			func(string, []string, []string) (tofutest.TestResult, error) { ran = true; return tofutest.TestResult{}, nil },
+			func(string, string) {},
+			func(code int) { exitCode = code },
+		)
+	})
+	want := "OpenTofu 1.5.7 is installed, but " + root + " requires >= 1.6.0"
+	if err == nil || err.Error() != want || exitCode != 1 || ran {
+		t.Errorf("Expected %q and exit 1 before running tests, got %v (exit=%d, ran=%v)", want, err, exitCode, ran)
+	}
+	if !strings.Contains(out, want) {
+		t.Errorf("Expected output to contain %q, got:\n%s", want, out)
+	}
+
+	if opts := parseArgs([]string{"--tofu-version", ">= 1.8"}); opts.TofuVersion != ">= 1.8" || len(opts.ExtraArgs) != 0 {
+		t.Errorf("parseArgs() = %+v, want TofuVersion consumed by the hook", opts)
+	}
+}
+
+func TestParseArgs_ReportJSON(t *testing.T) {
+	opts := parseArgs([]string{"-verbose", "--report-json", "out.json", "-filter", "TestFoo", "-report-junit=junit.xml", "--binary", "terraform"})
+	if opts.Binary != "terraform" {
+		t.Errorf("Binary = %q, want terraform", opts.Binary)
+	}
+	if opts.ReportJSON != "out.json" || opts.ReportJUnit != "junit.xml" {
+		t.Errorf("ReportJSON = %q, ReportJUnit = %q; want out.json, junit.xml", opts.ReportJSON, opts.ReportJUnit)
+	}
+	want := []string{"-verbose", "-filter", "TestFoo"}
+	if len(opts.ExtraArgs) != len(want) {
+		t.Fatalf("ExtraArgs = %v, want %v", opts.ExtraArgs, want)
+	}
+	for i, v := range want {
+		if opts.ExtraArgs[i] != v {
+			t.Errorf("ExtraArgs[%d] = %q, want %q", i, opts.ExtraArgs[i], v)
+		}
+	}
+}
+
+func TestParseArgs_FilesAndAll(t *testing.T) {
+	t.Setenv(prePushEnv, "")
+	opts := parseArgs([]string{"-verbose", "main.tf", "modules/net/main.tf"})
+	if opts.All || strings.Join(opts.Files, ",") != "main.tf,modules/net/main.tf" {
+		t.Errorf("Files = %v, All = %v; want the staged files", opts.Files, opts.All)
+	}
+	if opts := parseArgs([]string{"-all", "main.tf"}); !opts.All {
+		t.Error("All = false with -all")
+	}
+	t.Setenv(prePushEnv, "origin")
+	if opts := parseArgs([]string{"main.tf"}); !opts.All {
+		t.Error("All = false in the pre-push stage")
+	}
+}
+
+func TestParseArgs_PluginCache(t *testing.T) {
+	if opts := parseArgs([]string{"-verbose"}); !opts.PluginCache || opts.PluginCacheDir != "" {
+		t.Errorf("PluginCache = %v, PluginCacheDir = %q; want enabled with default dir", opts.PluginCache, opts.PluginCacheDir)
+	}
+	opts := parseArgs([]string{"--no-plugin-cache", "--plugin-cache-dir", "/tmp/cache"})
+	if opts.PluginCache || opts.PluginCacheDir != "/tmp/cache" || len(opts.ExtraArgs) != 0 {
+		t.Errorf("parseArgs() = %+v, want the plugin cache flags consumed by the hook", opts)
+	}
+}
+
+func TestLoadOptions_Config(t *testing.T) {
+	dir := t.TempDir()
+	content := `{"test": {"args": ["-verbose"], "reports": {"junit": "file.xml", "json": "file.json"}}}`
+	if err := os.WriteFile(filepath.Join(dir, ".tofu-hooks.json"), []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	wd, _ := os.Getwd()
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chdir(wd)
+
+	opts, err := loadOptions([]string{"-report-junit=cli.xml"})
+	if err != nil {
+		t.Fatalf("loadOptions() error: %v", err)
+	}
+	if opts.ReportJUnit != "cli.xml" || opts.ReportJSON != "file.json" {
+		t.Errorf("ReportJUnit = %q, ReportJSON = %q; want the flag to override the file", opts.ReportJUnit, opts.ReportJSON)
+	}
+	if len(opts.ExtraArgs) != 1 || opts.ExtraArgs[0] != "-verbose" {
+		t.Errorf("ExtraArgs = %v, want [-verbose]", opts.ExtraArgs)
+	}
+
+	if err := os.WriteFile(filepath.Join(dir, ".tofu-hooks.json"), []byte(`{"test": {"jobs": 2, "include": ["modules/**"]}}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if opts, err := loadOptions(nil); err != nil || opts.Jobs != 2 || len(opts.Config.Include) != 1 {
+		t.Errorf("loadOptions() Jobs = %d, Config = %+v, %v; want both from the file", opts.Jobs, opts.Config, err)
+	}
+	if _, err := loadOptions([]string{"-jobs=0"}); err == nil || !strings.Contains(err.Error(), "invalid -jobs value") {
+		t.Errorf("loadOptions(-jobs=0) = %v, want an invalid value error", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, ".tofu-hooks.json"), []byte(`{"test": {"reports": {"sarif": "x.sarif"}}}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := loadOptions(nil); err == nil || !strings.Contains(err.Error(), "test does not support reports.sarif") {
+		t.Errorf("loadOptions() = %v, want unsupported setting error", err)
+	}
+	if err := os.Remove(filepath.Join(dir, ".tofu-hooks.json")); err != nil {
+		t.Fatal(err)
+	}
+	if opts, err := loadOptions([]string{"-git-discovery=not-ignored"}); err != nil || opts.Discover.Git != discover.GitNotIgnored {
+		t.Errorf("loadOptions() Git = %q, %v; want not-ignored", opts.Discover.Git, err)
+	}
+	if _, err := loadOptions([]string{"-git-discovery=staged"}); err == nil || !strings.Contains(err.Error(), "invalid git discovery mode") {
+		t.Errorf("loadOptions() = %v, want invalid mode error", err)
+	}
+}
+
 func TestParseExtraArgs_StandardFlag(t *testing.T) {
-	got := parseExtraArgs([]string{"-verbose"})
+	got := parseArgs([]string{"-verbose"}).ExtraArgs
 	if len(got) != 1 || got[0] != "-verbose" {
-		t.Errorf("parseExtraArgs([-verbose]) = %v, want [-verbose]", got)
+		t.Errorf("parseArgs([-verbose]) = %v, want [-verbose]", got)
 	}
 }
 
 func TestParseExtraArgs_EqualForm(t *testing.T) {
-	got := parseExtraArgs([]string{"-filter=TestFoo"})
+	got := parseArgs([]string{"-filter=TestFoo"}).ExtraArgs
 	if len(got) != 1 || got[0] != "-filter=TestFoo" {
-		t.Errorf("parseExtraArgs([-filter=TestFoo]) = %v, want [-filter=TestFoo]", got)
+		t.Errorf("parseArgs([-filter=TestFoo]) = %v, want [-filter=TestFoo]", got)
 	}
 }
 
 func TestParseExtraArgs_SplitForm(t *testing.T) {
-	got := parseExtraArgs([]string{"-filter", "TestFoo"})
+	got := parseArgs([]string{"-filter", "TestFoo"}).ExtraArgs
 	if len(got) != 2 || got[0] != "-filter" || got[1] != "TestFoo" {
-		t.Errorf("parseExtraArgs([-filter TestFoo]) = %v, want [-filter TestFoo]", got)
+		t.Errorf("parseArgs([-filter TestFoo]) = %v, want [-filter TestFoo]", got)
 	}
 }
 
 func TestParseExtraArgs_Mixed(t *testing.T) {
-	got := parseExtraArgs([]string{"-verbose", "-filter", "TestFoo", "-json"})
+	got := parseArgs([]string{"-verbose", "-filter", "TestFoo", "-json"}).ExtraArgs
 	want := []string{"-verbose", "-filter", "TestFoo", "-json"}
 	if len(got) != len(want) {
-		t.Fatalf("parseExtraArgs() = %v, want %v", got, want)
+		t.Fatalf("parseArgs() = %v, want %v", got, want)
 	}
 	for i, v := range want {
 		if got[i] != v {
-			t.Errorf("parseExtraArgs()[%d] = %q, want %q", i, got[i], v)
+			t.Errorf("parseArgs()[%d] = %q, want %q", i, got[i], v)
 		}
 	}
 }
 
 func TestParseExtraArgs_NonFlagTokensOnly(t *testing.T) {
-	got := parseExtraArgs([]string{"file1.tf", "file2.tofu"})
+	got := parseArgs([]string{"file1.tf", "file2.tofu"}).ExtraArgs
 	if len(got) != 0 {
-		t.Errorf("parseExtraArgs() = %v, want empty slice", got)
+		t.Errorf("parseArgs() = %v, want empty slice", got)
 	}
 }
 
 func TestParseExtraArgs_TrailingFlagNoValue(t *testing.T) {
-	got := parseExtraArgs([]string{"-verbose", "-filter"})
+	got := parseArgs([]string{"-verbose", "-filter"}).ExtraArgs
 	want := []string{"-verbose", "-filter"}
 	if len(got) != len(want) {
-		t.Fatalf("parseExtraArgs() = %v, want %v", got, want)
+		t.Fatalf("parseArgs() = %v, want %v", got, want)
 	}
 	for i, v := range want {
 		if got[i] != v {
-			t.Errorf("parseExtraArgs()[%d] = %q, want %q", i, got[i], v)
+			t.Errorf("parseArgs()[%d] = %q, want %q", i, got[i], v)
 		}
 	}
 }
 
 func TestParseExtraArgs_BoolFlagDoesNotCapturePositional(t *testing.T) {
 	// Boolean flags must not consume a following positional token.
-	got := parseExtraArgs([]string{"-verbose", "file.tf"})
+	got := parseArgs([]string{"-verbose", "file.tf"}).ExtraArgs
 	if len(got) != 1 || got[0] != "-verbose" {
-		t.Errorf("parseExtraArgs([-verbose file.tf]) = %v, want [-verbose]", got)
+		t.Errorf("parseArgs([-verbose file.tf]) = %v, want [-verbose]", got)
 	}
 }
 
 func TestParseExtraArgs_EndOfFlags(t *testing.T) {
 	// A "--" token must end flag processing; everything after is ignored.
-	got := parseExtraArgs([]string{"-verbose", "--", "-filter", "TestFoo"})
+	got := parseArgs([]string{"-verbose", "--", "-filter", "TestFoo"}).ExtraArgs
 	if len(got) != 1 || got[0] != "-verbose" {
-		t.Errorf("parseExtraArgs([-verbose -- -filter TestFoo]) = %v, want [-verbose]", got)
+		t.Errorf("parseArgs([-verbose -- -filter TestFoo]) = %v, want [-verbose]", got)
+	}
+}
+
+func TestCountRuns(t *testing.T) {
+	files := []tofutest.FileResult{{Runs: []tofutest.RunResult{{Status: tofutest.StatusPass}, {Status: tofutest.StatusSkip}}}}
+	if got := countRuns(files); got != "(1 passed, 0 failed, 1 skipped)" {
+		t.Errorf("countRuns() = %q", got)
+	}
+	files[0].Runs = append(files[0].Runs, tofutest.RunResult{Status: tofutest.StatusFail, Assertions: make([]output.Diagnostic, 2)})
+	if got := countRuns(files); got != "(1 passed, 1 failed, 1 skipped; 2 assertion(s) failed)" {
+		t.Errorf("countRuns() with failed assertions = %q", got)
 	}
 }
@@ -4,28 +4,101 @@ import (
 	"errors"
 	"fmt"
 	"os"
-	"os/exec"
+	"path"
 	"path/filepath"
+	"runtime"
+	"strconv"
 	"strings"
+	"time"
 
+	"pre-commit-hooks/internal/cliargs"
+	"pre-commit-hooks/internal/config"
+	"pre-commit-hooks/internal/discover"
+	"pre-commit-hooks/internal/hookutil"
+	"pre-commit-hooks/internal/modules"
 	"pre-commit-hooks/internal/output"
+	"pre-commit-hooks/internal/parallel"
+	"pre-commit-hooks/internal/runner"
+	"pre-commit-hooks/internal/tofubin"
+	"pre-commit-hooks/internal/tofuenv"
 	tofuvalidate "pre-commit-hooks/internal/tofuvalidate"
+	"pre-commit-hooks/internal/tofuversion"
 )
 
+// hookFlags lists the flags consumed by the hook itself rather than forwarded
+// to tofu, and whether each takes a value.
+var hookFlags = map[string]bool{
+	"isolate":            false,
+	"jobs":               true,
+	"no-plugin-cache":    false,
+	"plugin-cache-dir":   true,
+	"report-json":        true,
+	"report-sarif":       true,
+	"github-annotations": false,
+	"binary":             true,
+	"tofu-version":       true,
+	"exclude":            true,
+	"git-discovery":      true,
+	"timeout":            true,
+}
+
+// knownValueFlags lists tofu validate flags that accept a value in split form.
+var knownValueFlags = map[string]bool{
+	"-var":      true,
+	"-var-file": true,
+}
+
+// options holds the parsed command line of the hook.
+type options struct {
+	// ExtraArgs are forwarded to both tofu init and tofu validate.
+	ExtraArgs []string
+	// Jobs is the number of directories processed concurrently.
+	Jobs int
+	// Files are the staged filenames passed by pre-commit. When set, only the
+	// directories affected by these files are validated.
+	Files []string
+	// Isolate runs tofu with a temporary TF_DATA_DIR per directory and restores
+	// the dependency lock file afterwards, leaving the working tree untouched.
+	Isolate bool
+	// PluginCache shares a provider plugin cache across every tofu init.
+	PluginCache bool
+	// PluginCacheDir overrides the plugin cache location.
+	PluginCacheDir string
+	// ReportJSON is the path of the JSON report to write, if any.
+	ReportJSON string
+	// ReportSARIF is the path of the SARIF log to write, if any.
+	ReportSARIF string
+	// GitHubAnnotations emits GitHub Actions workflow commands: a log group
+	// per directory and an annotation per diagnostic.
+	GitHubAnnotations bool
+	// Binary selects tofu, terraform or a path; see tofubin.Resolve.
+	Binary string
+	// TofuVersion is a version constraint checked in addition to the
+	// required_version of the validated directories.
+	TofuVersion string
+	// Discover holds the -exclude globs and the -git-discovery mode, applied
+	// in addition to .tofuignore files.
+	Discover discover.Options
+	// Timeouts limits how long each init and validate may run.
+	Timeouts runner.Timeouts
+	// Config holds the validate settings of the configuration file; its
+	// flags and args are already merged into the fields above.
+	Config config.Hook
+}
+
 func main() {
-	// Only pass flags (arguments starting with '-') to tofu commands
-	extraArgs := []string{}
-	for _, arg := range os.Args[1:] {
-		if strings.HasPrefix(arg, "-") {
-			extraArgs = append(extraArgs, arg)
-		}
+	runner.HandleInterrupts()
+	opts, err := loadOptions(os.Args[1:])
+	if err != nil {
+		output.PrintStatus(output.Error, err.Error(), output.Red)
+		os.Exit(1)
 	}
-	err := RunTofuValidateCLI(
-		extraArgs,
+	err = RunTofuValidateCLI(
+		opts,
 		tofuvalidate.CheckOpenTofuInstalled,
 		os.Getwd,
 		findDirsWithTfFiles,
-		runCmdInDir,
+		hookutil.RunCmdInDir,
 		tofuvalidate.RunTofuValidate,
 		printStatus,
 		os.Exit,
@@ -35,174 +108,411 @@ func main() {
 	}
 }
 
+// loadOptions parses args merged with the validate settings of the
+// configuration file in the working directory, the repository root under
+// pre-commit
+func loadOptions(args []string) (options, error) {
+	wd, err := os.Getwd()
+	if err != nil {
+		return options{}, err
+	}
+	hook, err := config.LoadHook(wd, "validate")
+	if err != nil {
+		return options{}, err
+	}
+	opts, err := parseArgs(hook.Merge(args))
+	opts.Config = hook
+	return opts, err
+}
+
+// parseArgs splits the hook command line into options. Only flags (arguments
+// starting with '-') are passed to tofu commands, along with the value of a
+// split-form flag in knownValueFlags; -jobs N is consumed by the hook and
+// defaults to the number of CPUs. Positional arguments are staged filenames.
+func parseArgs(args []string) (options, error) {
+	parsed := cliargs.Parse(args, hookFlags, knownValueFlags)
+	opts := options{
+		ExtraArgs: parsed.Extra,
+		Jobs:      runtime.NumCPU(),
+		Files:     parsed.Files,
+		Isolate:   parsed.Bool("isolate"),
+
+		PluginCache:    !parsed.Bool("no-plugin-cache"),
+		PluginCacheDir: parsed.String("plugin-cache-dir", ""),
+		ReportJSON:     parsed.String("report-json", ""),
+		ReportSARIF:    parsed.String("report-sarif", ""),
+
+		GitHubAnnotations: parsed.BoolEnv("github-annotations", output.GitHubActionsEnv, false),
+		Binary:            parsed.String("binary", ""),
+		TofuVersion:       parsed.String(tofuversion.Flag, ""),
+	}
+	git, err := discover.ParseGitMode(parsed.String("git-discovery", ""))
+	if err != nil {
+		return opts, err
+	}
+	opts.Discover = discover.Options{Exclude: parsed.List("exclude"), Git: git}
+	if opts.Timeouts, err = runner.ParseTimeouts(parsed.List(runner.Flag), "init", "validate"); err != nil {
+		return opts, err
+	}
+	if parsed.Has("jobs") {
+		jobs, err := strconv.Atoi(parsed.String("jobs", ""))
+		if err != nil || jobs < 1 {
+			return opts, fmt.Errorf("invalid -jobs value %q: must be a positive integer", parsed.String("jobs", ""))
+		}
+		opts.Jobs = jobs
+	}
+	return opts, nil
+}
+
 // RunTofuValidateCLI runs the tofu validate CLI logic. Returns error if any step fails.
+//
+// Directories are initialized and validated concurrently, up to opts.Jobs at a
+// time. Each directory's output is buffered and printed in discovery order.
+// When opts.Files is set, only the directories owning those files and the
+// directories that call them as local modules are validated. With
+// opts.GitHubAnnotations each directory's log is wrapped in a GitHub Actions
+// group and every diagnostic is emitted as an ::error or ::warning command.
+// The include, exclude and skip globs of opts.Config filter the directories,
+// and its per-directory args are added to the tofu arguments. Each init and
+// validate is stopped after its opts.Timeouts limit and reported as timed out.
 func RunTofuValidateCLI(
-	extraArgs []string,
+	opts options,
 	checkInstalled func() bool,
 	getwd func() (string, error),
-	findDirs func(string) []string,
-	runCmd func(string, []string) (string, error),
-	runValidate func(string, []string) (string, error),
+	findDirs func(string, discover.Options) discover.Result,
+	runCmd func(string, []string, []string) (string, error),
+	runValidate func(string, []string, []string) (tofuvalidate.ValidateResult, error),
 	printStatus func(string, string),
 	exit func(int),
 ) error {
+	bin := tofubin.Resolve(opts.Binary)
+	tofubin.Set(bin)
+	runner.SetTimeouts(opts.Timeouts)
 	if !checkInstalled() {
-		fmt.Println("OpenTofu is not installed or not in PATH.")
+		output.PrintStatus(output.Error, fmt.Sprintf("%s is not installed or not in PATH.", bin.DisplayName()), output.Red)
 		exit(1)
-		return fmt.Errorf("OpenTofu not installed")
+		return fmt.Errorf("%s not installed", bin.DisplayName())
 	}
 
 	rootDir, err := getwd()
 	if err != nil {
-		fmt.Println("Could not get working directory.")
+		output.PrintStatus(output.Error, "Could not get working directory.", output.Red)
 		exit(1)
 		return err
 	}
 
-	dirsWithTf := findDirs(rootDir)
+	baseDir := filepath.Base(rootDir)
+	found := findDirs(rootDir, opts.Discover)
+	dirsWithTf := found.Dirs
+	var skipped []output.Result
+	for _, s := range found.Skipped {
+		skipped = append(skipped, skippedResult(hookutil.DisplayPath(rootDir, baseDir, s.Path), s.Reason))
+	}
 	if len(dirsWithTf) == 0 {
-		fmt.Println("No directories with Terraform files found.")
-		exit(0)
-		return nil
+		printStatus(output.ThumbsUp, "No directories with Terraform files found.")
+		return finishEarly(opts, skipped, exit)
 	}
 
-	var errorMessages []output.TofuMessage
-	var warningMessages []output.TofuMessage
-	baseDir := filepath.Base(rootDir)
-	for _, dir := range dirsWithTf {
-		relPath, err := filepath.Rel(rootDir, dir)
-		if err != nil {
-			relPath = dir // fallback to absolute path
-		}
-		fullPath := relPath
-		if relPath == "." {
-			fullPath = baseDir
-		} else if strings.HasPrefix(relPath, "..") {
-			// If path is outside rootDir, use just the dir name
-			fullPath = filepath.Base(dir)
-		} else {
-			fullPath = baseDir + "/" + relPath
+	if len(opts.Files) > 0 {
+		dirsWithTf = modules.Affected(dirsWithTf, hookutil.AbsPaths(rootDir, opts.Files))
+		if len(dirsWithTf) == 0 {
+			printStatus(output.ThumbsUp, "No directories affected by the staged files.")
+			return finishEarly(opts, skipped, exit)
 		}
-		printStatus(output.Running, fmt.Sprintf("Running tofu init in: %s...", fullPath))
-		initCmd := []string{"init", "-input=false", "--backend=false"}
-		cmdArgs := append(initCmd, extraArgs...)
-		out, err := runCmd(dir, cmdArgs)
-		printIndentedOutput(out, true)
-		// Always check for warnings in init output
-		if hasWarning(out) {
-			warningMessages = append(warningMessages, output.TofuMessage{Step: "init", RelPath: fullPath, Output: out})
-		}
-		if err != nil {
-			errorMessages = append(errorMessages, output.TofuMessage{Step: "init", RelPath: fullPath, Output: out})
-			continue
+	}
+
+	dirsWithTf, skippedDirs := configuredDirs(opts.Config, rootDir, dirsWithTf)
+	for _, dir := range skippedDirs {
+		skipped = append(skipped, skippedResult(hookutil.DisplayPath(rootDir, baseDir, dir), "listed in the skip settings"))
+	}
+	if len(dirsWithTf) == 0 {
+		printStatus(output.ThumbsUp, "No directories left to validate after the configured filters.")
+		return finishEarly(opts, skipped, exit)
+	}
+
+	if err := tofuversion.Check(opts.TofuVersion, dirsWithTf); err != nil {
+		output.PrintStatus(output.Error, err.Error(), output.Red)
+		exit(1)
+		return err
+	}
+
+	var cache *tofuenv.PluginCache
+	if opts.PluginCache {
+		if cache, err = tofuenv.NewPluginCache(opts.PluginCacheDir); err != nil {
+			output.PrintStatus(output.Warning, fmt.Sprintf("Could not set up plugin cache, continuing without it: %v", err), output.Yellow)
+			cache = nil
 		}
+	}
 
-		printStatus(output.Running, fmt.Sprintf("Running tofu validate in: %s...", fullPath))
-		out, err = runValidate(dir, extraArgs)
-		printIndentedOutput(out, true)
-		// Always check for warnings in validate output
-		if hasWarning(out) {
-			warningMessages = append(warningMessages, output.TofuMessage{Step: "validate", RelPath: fullPath, Output: out})
+	var errorMessages []output.TofuMessage
+	var warningMessages []output.TofuMessage
+	var stepResults, repoResults []output.Result
+	stepResults = append(stepResults, skipped...)
+	results := make([]dirResult, len(dirsWithTf))
+	parallel.Ordered(len(dirsWithTf), opts.Jobs, func(i int) {
+		dir := dirsWithTf[i]
+		dirOpts := opts
+		dirOpts.ExtraArgs = opts.Config.ArgsFor(hookutil.RelPath(rootDir, dir), opts.ExtraArgs)
+		results[i] = validateDir(dir, hookutil.DisplayPath(rootDir, baseDir, dir), dirOpts, cache, runCmd, runValidate)
+	}, func(i int) {
+		if opts.GitHubAnnotations {
+			fmt.Println(output.GroupStart(hookutil.DisplayPath(rootDir, baseDir, dirsWithTf[i])))
 		}
-		if err != nil {
-			errorMessages = append(errorMessages, output.TofuMessage{Step: "validate", RelPath: fullPath, Output: out})
-			continue
+		results[i].Replay(printStatus)
+		if opts.GitHubAnnotations {
+			fmt.Println(output.GroupEnd)
 		}
+		warningMessages = append(warningMessages, results[i].warnings...)
+		errorMessages = append(errorMessages, results[i].errors...)
+		stepResults = append(stepResults, results[i].steps...)
+		repoResults = append(repoResults, repoRelative(rootDir, dirsWithTf[i], results[i].steps)...)
+	})
+	reportErr := writeReports(opts, stepResults, repoResults)
+	if opts.GitHubAnnotations {
+		output.PrintAnnotations(output.ResultAnnotations(repoResults))
+	}
+
+	if cache != nil {
+		hits, misses := cache.Stats()
+		printStatus(output.Running, fmt.Sprintf("Plugin cache %s: %d hit(s), %d miss(es)", cache.Dir, hits, misses))
+		fmt.Println()
 	}
 
+	output.PrintSkippedSummary(hookutil.SkippedMessages(skipped))
+
 	if len(warningMessages) > 0 {
 		output.PrintWarningSummary(warningMessages)
 	}
 
 	if len(errorMessages) > 0 {
-		output.PrintErrorSummary(errorMessages, printIndentedOutput)
+		output.PrintErrorSummary(errorMessages, hookutil.PrintIndentedOutput)
 		exit(1)
 		return fmt.Errorf("validation failed")
 	}
 
+	if reportErr != nil {
+		exit(1)
+		return reportErr
+	}
+
 	if len(warningMessages) > 0 {
-		printStatus(output.ThumbsUp, "OpenTofu validate completed with warnings.")
+		printStatus(output.ThumbsUp, fmt.Sprintf("%s validate completed with warnings.", bin.DisplayName()))
 		fmt.Println()
 		exit(0)
 		return nil
 	}
 
-	printStatus(output.ThumbsUp, "OpenTofu validate completed successfully for all directories.")
+	printStatus(output.ThumbsUp, fmt.Sprintf("%s validate completed successfully for all directories.", bin.DisplayName()))
 	fmt.Println()
 	return nil
 }
 
-// runCmdInDir runs a command in the specified directory, returns all output and error
-func runCmdInDir(dir string, args []string) (string, error) {
-	cmd := exec.Command("tofu", args...)
-	cmd.Dir = dir
-	out, err := cmd.CombinedOutput()
-	return string(out), err
+// finishEarly lists the skipped paths and writes the reports when there is
+// nothing to validate
+func finishEarly(opts options, skipped []output.Result, exit func(int)) error {
+	return hookutil.FinishEarly(skipped, func() error { return writeReports(opts, skipped, nil) }, exit)
 }
 
-// findDirsWithTfFiles recursively finds directories containing .tf files
-func findDirsWithTfFiles(root string) []string {
-	var dirs []string
-	if err := walkDirs(root, &dirs); err != nil {
-		fmt.Fprintf(os.Stderr, "Warning: Error scanning directories: %v\n", err)
+// skippedResult records a path left out of the run, and why, for the reports
+func skippedResult(displayPath, reason string) output.Result {
+	return output.Result{TofuMessage: output.TofuMessage{Step: "validate", RelPath: displayPath, Output: reason}, Status: output.StatusSkipped}
+}
+
+// writeReports writes the JSON report and SARIF log requested in opts. The
+// SARIF log takes results whose paths are relative to the repository root.
+func writeReports(opts options, results, sarifResults []output.Result) error {
+	if opts.ReportJSON != "" {
+		if err := output.WriteJSONReport(opts.ReportJSON, output.NewReport("tofuvalidate", results)); err != nil {
+			output.PrintStatus(output.Error, fmt.Sprintf("Could not write JSON report: %v", err), output.Red)
+			return err
+		}
 	}
-	return dirs
+	if opts.ReportSARIF != "" {
+		if err := output.WriteSARIF(opts.ReportSARIF, output.NewSARIFLog("tofuvalidate", sarifResults)); err != nil {
+			output.PrintStatus(output.Error, fmt.Sprintf("Could not write SARIF report: %v", err), output.Red)
+			return err
+		}
+	}
+	return nil
 }
 
-func walkDirs(dir string, dirs *[]string) error {
-	entries, err := os.ReadDir(dir)
-	if err != nil {
-		return err
+// repoRelative returns copies of a directory's step results with the path and
+// diagnostic filenames relative to rootDir, as code-scanning tools expect
+func repoRelative(rootDir, dir string, steps []output.Result) []output.Result {
+	rel, err := filepath.Rel(rootDir, dir)
+	if err != nil || strings.HasPrefix(rel, "..") {
+		rel = dir
 	}
-	var errs []error
-	hasTfOrTofu := false
-	for _, entry := range entries {
-		name := entry.Name()
-		// Skip hidden/system folders
-		if entry.IsDir() {
-			if strings.HasPrefix(name, ".") || name == ".terraform" {
-				continue
-			}
-			path := filepath.Join(dir, name)
-			if err := walkDirs(path, dirs); err != nil {
-				errs = append(errs, err)
+	rel = filepath.ToSlash(rel)
+	relocated := make([]output.Result, 0, len(steps))
+	for _, step := range steps {
+		step.RelPath = rel
+		diags := make([]output.Diagnostic, len(step.Diagnostics))
+		for i, diag := range step.Diagnostics {
+			if diag.Range != nil {
+				r := *diag.Range
+				r.Filename = path.Join(rel, filepath.ToSlash(r.Filename))
+				diag.Range = &r
 			}
-		} else if strings.HasSuffix(name, ".tf") || strings.HasSuffix(name, ".tofu") {
-			hasTfOrTofu = true
+			diags[i] = diag
 		}
+		step.Diagnostics = diags
+		relocated = append(relocated, step)
 	}
-	if hasTfOrTofu {
-		*dirs = append(*dirs, dir)
-	}
-	return errors.Join(errs...)
+	return relocated
 }
 
-// printIndentedOutput prints each line of output indented for better readability
-func printIndentedOutput(output string, addNewline bool) {
-	lines := strings.Split(output, "\n")
-	lastNonEmpty := -1
-	for idx := range lines {
-		if strings.TrimSpace(lines[idx]) != "" {
-			lastNonEmpty = idx
+// dirResult holds the buffered output, messages and step results of one directory
+type dirResult struct {
+	hookutil.Log
+	warnings []output.TofuMessage
+	errors   []output.TofuMessage
+	steps    []output.Result
+}
+
+// addStep records the outcome of a step for the JSON report
+func (r *dirResult) addStep(msg output.TofuMessage, status string, elapsed time.Duration) {
+	r.steps = append(r.steps, output.Result{TofuMessage: msg, Status: status, Duration: elapsed})
+}
+
+// validateDir runs tofu init and tofu validate in dir, buffering all output
+func validateDir(
+	dir, fullPath string,
+	opts options,
+	cache *tofuenv.PluginCache,
+	runCmd func(string, []string, []string) (string, error),
+	runValidate func(string, []string, []string) (tofuvalidate.ValidateResult, error),
+) (result dirResult) {
+	var env []string
+	if cache != nil {
+		env = append(env, cache.Env()...)
+	}
+	if opts.Isolate {
+		iso, err := tofuenv.Isolate(dir)
+		if err != nil {
+			initMsg := output.TofuMessage{Step: "init", RelPath: fullPath, Output: fmt.Sprintf("could not isolate data directory: %v", err)}
+			result.errors = append(result.errors, initMsg)
+			result.addStep(initMsg, output.StatusFailed, 0)
+			result.addStep(output.TofuMessage{Step: "validate", RelPath: fullPath}, output.StatusSkipped, 0)
+			return result
 		}
+		defer func() {
+			if err := iso.Restore(); err != nil {
+				result.errors = append(result.errors, output.TofuMessage{Step: "cleanup", RelPath: fullPath, Output: err.Error()})
+			}
+		}()
+		env = append(env, iso.Env()...)
 	}
-	for _, line := range lines {
-		if strings.TrimSpace(line) != "" {
-			fmt.Printf("    %s\n", line)
+
+	result.AddStatus(output.Running, fmt.Sprintf("Running %s init in: %s...", tofubin.Current().Name, fullPath))
+	initCmd := []string{"init", "-input=false", "--backend=false"}
+	cmdArgs := append(initCmd, opts.ExtraArgs...)
+	release := func() {}
+	if cache != nil {
+		// Inits may only share the cache while none of them downloads into it
+		release = cache.Acquire(dir)
+	}
+	started := time.Now()
+	out, err := runCmd(dir, env, cmdArgs)
+	elapsed := time.Since(started)
+	release()
+	if cache != nil {
+		cache.Record(out)
+	}
+	result.AddOutput(out)
+	initMsg := output.TofuMessage{Step: "init", RelPath: fullPath, Output: out}
+	status := output.StatusPassed
+	// Always check for warnings in init output
+	if hasWarning(out) {
+		result.warnings = append(result.warnings, initMsg)
+		status = output.StatusWarning
+	}
+	if err != nil {
+		initMsg.Timeout = hookutil.Timeout(err)
+		result.errors = append(result.errors, initMsg)
+		result.addStep(initMsg, output.StatusFailed, elapsed)
+		result.addStep(output.TofuMessage{Step: "validate", RelPath: fullPath}, output.StatusSkipped, 0)
+		return result
+	}
+	result.addStep(initMsg, status, elapsed)
+
+	result.AddStatus(output.Running, fmt.Sprintf("Running %s validate in: %s...", tofubin.Current().Name, fullPath))
+	started = time.Now()
+	validated, err := runValidate(dir, env, opts.ExtraArgs)
+	elapsed = time.Since(started)
+	validateMsg := output.TofuMessage{Step: "validate", RelPath: fullPath, Output: validated.Output}
+	status = output.StatusPassed
+	if validated.FormatVersion == "" {
+		// The -json output could not be decoded; fall back to scraping text
+		result.AddOutput(validated.Output)
+		if hasWarning(validated.Output) {
+			result.warnings = append(result.warnings, validateMsg)
+			status = output.StatusWarning
+		}
+	} else {
+		validateMsg.Output = output.FormatDiagnostics(validated.Diagnostics)
+		if len(validated.Diagnostics) == 0 {
+			validateMsg.Output = "Success! The configuration is valid."
+		}
+		validateMsg.Diagnostics = validated.Diagnostics
+		result.AddOutput(validateMsg.Output)
+		if warnings := output.FilterDiagnostics(validated.Diagnostics, output.SeverityWarning); len(warnings) > 0 {
+			warningMsg := validateMsg
+			warningMsg.Diagnostics = warnings
+			result.warnings = append(result.warnings, warningMsg)
+			status = output.StatusWarning
+		}
+		if !validated.Valid {
+			// tofu exits non-zero for invalid configuration, but do not rely on it
+			err = errors.Join(err, fmt.Errorf("configuration is invalid"))
 		}
 	}
-	// Only add newline if not already present at the end
-	if addNewline && lastNonEmpty != len(lines)-1 {
-		fmt.Println()
+	if err != nil {
+		validateMsg.Timeout = hookutil.Timeout(err)
+		result.errors = append(result.errors, validateMsg)
+		status = output.StatusFailed
+	}
+	result.addStep(validateMsg, status, elapsed)
+	return result
+}
+
+// configuredDirs splits dirs into those passing the include and exclude globs
+// of the configuration file and those it lists to skip; excluded directories
+// are dropped
+func configuredDirs(hook config.Hook, rootDir string, dirs []string) (selected, skipped []string) {
+	for _, dir := range dirs {
+		rel := hookutil.RelPath(rootDir, dir)
+		switch {
+		case !hook.Selected(rel):
+		case hook.Skipped(rel):
+			skipped = append(skipped, dir)
+		default:
+			selected = append(selected, dir)
+		}
+	}
+	return selected, skipped
+}
+
+// findDirsWithTfFiles recursively finds directories containing configuration
+// files: .tf files, and .tofu files unless the binary is terraform. Hidden
+// directories, paths ignored by .tofuignore files, paths matching an exclude
+// glob and, with a git mode, paths git leaves out are skipped; the result
+// lists them.
+func findDirsWithTfFiles(root string, opts discover.Options) discover.Result {
+	found, err := discover.Walk(root, opts, tofubin.Current().IsConfigFile)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: Error scanning directories: %v\n", err)
 	}
+	return found
 }
 
 // printStatus prints a colored emoji status message
 func printStatus(emoji, msg string) {
-	fmt.Println(output.EmojiColorText(emoji, msg, output.Green))
+	output.PrintStatus(emoji, msg, output.Green)
 }
 
-// hasWarning checks if output contains a warning message
+// hasWarning checks if text output contains a warning message. tofu init has
+// no -json mode, so its output is still scraped.
 // Uses pattern matching to avoid false positives from filenames or unrelated text
 func hasWarning(output string) bool {
 	lines := strings.Split(output, "\n")
@@ -210,9 +520,9 @@ func hasWarning(output string) bool {
 		trimmed := strings.TrimSpace(line)
 		lower := strings.ToLower(trimmed)
 		// Check for common warning patterns at start of line
-		if strings.HasPrefix(lower, "warning:") || 
-		   strings.HasPrefix(lower, "│ warning:") ||
-		   strings.HasPrefix(lower, "╷") && strings.Contains(lower, "warning") {
+		if strings.HasPrefix(lower, "warning:") ||
+			strings.HasPrefix(lower, "│ warning:") ||
+			strings.HasPrefix(lower, "╷") && strings.Contains(lower, "warning") {
 			return true
 		}
 	}
@@ -1,24 +1,31 @@
 package main
 
 import (
+	"encoding/json"
 	"fmt"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"runtime"
+	"sort"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
 
+	"pre-commit-hooks/internal/discover"
+	"pre-commit-hooks/internal/output"
+	"pre-commit-hooks/internal/runner"
 	"pre-commit-hooks/internal/testutil"
+	"pre-commit-hooks/internal/tofubin"
+	"pre-commit-hooks/internal/tofuenv"
+	tofuvalidate "pre-commit-hooks/internal/tofuvalidate"
 )
 
-func Test_runCmdInDir(t *testing.T) {
-	out, err := runCmdInDir(".", []string{"nonexistentcmd"})
-	if err == nil {
-		t.Error("Expected error for nonexistent command")
-	}
-	if out == "" {
-		t.Log("Output is empty as expected for nonexistent command")
-	}
+// textResult returns a validate result that was not decoded from JSON
+func textResult(out string) tofuvalidate.ValidateResult {
+	return tofuvalidate.ValidateResult{Output: out}
 }
 
 func Test_findDirsWithTfFiles_and_walkDirs(t *testing.T) {
@@ -33,7 +40,7 @@ func Test_findDirsWithTfFiles_and_walkDirs(t *testing.T) {
 	os.WriteFile(filepath.Join(tempDir, "sub1", "main.tf"), []byte("terraform {}"), 0644)
 	os.WriteFile(filepath.Join(tempDir, "sub2", "other.txt"), []byte("not tf"), 0644)
 
-	dirs := findDirsWithTfFiles(tempDir)
+	dirs := findDirsWithTfFiles(tempDir, discover.Options{}).Dirs
 	found := false
 	for _, d := range dirs {
 		if strings.HasSuffix(d, "sub1") {
@@ -65,11 +72,9 @@ func Test_printStatus(t *testing.T) {
 	}
 }
 
-func Test_walkDirs_ErrorsAndHidden(t *testing.T) {
-	var dirs []string
-	err := walkDirs("/nonexistent/path", &dirs)
-	if err == nil {
-		t.Error("Expected error for non-existent directory")
+func Test_findDirsWithTfFiles_ErrorsAndHidden(t *testing.T) {
+	if dirs := findDirsWithTfFiles("/nonexistent/path", discover.Options{}).Dirs; len(dirs) != 0 {
+		t.Errorf("Expected no directories for non-existent path, got %v", dirs)
 	}
 
 	tempDir, err2 := os.MkdirTemp("", "walkdirs_test")
@@ -85,11 +90,7 @@ func Test_walkDirs_ErrorsAndHidden(t *testing.T) {
 	os.WriteFile(filepath.Join(tempDir, ".hidden", "main.tf"), []byte("terraform {}"), 0644)
 	os.WriteFile(filepath.Join(tempDir, ".terraform", "main.tf"), []byte("terraform {}"), 0644)
 
-	var foundDirs []string
-	err = walkDirs(tempDir, &foundDirs)
-	if err != nil {
-		t.Fatalf("walkDirs failed: %v", err)
-	}
+	foundDirs := findDirsWithTfFiles(tempDir, discover.Options{}).Dirs
 	foundVisible := false
 	foundHidden := false
 	foundTerraform := false
@@ -148,17 +149,17 @@ func TestRunTofuValidateCLI_AllBranches(t *testing.T) {
 				}
 				return "/mockroot", nil
 			}
-			findDirs := func(root string) []string { return tc.args.dirs }
-			runCmd := func(dir string, args []string) (string, error) {
+			findDirs := func(string, discover.Options) discover.Result { return discover.Result{Dirs: tc.args.dirs} }
+			runCmd := func(dir string, env []string, args []string) (string, error) {
 				return tc.args.runCmdOut, tc.args.runCmdErr
 			}
-			runValidate := func(dir string, args []string) (string, error) {
-				return tc.args.runValidateOut, tc.args.runValidateErr
+			runValidate := func(dir string, env []string, args []string) (tofuvalidate.ValidateResult, error) {
+				return textResult(tc.args.runValidateOut), tc.args.runValidateErr
 			}
 			statusMsgs := []string{}
 			printStatus := func(emoji, msg string) { statusMsgs = append(statusMsgs, emoji+":"+msg) }
 			exit := func(code int) { exited = code }
-			err := RunTofuValidateCLI([]string{}, checkInstalled, getwd, findDirs, runCmd, runValidate, printStatus, exit)
+			err := RunTofuValidateCLI(options{Jobs: 1}, checkInstalled, getwd, findDirs, runCmd, runValidate, printStatus, exit)
 			if tc.wantErr && err == nil {
 				t.Errorf("Expected error for case %q, got nil", tc.name)
 			}
@@ -175,17 +176,17 @@ func TestRunTofuValidateCLI_AllBranches(t *testing.T) {
 		exited := 0
 		checkInstalled := func() bool { return true }
 		getwd := func() (string, error) { return "/mockroot", nil }
-		findDirs := func(root string) []string { return []string{"/mockroot/subdir"} }
-		runCmd := func(dir string, args []string) (string, error) {
+		findDirs := func(string, discover.Options) discover.Result { return discover.Result{Dirs: []string{"/mockroot/subdir"}} }
+		runCmd := func(dir string, env []string, args []string) (string, error) {
 			return "init ok", nil
 		}
-		runValidate := func(dir string, args []string) (string, error) {
-			return "validate ok", nil
+		runValidate := func(dir string, env []string, args []string) (tofuvalidate.ValidateResult, error) {
+			return textResult("validate ok"), nil
 		}
 		var statusMsgs []string
 		printStatus := func(emoji, msg string) { statusMsgs = append(statusMsgs, emoji+":"+msg) }
 		exit := func(code int) { exited = code }
-		err := RunTofuValidateCLI([]string{}, checkInstalled, getwd, findDirs, runCmd, runValidate, printStatus, exit)
+		err := RunTofuValidateCLI(options{Jobs: 1}, checkInstalled, getwd, findDirs, runCmd, runValidate, printStatus, exit)
 		if err != nil {
 			t.Errorf("Did not expect error for relPath rewriting branch, got: %v", err)
 		}
@@ -207,23 +208,23 @@ func TestRunTofuValidateCLI_AllBranches(t *testing.T) {
 		exited := 0
 		checkInstalled := func() bool { return true }
 		getwd := func() (string, error) { return "/mockroot", nil }
-		findDirs := func(root string) []string { return []string{"/mock1", "/mock2"} }
-		runCmd := func(dir string, args []string) (string, error) {
+		findDirs := func(string, discover.Options) discover.Result { return discover.Result{Dirs: []string{"/mock1", "/mock2"}} }
+		runCmd := func(dir string, env []string, args []string) (string, error) {
 			if dir == "/mock1" {
 				return "init fail", fmt.Errorf("fail")
 			}
 			return "init ok", nil
 		}
-		runValidate := func(dir string, args []string) (string, error) {
+		runValidate := func(dir string, env []string, args []string) (tofuvalidate.ValidateResult, error) {
 			if dir == "/mock2" {
-				return "validate fail", fmt.Errorf("fail")
+				return textResult("validate fail"), fmt.Errorf("fail")
 			}
-			return "validate ok", nil
+			return textResult("validate ok"), nil
 		}
 		statusMsgs := []string{}
 		printStatus := func(emoji, msg string) { statusMsgs = append(statusMsgs, emoji+":"+msg) }
 		exit := func(code int) { exited = code }
-		err := RunTofuValidateCLI([]string{}, checkInstalled, getwd, findDirs, runCmd, runValidate, printStatus, exit)
+		err := RunTofuValidateCLI(options{Jobs: 1}, checkInstalled, getwd, findDirs, runCmd, runValidate, printStatus, exit)
 		if err == nil {
 			t.Error("Expected error for multi-error summary branch, got nil")
 		}
@@ -233,6 +234,749 @@ func TestRunTofuValidateCLI_AllBranches(t *testing.T) {
 	})
 }
 
+func TestRunTofuValidateCLI_ParallelKeepsOrder(t *testing.T) {
+	dirs := []string{"/mockroot/a", "/mockroot/b", "/mockroot/c", "/mockroot/d"}
+	delays := map[string]time.Duration{"/mockroot/a": 30 * time.Millisecond, "/mockroot/b": 20 * time.Millisecond, "/mockroot/c": 10 * time.Millisecond}
+	runCmd := func(dir string, env []string, args []string) (string, error) {
+		time.Sleep(delays[dir])
+		return "init ok", nil
+	}
+	runValidate := func(dir string, env []string, args []string) (tofuvalidate.ValidateResult, error) {
+		if dir == "/mockroot/b" {
+			return textResult("Error: bad"), fmt.Errorf("fail")
+		}
+		return textResult("validate ok"), nil
+	}
+	var statusMsgs []string
+	printStatus := func(emoji, msg string) { statusMsgs = append(statusMsgs, msg) }
+	err := RunTofuValidateCLI(
+		options{Jobs: 4},
+		func() bool { return true },
+		func() (string, error) { return "/mockroot", nil },
+		func(string, discover.Options) discover.Result { return discover.Result{Dirs: dirs} },
+		runCmd, runValidate, printStatus, func(int) {},
+	)
+	if err == nil {
+		t.Error("Expected error when one directory fails validation")
+	}
+	want := []string{
+		"Running tofu init in: mockroot/a...", "Running tofu validate in: mockroot/a...",
+		"Running tofu init in: mockroot/b...", "Running tofu validate in: mockroot/b...",
+		"Running tofu init in: mockroot/c...", "Running tofu validate in: mockroot/c...",
+		"Running tofu init in: mockroot/d...", "Running tofu validate in: mockroot/d...",
+	}
+	if strings.Join(statusMsgs, "|") != strings.Join(want, "|") {
+		t.Errorf("status messages out of order:\ngot  %v\nwant %v", statusMsgs, want)
+	}
+}
+
+func TestRunTofuValidateCLI_StagedFiles(t *testing.T) {
+	tempDir, cleanup := testutil.CreateTempDir(t, "validate_staged")
+	defer cleanup()
+	for rel, content := range map[string]string{
+		"main.tf":                 `module "net" { source = "./modules/network" }`,
+		"modules/network/main.tf": `variable "cidr" {}`,
+		"examples/basic/main.tf":  `variable "x" {}`,
+	} {
+		path := filepath.Join(tempDir, rel)
+		os.MkdirAll(filepath.Dir(path), 0755)
+		os.WriteFile(path, []byte(content), 0644)
+	}
+
+	cases := []struct {
+		name     string
+		files    []string
+		wantDirs []string
+	}{
+		{"module change includes caller", []string{"modules/network/main.tf"}, []string{tempDir, filepath.Join(tempDir, "modules", "network")}},
+		{"leaf change only", []string{"examples/basic/main.tf"}, []string{filepath.Join(tempDir, "examples", "basic")}},
+		{"nothing affected", []string{"/outside/the/repo/main.tf"}, nil},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			var mu sync.Mutex
+			var validated []string
+			runValidate := func(dir string, env []string, args []string) (tofuvalidate.ValidateResult, error) {
+				mu.Lock()
+				defer mu.Unlock()
+				validated = append(validated, dir)
+				return textResult("ok"), nil
+			}
+			err := RunTofuValidateCLI(
+				options{Jobs: 1, Files: tc.files},
+				func() bool { return true },
+				func() (string, error) { return tempDir, nil },
+				findDirsWithTfFiles,
+				func(string, []string, []string) (string, error) { return "ok", nil },
+				runValidate,
+				func(string, string) {},
+				func(int) {},
+			)
+			if err != nil {
+				t.Fatalf("Did not expect error, got: %v", err)
+			}
+			sort.Strings(validated)
+			want := append([]string(nil), tc.wantDirs...)
+			sort.Strings(want)
+			if strings.Join(validated, "|") != strings.Join(want, "|") {
+				t.Errorf("validated dirs = %v, want %v", validated, want)
+			}
+		})
+	}
+}
+
+func TestRunTofuValidateCLI_Config(t *testing.T) {
+	tempDir, cleanup := testutil.CreateTempDir(t, "validate_config")
+	defer cleanup()
+	for _, rel := range []string{"envs/prod/main.tf", "envs/sandbox/main.tf", "modules/net/main.tf", "vendor/mod/main.tf"} {
+		path := filepath.Join(tempDir, rel)
+		os.MkdirAll(filepath.Dir(path), 0755)
+		os.WriteFile(path, []byte(`variable "x" {}`), 0644)
+	}
+	if err := os.WriteFile(filepath.Join(tempDir, ".tofu-hooks.yaml"), []byte(`validate:
+  exclude: ["vendor/**"]
+  skip: ["envs/sandbox"]
+  args: ["-no-color"]
+  directories:
+    envs/prod:
+      args: ["-var-file=prod.tfvars"]
+`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	restore := testutil.RestoreWorkingDir(t, tempDir)
+	defer restore()
+	opts, err := loadOptions([]string{"--jobs=1"})
+	if err != nil {
+		t.Fatalf("loadOptions() error: %v", err)
+	}
+
+	var mu sync.Mutex
+	validated := map[string][]string{}
+	out := testutil.CaptureStdout(t, func() {
+		RunTofuValidateCLI(
+			opts,
+			func() bool { return true },
+			func() (string, error) { return tempDir, nil },
+			findDirsWithTfFiles,
+			func(string, []string, []string) (string, error) { return "ok", nil },
+			func(dir string, env []string, args []string) (tofuvalidate.ValidateResult, error) {
+				mu.Lock()
+				defer mu.Unlock()
+				rel, _ := filepath.Rel(tempDir, dir)
+				validated[filepath.ToSlash(rel)] = args
+				return textResult("ok"), nil
+			},
+			func(emoji, msg string) {},
+			func(int) {},
+		)
+	})
+	want := map[string][]string{
+		"envs/prod":   {"-no-color", "-var-file=prod.tfvars"},
+		"modules/net": {"-no-color"},
+	}
+	if fmt.Sprint(validated) != fmt.Sprint(want) {
+		t.Errorf("validated dirs and args = %v, want %v", validated, want)
+	}
+	for _, wantSkip := range []string{
+		filepath.Base(tempDir) + "/envs/sandbox (listed in the skip settings)",
+		filepath.Base(tempDir) + "/vendor (excluded by --exclude vendor/**)",
+	} {
+		if !strings.Contains(out, "Skipped Summary:") || !strings.Contains(out, wantSkip) {
+			t.Errorf("Expected skipped summary with %q, got:\n%s", wantSkip, out)
+		}
+	}
+
+	if err := os.WriteFile(filepath.Join(tempDir, ".tofu-hooks.yaml"), []byte("validate:\n  parallel: 2\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := loadOptions(nil); err == nil || !strings.Contains(err.Error(), "field parallel not found") {
+		t.Errorf("loadOptions() = %v, want unknown key error", err)
+	}
+}
+
+func TestRunTofuValidateCLI_IgnoreAndExclude(t *testing.T) {
+	root := t.TempDir()
+	for rel, content := range map[string]string{
+		"modules/net/main.tf":     "",
+		"examples/basic/main.tf":  "",
+		"vendor/mod/main.tf":      "",
+		".tofuignore":             "examples/\n",
+		"modules/net/.tofuignore": "scratch.tf\n",
+		"modules/net/scratch.tf":  "",
+		"modules/dns/README.md":   "",
+		"modules/dns/.tofuignore": "*\n",
+	} {
+		path := filepath.Join(root, rel)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	opts, err := parseArgs([]string{"--exclude=vendor/**", "--jobs=1"})
+	if err != nil {
+		t.Fatalf("parseArgs() error: %v", err)
+	}
+	if len(opts.Discover.Exclude) != 1 || opts.Discover.Exclude[0] != "vendor/**" {
+		t.Fatalf("Exclude = %v, want [vendor/**]", opts.Discover.Exclude)
+	}
+
+	var initDirs []string
+	out := testutil.CaptureStdout(t, func() {
+		RunTofuValidateCLI(
+			opts,
+			func() bool { return true },
+			func() (string, error) { return root, nil },
+			findDirsWithTfFiles,
+			func(dir string, env []string, args []string) (string, error) {
+				rel, _ := filepath.Rel(root, dir)
+				initDirs = append(initDirs, filepath.ToSlash(rel))
+				return "init ok", nil
+			},
+			func(string, []string, []string) (tofuvalidate.ValidateResult, error) {
+				return tofuvalidate.ValidateResult{FormatVersion: "1.0", Valid: true}, nil
+			},
+			func(string, string) {},
+			func(int) {},
+		)
+	})
+	if len(initDirs) != 1 || initDirs[0] != "modules/net" {
+		t.Errorf("Expected only modules/net to be validated, got %v", initDirs)
+	}
+	base := filepath.Base(root)
+	for _, want := range []string{
+		"Skipped Summary:",
+		base + "/examples (ignored by .tofuignore)",
+		base + "/modules/net/scratch.tf (ignored by modules/net/.tofuignore)",
+		base + "/vendor (excluded by --exclude vendor/**)",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("Expected output to contain %q, got:\n%s", want, out)
+		}
+	}
+	if strings.Contains(out, "modules/dns") {
+		t.Errorf("Expected directories without configuration files to stay out of the summary, got:\n%s", out)
+	}
+}
+
+func TestRunTofuValidateCLI_Timeout(t *testing.T) {
+	opts, err := parseArgs([]string{"--timeout=init=1m", "--timeout", "30s", "--jobs=1"})
+	if err != nil {
+		t.Fatalf("parseArgs() error: %v", err)
+	}
+	if opts.Timeouts.For("init") != time.Minute || opts.Timeouts.For("validate") != 30*time.Second {
+		t.Errorf("Timeouts = %v, want init=1m and 30s for validate", opts.Timeouts)
+	}
+	if _, err := parseArgs([]string{"--timeout=test=1m"}); err == nil {
+		t.Error("parseArgs(--timeout=test=1m) expected error for a step validate does not run")
+	}
+
+	exitCode := 0
+	var validated []string
+	out := testutil.CaptureStdout(t, func() {
+		RunTofuValidateCLI(
+			opts,
+			func() bool { return true },
+			func() (string, error) { return "/mockroot", nil },
+			func(string, discover.Options) discover.Result { return discover.Result{Dirs: []string{"/mockroot/a", "/mockroot/b"}} },
+			func(dir string, env []string, args []string) (string, error) {
+				if dir == "/mockroot/a" {
+					return "Initializing provider plugins...", &runner.TimeoutError{Step: "init", Timeout: time.Minute}
+				}
+				return "init ok", nil
+			},
+			func(dir string, env []string, args []string) (tofuvalidate.ValidateResult, error) {
+				validated = append(validated, dir)
+				return tofuvalidate.ValidateResult{FormatVersion: "1.0", Valid: true}, nil
+			},
+			func(string, string) {},
+			func(code int) { exitCode = code },
+		)
+	})
+	if exitCode != 1 || len(validated) != 1 || validated[0] != "/mockroot/b" {
+		t.Errorf("exit = %d, validated = %v; want exit 1 and only b validated", exitCode, validated)
+	}
+	if !strings.Contains(out, "OpenTofu init timed out after 1m0s in: mockroot/a") {
+		t.Errorf("Expected the timeout in the error summary, got:\n%s", out)
+	}
+}
+
+func TestRunTofuValidateCLI_Isolate(t *testing.T) {
+	tempDir, cleanup := testutil.CreateTempDir(t, "validate_isolate")
+	defer cleanup()
+	lockPath := filepath.Join(tempDir, ".terraform.lock.hcl")
+
+	var dataDirs []string
+	runCmd := func(dir string, env []string, args []string) (string, error) {
+		if len(env) != 1 || !strings.HasPrefix(env[0], "TF_DATA_DIR=") {
+			t.Errorf("init env = %v, want a TF_DATA_DIR entry", env)
+			return "", nil
+		}
+		dataDirs = append(dataDirs, strings.TrimPrefix(env[0], "TF_DATA_DIR="))
+		// Simulate tofu init creating a lock file in the module directory
+		os.WriteFile(lockPath, []byte("# lock"), 0644)
+		return "init ok", nil
+	}
+	runValidate := func(dir string, env []string, args []string) (tofuvalidate.ValidateResult, error) {
+		if len(env) != 1 || env[0] != "TF_DATA_DIR="+dataDirs[0] {
+			t.Errorf("validate env = %v, want same TF_DATA_DIR as init", env)
+		}
+		return textResult("validate ok"), nil
+	}
+	err := RunTofuValidateCLI(
+		options{Jobs: 1, Isolate: true},
+		func() bool { return true },
+		func() (string, error) { return tempDir, nil },
+		func(string, discover.Options) discover.Result { return discover.Result{Dirs: []string{tempDir}} },
+		runCmd, runValidate, func(string, string) {}, func(int) {},
+	)
+	if err != nil {
+		t.Fatalf("Did not expect error, got: %v", err)
+	}
+	if _, err := os.Stat(lockPath); !os.IsNotExist(err) {
+		t.Errorf("Expected lock file created by init to be removed, stat error: %v", err)
+	}
+	if len(dataDirs) != 1 {
+		t.Fatalf("Expected one data dir, got %v", dataDirs)
+	}
+	if _, err := os.Stat(dataDirs[0]); !os.IsNotExist(err) {
+		t.Errorf("Expected temporary data dir to be removed, stat error: %v", err)
+	}
+}
+
+func TestRunTofuValidateCLI_PluginCache(t *testing.T) {
+	cacheDir := filepath.Join(t.TempDir(), "plugins")
+	var running, overlapped atomic.Int32
+	runCmd := func(dir string, env []string, args []string) (string, error) {
+		if running.Add(1) > 1 {
+			overlapped.Store(1)
+		}
+		defer running.Add(-1)
+		time.Sleep(20 * time.Millisecond)
+		if len(env) == 0 || env[0] != "TF_PLUGIN_CACHE_DIR="+cacheDir {
+			t.Errorf("init env = %v, want TF_PLUGIN_CACHE_DIR=%s", env, cacheDir)
+		}
+		if dir == "/mockroot/a" {
+			return "- Installing hashicorp/null v3.2.2...", nil
+		}
+		return "- Using hashicorp/null v3.2.2 from the shared cache directory", nil
+	}
+	var statusMsgs []string
+	err := RunTofuValidateCLI(
+		options{Jobs: 3, PluginCache: true, PluginCacheDir: cacheDir},
+		func() bool { return true },
+		func() (string, error) { return "/mockroot", nil },
+		func(string, discover.Options) discover.Result { return discover.Result{Dirs: []string{"/mockroot/a", "/mockroot/b", "/mockroot/c"}} },
+		runCmd,
+		func(string, []string, []string) (tofuvalidate.ValidateResult, error) { return textResult("ok"), nil },
+		func(emoji, msg string) { statusMsgs = append(statusMsgs, msg) },
+		func(int) {},
+	)
+	if err != nil {
+		t.Fatalf("Did not expect error, got: %v", err)
+	}
+	if info, err := os.Stat(cacheDir); err != nil || !info.IsDir() {
+		t.Errorf("Expected plugin cache dir to be created: %v", err)
+	}
+	if overlapped.Load() != 0 {
+		t.Error("init runs that may download into the plugin cache overlapped, want them serialized")
+	}
+	want := fmt.Sprintf("Plugin cache %s: 2 hit(s), 1 miss(es)", cacheDir)
+	found := false
+	for _, msg := range statusMsgs {
+		found = found || msg == want
+	}
+	if !found {
+		t.Errorf("Expected status %q, got %v", want, statusMsgs)
+	}
+}
+
+func TestRunTofuValidateCLI_PluginCacheWarm(t *testing.T) {
+	root := t.TempDir()
+	cacheDir := filepath.Join(root, ".cache")
+	lock := "provider \"registry.opentofu.org/hashicorp/null\" {\n  version = \"3.2.2\"\n}\n"
+	for _, dir := range []string{"a", "b"} {
+		if err := os.Mkdir(filepath.Join(root, dir), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(root, dir, tofuenv.LockFile), []byte(lock), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.MkdirAll(filepath.Join(cacheDir, "registry.opentofu.org", "hashicorp", "null", "3.2.2", runtime.GOOS+"_"+runtime.GOARCH), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	// Each init waits for the other, which only works when they run together
+	var started sync.WaitGroup
+	started.Add(2)
+	both := make(chan struct{})
+	go func() { started.Wait(); close(both) }()
+	runCmd := func(dir string, env []string, args []string) (string, error) {
+		started.Done()
+		select {
+		case <-both:
+		case <-time.After(2 * time.Second):
+			return "", fmt.Errorf("init in %s ran alone", dir)
+		}
+		return "- Using hashicorp/null v3.2.2 from the shared cache directory", nil
+	}
+	err := RunTofuValidateCLI(
+		options{Jobs: 2, PluginCache: true, PluginCacheDir: cacheDir},
+		func() bool { return true },
+		func() (string, error) { return root, nil },
+		func(string, discover.Options) discover.Result {
+			return discover.Result{Dirs: []string{filepath.Join(root, "a"), filepath.Join(root, "b")}}
+		},
+		runCmd,
+		func(string, []string, []string) (tofuvalidate.ValidateResult, error) { return textResult("ok"), nil },
+		func(string, string) {},
+		func(int) {},
+	)
+	if err != nil {
+		t.Errorf("Expected the inits reading from a warm plugin cache to run concurrently, got: %v", err)
+	}
+}
+
+func TestValidateDir_JSONDiagnostics(t *testing.T) {
+	warning := output.Diagnostic{Severity: output.SeverityWarning, Summary: "Deprecated", Range: &output.Range{Filename: "main.tf", Start: output.Pos{Line: 3}}}
+	failure := output.Diagnostic{Severity: output.SeverityError, Summary: "Unsupported argument"}
+	runCmd := func(string, []string, []string) (string, error) { return "init ok", nil }
+	cases := []struct {
+		name         string
+		result       tofuvalidate.ValidateResult
+		err          error
+		wantWarnings int
+		wantErrors   int
+	}{
+		{"valid", tofuvalidate.ValidateResult{FormatVersion: "1.0", Valid: true}, nil, 0, 0},
+		{"warning only", tofuvalidate.ValidateResult{FormatVersion: "1.0", Valid: true, WarningCount: 1, Diagnostics: []output.Diagnostic{warning}}, nil, 1, 0},
+		{"error and warning", tofuvalidate.ValidateResult{FormatVersion: "1.0", ErrorCount: 1, WarningCount: 1, Diagnostics: []output.Diagnostic{warning, failure}}, fmt.Errorf("exit status 1"), 1, 1},
+		{"warning text not scraped", tofuvalidate.ValidateResult{FormatVersion: "1.0", Valid: true, Output: "Warning: looks like a warning"}, nil, 0, 0},
+		{"undecoded falls back to text", textResult("Warning: something\nError: boom"), fmt.Errorf("exit status 1"), 1, 1},
+		{"undecoded success passes", textResult("Success! The configuration is valid."), nil, 0, 0},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			runValidate := func(string, []string, []string) (tofuvalidate.ValidateResult, error) { return tc.result, tc.err }
+			result := validateDir("/mock", "mock", options{}, nil, runCmd, runValidate)
+			if len(result.warnings) != tc.wantWarnings || len(result.errors) != tc.wantErrors {
+				t.Fatalf("got %d warnings, %d errors; want %d, %d", len(result.warnings), len(result.errors), tc.wantWarnings, tc.wantErrors)
+			}
+			if tc.wantWarnings > 0 && tc.result.FormatVersion != "" {
+				if diags := result.warnings[0].Diagnostics; len(diags) != 1 || diags[0].Summary != "Deprecated" {
+					t.Errorf("warning diagnostics = %+v, want the deprecated warning only", diags)
+				}
+			}
+		})
+	}
+}
+
+func TestRunTofuValidateCLI_ReportJSON(t *testing.T) {
+	reportPath := filepath.Join(t.TempDir(), "report.json")
+	runCmd := func(dir string, env []string, args []string) (string, error) {
+		if dir == "/mockroot/b" {
+			return "Error: init failed", fmt.Errorf("exit status 1")
+		}
+		return "init ok", nil
+	}
+	runValidate := func(string, []string, []string) (tofuvalidate.ValidateResult, error) {
+		return tofuvalidate.ValidateResult{FormatVersion: "1.0", Valid: true}, nil
+	}
+	err := RunTofuValidateCLI(
+		options{Jobs: 1, ReportJSON: reportPath},
+		func() bool { return true },
+		func() (string, error) { return "/mockroot", nil },
+		func(string, discover.Options) discover.Result { return discover.Result{Dirs: []string{"/mockroot/a", "/mockroot/b"}} },
+		runCmd,
+		runValidate,
+		func(string, string) {},
+		func(int) {},
+	)
+	if err == nil {
+		t.Fatal("Expected error for failed init")
+	}
+	data, err := os.ReadFile(reportPath)
+	if err != nil {
+		t.Fatalf("report not written: %v", err)
+	}
+	var report struct {
+		Hook    string `json:"hook"`
+		Status  string `json:"status"`
+		Results []struct {
+			Step   string `json:"step"`
+			Path   string `json:"path"`
+			Status string `json:"status"`
+		} `json:"results"`
+	}
+	if err := json.Unmarshal(data, &report); err != nil {
+		t.Fatalf("invalid report JSON: %v", err)
+	}
+	if report.Hook != "tofuvalidate" || report.Status != output.StatusFailed {
+		t.Errorf("report = %s/%s, want tofuvalidate/failed", report.Hook, report.Status)
+	}
+	want := []string{
+		"init mockroot/a passed",
+		"validate mockroot/a passed",
+		"init mockroot/b failed",
+		"validate mockroot/b skipped",
+	}
+	var got []string
+	for _, r := range report.Results {
+		got = append(got, r.Step+" "+r.Path+" "+r.Status)
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("results = %v, want %v", got, want)
+	}
+}
+
+func TestRunTofuValidateCLI_ReportSARIF(t *testing.T) {
+	sarifPath := filepath.Join(t.TempDir(), "validate.sarif")
+	runValidate := func(dir string, env []string, args []string) (tofuvalidate.ValidateResult, error) {
+		if dir == "/mockroot/modules/net" {
+			return tofuvalidate.ValidateResult{FormatVersion: "1.0", ErrorCount: 1, Diagnostics: []output.Diagnostic{{
+				Severity: output.SeverityError,
+				Summary:  "Unsupported argument",
+				Range:    &output.Range{Filename: "main.tf", Start: output.Pos{Line: 4, Column: 3}, End: output.Pos{Line: 4, Column: 6}},
+			}}}, fmt.Errorf("exit status 1")
+		}
+		return tofuvalidate.ValidateResult{FormatVersion: "1.0", Valid: true}, nil
+	}
+	RunTofuValidateCLI(
+		options{Jobs: 1, ReportSARIF: sarifPath},
+		func() bool { return true },
+		func() (string, error) { return "/mockroot", nil },
+		func(string, discover.Options) discover.Result { return discover.Result{Dirs: []string{"/mockroot", "/mockroot/modules/net"}} },
+		func(string, []string, []string) (string, error) { return "init ok", nil },
+		runValidate,
+		func(string, string) {},
+		func(int) {},
+	)
+	data, err := os.ReadFile(sarifPath)
+	if err != nil {
+		t.Fatalf("SARIF log not written: %v", err)
+	}
+	var log output.SARIFLog
+	if err := json.Unmarshal(data, &log); err != nil {
+		t.Fatalf("invalid SARIF JSON: %v", err)
+	}
+	if len(log.Runs) != 1 || len(log.Runs[0].Results) != 1 {
+		t.Fatalf("Unexpected SARIF log: %s", data)
+	}
+	result := log.Runs[0].Results[0]
+	location := result.Locations[0].PhysicalLocation
+	if result.RuleID != "validate/unsupported-argument" || result.Level != "error" {
+		t.Errorf("result = %+v, want an unsupported-argument error", result)
+	}
+	if location.ArtifactLocation.URI != "modules/net/main.tf" || location.Region.StartLine != 4 || location.Region.StartColumn != 3 {
+		t.Errorf("location = %+v, want modules/net/main.tf:4:3", location)
+	}
+}
+
+func TestRunTofuValidateCLI_GitHubAnnotations(t *testing.T) {
+	runValidate := func(dir string, env []string, args []string) (tofuvalidate.ValidateResult, error) {
+		if dir == "/mockroot/net" {
+			return tofuvalidate.ValidateResult{FormatVersion: "1.0", Valid: true, WarningCount: 1, Diagnostics: []output.Diagnostic{{
+				Severity: output.SeverityWarning,
+				Summary:  "Deprecated attribute",
+				Range:    &output.Range{Filename: "main.tf", Start: output.Pos{Line: 7, Column: 3}, End: output.Pos{Line: 7, Column: 9}},
+			}}}, nil
+		}
+		return tofuvalidate.ValidateResult{FormatVersion: "1.0", Valid: true}, nil
+	}
+	run := func(annotate bool) string {
+		return testutil.CaptureStdout(t, func() {
+			RunTofuValidateCLI(
+				options{Jobs: 1, GitHubAnnotations: annotate},
+				func() bool { return true },
+				func() (string, error) { return "/mockroot", nil },
+				func(string, discover.Options) discover.Result { return discover.Result{Dirs: []string{"/mockroot", "/mockroot/net"}} },
+				func(string, []string, []string) (string, error) { return "init ok", nil },
+				runValidate,
+				func(string, string) {},
+				func(int) {},
+			)
+		})
+	}
+
+	out := run(true)
+	for _, want := range []string{
+		"::group::mockroot\n",
+		"::group::mockroot/net\n",
+		"::endgroup::\n",
+		"::warning file=net/main.tf,line=7,endLine=7,col=3,endColumn=9,title=Deprecated attribute::Deprecated attribute\n",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("Expected output to contain %q, got:\n%s", want, out)
+		}
+	}
+	if strings.Count(out, "::group::") != 2 || strings.Count(out, "::endgroup::") != 2 {
+		t.Errorf("Expected one group per directory, got:\n%s", out)
+	}
+
+	if out := run(false); strings.Contains(out, "::") {
+		t.Errorf("Expected no workflow commands when disabled, got:\n%s", out)
+	}
+}
+
+func TestParseArgs_GitHubAnnotations(t *testing.T) {
+	t.Setenv(output.GitHubActionsEnv, "true")
+	if opts, _ := parseArgs(nil); !opts.GitHubAnnotations {
+		t.Error("GitHubAnnotations = false, want auto-detected from GITHUB_ACTIONS")
+	}
+	if opts, _ := parseArgs([]string{"--github-annotations=false"}); opts.GitHubAnnotations {
+		t.Error("GitHubAnnotations = true, want the flag to override GITHUB_ACTIONS")
+	}
+	t.Setenv(output.GitHubActionsEnv, "")
+	if opts, _ := parseArgs([]string{"-github-annotations"}); !opts.GitHubAnnotations {
+		t.Error("GitHubAnnotations = false, want forced on by the flag")
+	}
+}
+
+func TestParseArgs_SplitValueFlags(t *testing.T) {
+	opts, err := parseArgs([]string{"-var-file", "prod.tfvars", "-var", "region=eu", "-no-color", "main.tf"})
+	if err != nil {
+		t.Fatalf("parseArgs() error: %v", err)
+	}
+	if want := "[-var-file prod.tfvars -var region=eu -no-color]"; fmt.Sprint(opts.ExtraArgs) != want {
+		t.Errorf("ExtraArgs = %v, want %s", opts.ExtraArgs, want)
+	}
+	if fmt.Sprint(opts.Files) != "[main.tf]" {
+		t.Errorf("Files = %v, want [main.tf]", opts.Files)
+	}
+}
+
+func TestRunTofuValidateCLI_TerraformBinary(t *testing.T) {
+	defer tofubin.Set(tofubin.Current())
+	root := t.TempDir()
+	for _, file := range []string{"tf/main.tf", "tofu/main.tofu"} {
+		path := filepath.Join(root, file)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(""), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	var initDirs, statusMsgs []string
+	RunTofuValidateCLI(
+		options{Jobs: 1, Binary: "terraform"},
+		func() bool { return true },
+		func() (string, error) { return root, nil },
+		findDirsWithTfFiles,
+		func(dir string, env []string, args []string) (string, error) {
+			initDirs = append(initDirs, filepath.Base(dir))
+			return "init ok", nil
+		},
+		func(string, []string, []string) (tofuvalidate.ValidateResult, error) {
+			return tofuvalidate.ValidateResult{FormatVersion: "1.0", Valid: true}, nil
+		},
+		func(emoji, msg string) { statusMsgs = append(statusMsgs, msg) },
+		func(int) {},
+	)
+	if len(initDirs) != 1 || initDirs[0] != "tf" {
+		t.Errorf("Expected only the .tf directory to be validated with terraform, got %v", initDirs)
+	}
+	want := "Running terraform init in: " + filepath.Base(root) + "/tf..."
+	if len(statusMsgs) == 0 || statusMsgs[0] != want {
+		t.Errorf("Expected first status %q, got %v", want, statusMsgs)
+	}
+	if last := statusMsgs[len(statusMsgs)-1]; last != "Terraform validate completed successfully for all directories." {
+		t.Errorf("Expected Terraform completion status, got %q", last)
+	}
+}
+
+func TestRunTofuValidateCLI_TofuVersion(t *testing.T) {
+	defer tofubin.Set(tofubin.Current())
+	root := t.TempDir()
+	module := filepath.Join(root, "modules", "net")
+	if err := os.MkdirAll(module, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(module, "versions.tf"), []byte("terraform {\n  required_version = \"~> 1.8\"\n}\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	var initDirs []string
+	exitCode := 0
+	var err error
+	out := testutil.CaptureStdout(t, func() {
+		err = RunTofuValidateCLI(
+			options{Jobs: 1, Binary: testutil.FakeTofu(t, "1.7.3")},
+			func() bool { return true },
+			func() (string, error) { return root, nil },
+			findDirsWithTfFiles,
+			func(dir string, env []string, args []string) (string, error) {
+				initDirs = append(initDirs, dir)
+				return "init ok", nil
+			},
+			func(string, []string, []string) (tofuvalidate.ValidateResult, error) {
+				return tofuvalidate.ValidateResult{Valid: true}, nil
+			},
+			func(string, string) {},
+			func(code int) { exitCode = code },
+		)
+	})
+	want := "OpenTofu 1.7.3 is installed, but " + module + " requires ~> 1.8"
+	if err == nil || err.Error() != want || exitCode != 1 || len(initDirs) != 0 {
+		t.Errorf("Expected %q and exit 1 before init, got %v (exit=%d, init=%v)", want, err, exitCode, initDirs)
+	}
+	if !strings.Contains(out, want) {
+		t.Errorf("Expected output to contain %q, got:\n%s", want, out)
+	}
+
+	opts, _ := parseArgs([]string{"--tofu-version=>= 1.8", "main.tf"})
+	if opts.TofuVersion != ">= 1.8" || len(opts.ExtraArgs) != 0 {
+		t.Errorf("parseArgs() = %+v, want TofuVersion consumed by the hook", opts)
+	}
+}
+
+func TestParseArgs(t *testing.T) {
+	opts, err := parseArgs([]string{"-no-color", "main.tf", "--jobs", "3", "--isolate"})
+	if err != nil {
+		t.Fatalf("parseArgs() error: %v", err)
+	}
+	if !opts.Isolate {
+		t.Error("Isolate = false, want true")
+	}
+	if !opts.PluginCache || opts.PluginCacheDir != "" {
+		t.Errorf("PluginCache = %v, PluginCacheDir = %q; want enabled with default dir", opts.PluginCache, opts.PluginCacheDir)
+	}
+	if opts.Jobs != 3 {
+		t.Errorf("Jobs = %d, want 3", opts.Jobs)
+	}
+	if len(opts.ExtraArgs) != 1 || opts.ExtraArgs[0] != "-no-color" {
+		t.Errorf("ExtraArgs = %v, want [-no-color]", opts.ExtraArgs)
+	}
+	if len(opts.Files) != 1 || opts.Files[0] != "main.tf" {
+		t.Errorf("Files = %v, want [main.tf]", opts.Files)
+	}
+
+	opts, _ = parseArgs([]string{"--no-plugin-cache", "--plugin-cache-dir", "/tmp/cache"})
+	if opts.PluginCache || opts.PluginCacheDir != "/tmp/cache" {
+		t.Errorf("PluginCache = %v, PluginCacheDir = %q; want disabled with /tmp/cache", opts.PluginCache, opts.PluginCacheDir)
+	}
+
+	opts, err = parseArgs(nil)
+	if err != nil || opts.Jobs != runtime.NumCPU() {
+		t.Errorf("parseArgs(nil) = %+v, %v; want Jobs = NumCPU", opts, err)
+	}
+
+	for _, bad := range []string{"0", "-2", "many"} {
+		if _, err := parseArgs([]string{"--jobs=" + bad}); err == nil {
+			t.Errorf("parseArgs(--jobs=%s) expected error", bad)
+		}
+	}
+
+	opts, err = parseArgs([]string{"--git-discovery", "tracked", "--exclude=vendor/**,examples/*"})
+	if err != nil || opts.Discover.Git != discover.GitTracked || len(opts.Discover.Exclude) != 2 {
+		t.Errorf("parseArgs() Discover = %+v, %v; want tracked with two exclude globs", opts.Discover, err)
+	}
+	if _, err := parseArgs([]string{"--git-discovery=staged"}); err == nil {
+		t.Error("parseArgs(--git-discovery=staged) expected error")
+	}
+}
+
 // TestCheckOpenTofuInstalled tests the shared CheckOpenTofuInstalled function
 func TestCheckOpenTofuInstalled(t *testing.T) {
 	testutil.SkipIfTofuNotInstalled(t)
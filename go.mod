@@ -1,3 +1,5 @@
 module pre-commit-hooks
 
 go 1.25.8
+
+require gopkg.in/yaml.v3 v3.0.1
@@ -0,0 +1,125 @@
+package cliargs
+
+import (
+	"os"
+	"strconv"
+	"strings"
+)
+
+// Args holds a hook command line split into the flags consumed by the hook
+// itself, the flags forwarded to tofu, and positional arguments.
+type Args struct {
+	// Hook holds hook-level flags keyed by name without leading dashes.
+	Hook map[string]string
+	// Extra holds flags (and split-form values) forwarded to tofu.
+	Extra []string
+	// Files holds positional arguments, normally filenames passed by pre-commit.
+	Files []string
+	// all holds every value given for each hook flag, for List
+	all map[string][]string
+}
+
+// Parse splits args into hook flags, tofu flags and positional arguments.
+//
+// hookFlags maps each hook-level flag name to whether it takes a value. Hook
+// flags are matched with one or two leading dashes and may be given as
+// -name=value or, for flags that take a value, -name value. Boolean hook flags
+// without an explicit value are recorded as "true".
+//
+// tofuValueFlags lists tofu flags (with their leading dash) that accept a value
+// in split form, so that the value token is forwarded along with the flag
+// instead of being treated as a filename. A "--" token ends flag processing;
+// everything after it is positional.
+func Parse(args []string, hookFlags map[string]bool, tofuValueFlags map[string]bool) Args {
+	parsed := Args{Hook: map[string]string{}, Extra: []string{}, Files: []string{}, all: map[string][]string{}}
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if arg == "--" {
+			parsed.Files = append(parsed.Files, args[i+1:]...)
+			break
+		}
+		if !strings.HasPrefix(arg, "-") {
+			parsed.Files = append(parsed.Files, arg)
+			continue
+		}
+
+		name, value, hasValue := strings.Cut(strings.TrimLeft(arg, "-"), "=")
+		if takesValue, ok := hookFlags[name]; ok {
+			switch {
+			case hasValue:
+				parsed.Hook[name] = value
+			case takesValue && i+1 < len(args):
+				parsed.Hook[name] = args[i+1]
+				i++ // skip the value token
+			case takesValue:
+				parsed.Hook[name] = ""
+			default:
+				parsed.Hook[name] = "true"
+			}
+			parsed.all[name] = append(parsed.all[name], parsed.Hook[name])
+			continue
+		}
+
+		parsed.Extra = append(parsed.Extra, arg)
+		// Only consume the next token as a value for flags known to accept
+		// one, and only when no value is already embedded via '='.
+		if !hasValue && tofuValueFlags[arg] &&
+			i+1 < len(args) && !strings.HasPrefix(args[i+1], "-") {
+			parsed.Extra = append(parsed.Extra, args[i+1])
+			i++ // skip the value token
+		}
+	}
+	return parsed
+}
+
+// Bool reports whether the named hook flag was set to a true value.
+// Unparseable values count as false.
+func (a Args) Bool(name string) bool {
+	value, ok := a.Hook[name]
+	if !ok {
+		return false
+	}
+	b, err := strconv.ParseBool(value)
+	return err == nil && b
+}
+
+// BoolEnv resolves a boolean hook option from its flag, then the environment
+// variable env, falling back to def when neither is set to a valid value.
+func (a Args) BoolEnv(name, env string, def bool) bool {
+	if a.Has(name) {
+		return a.Bool(name)
+	}
+	if enabled, err := strconv.ParseBool(os.Getenv(env)); err == nil {
+		return enabled
+	}
+	return def
+}
+
+// String returns the value of the named hook flag, or def when it was not set.
+func (a Args) String(name, def string) string {
+	if value, ok := a.Hook[name]; ok {
+		return value
+	}
+	return def
+}
+
+// List returns every value given for the named hook flag, which may be
+// repeated, with comma-separated values split apart. String returns only the
+// last value.
+func (a Args) List(name string) []string {
+	var values []string
+	for _, value := range a.all[name] {
+		for _, item := range strings.Split(value, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				values = append(values, item)
+			}
+		}
+	}
+	return values
+}
+
+// Has reports whether the named hook flag was given on the command line.
+func (a Args) Has(name string) bool {
+	_, ok := a.Hook[name]
+	return ok
+}
@@ -0,0 +1,92 @@
+package cliargs
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	hookFlags := map[string]bool{"recursive": false, "jobs": true}
+	tofuValueFlags := map[string]bool{"-filter": true}
+	cases := []struct {
+		name      string
+		args      []string
+		wantHook  map[string]string
+		wantExtra []string
+		wantFiles []string
+	}{
+		{"empty", nil, map[string]string{}, []string{}, []string{}},
+		{"files only", []string{"main.tf", "sub/vars.tfvars"}, map[string]string{}, []string{}, []string{"main.tf", "sub/vars.tfvars"}},
+		{"tofu flags forwarded", []string{"-diff", "main.tf"}, map[string]string{}, []string{"-diff"}, []string{"main.tf"}},
+		{"bool hook flag single dash", []string{"-recursive"}, map[string]string{"recursive": "true"}, []string{}, []string{}},
+		{"bool hook flag double dash with value", []string{"--recursive=false"}, map[string]string{"recursive": "false"}, []string{}, []string{}},
+		{"value hook flag split form", []string{"--jobs", "4", "main.tf"}, map[string]string{"jobs": "4"}, []string{}, []string{"main.tf"}},
+		{"value hook flag equals form", []string{"--jobs=4"}, map[string]string{"jobs": "4"}, []string{}, []string{}},
+		{"tofu value flag split form", []string{"-filter", "TestFoo", "main.tf"}, map[string]string{}, []string{"-filter", "TestFoo"}, []string{"main.tf"}},
+		{"end of flags", []string{"-diff", "--", "-odd.tf"}, map[string]string{}, []string{"-diff"}, []string{"-odd.tf"}},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			got := Parse(c.args, hookFlags, tofuValueFlags)
+			if !reflect.DeepEqual(got.Hook, c.wantHook) {
+				t.Errorf("Hook = %v, want %v", got.Hook, c.wantHook)
+			}
+			if !reflect.DeepEqual(got.Extra, c.wantExtra) {
+				t.Errorf("Extra = %v, want %v", got.Extra, c.wantExtra)
+			}
+			if !reflect.DeepEqual(got.Files, c.wantFiles) {
+				t.Errorf("Files = %v, want %v", got.Files, c.wantFiles)
+			}
+		})
+	}
+}
+
+func TestArgsAccessors(t *testing.T) {
+	args := Parse([]string{"--recursive", "--jobs=8", "--check-only=nope"}, map[string]bool{"recursive": false, "jobs": true, "check-only": false}, nil)
+	if !args.Bool("recursive") {
+		t.Error("Bool(recursive) = false, want true")
+	}
+	if args.Bool("check-only") {
+		t.Error("Bool(check-only) = true, want false for unparseable value")
+	}
+	if args.Bool("missing") {
+		t.Error("Bool(missing) = true, want false")
+	}
+	if got := args.String("jobs", "1"); got != "8" {
+		t.Errorf("String(jobs) = %q, want %q", got, "8")
+	}
+	if got := args.String("missing", "def"); got != "def" {
+		t.Errorf("String(missing) = %q, want %q", got, "def")
+	}
+	if !args.Has("jobs") || args.Has("missing") {
+		t.Error("Has() did not report flag presence correctly")
+	}
+}
+
+func TestArgsBoolEnv(t *testing.T) {
+	t.Setenv("CLIARGS_TEST_ENV", "true")
+	args := Parse([]string{"--check-only=false"}, map[string]bool{"check-only": false, "other": false}, nil)
+	if args.BoolEnv("check-only", "CLIARGS_TEST_ENV", true) {
+		t.Error("BoolEnv(check-only) = true, want the explicit flag to win")
+	}
+	if !args.BoolEnv("other", "CLIARGS_TEST_ENV", false) {
+		t.Error("BoolEnv(other) = false, want the environment variable value")
+	}
+	t.Setenv("CLIARGS_TEST_ENV", "maybe")
+	if !args.BoolEnv("other", "CLIARGS_TEST_ENV", true) {
+		t.Error("BoolEnv(other) = false, want the default for an invalid environment value")
+	}
+}
+
+func TestArgsList(t *testing.T) {
+	args := Parse([]string{"--exclude=a/**,b", "-exclude", " c ", "--exclude=", "--other=x"}, map[string]bool{"exclude": true, "other": true}, nil)
+	if got := args.List("exclude"); !reflect.DeepEqual(got, []string{"a/**", "b", "c"}) {
+		t.Errorf("List(exclude) = %q, want [a/** b c]", got)
+	}
+	if got := args.String("exclude", "default"); got != "" {
+		t.Errorf("String(exclude) = %q, want the last value", got)
+	}
+	if got := args.List("missing"); got != nil {
+		t.Errorf("List(missing) = %q, want nil", got)
+	}
+}
@@ -0,0 +1,314 @@
+// Package config loads the optional repository configuration file,
+// .tofu-hooks.yaml or .tofu-hooks.json, which holds per-hook settings.
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// FileNames lists the configuration files looked up in the repository root,
+// in order. The first one found is used.
+var FileNames = []string{".tofu-hooks.yaml", ".tofu-hooks.yml", ".tofu-hooks.json"}
+
+// Config is the configuration file: one section per hook.
+type Config struct {
+	Fmt      Hook `yaml:"fmt" json:"fmt"`
+	Validate Hook `yaml:"validate" json:"validate"`
+	Test     Hook `yaml:"test" json:"test"`
+}
+
+// Hook holds the settings of one hook. Command line flags take precedence.
+type Hook struct {
+	// Include limits the hook to paths matching one of these globs.
+	Include []string `yaml:"include" json:"include"`
+	// Exclude drops paths matching one of these globs. It is passed to the
+	// hook as -exclude flags, so excluded paths are reported as skipped.
+	Exclude []string `yaml:"exclude" json:"exclude"`
+	// Skip lists globs of paths that are skipped with a note in the output.
+	Skip []string `yaml:"skip" json:"skip"`
+	// Args are added to the hook's command line, before its own arguments.
+	Args []string `yaml:"args" json:"args"`
+	// Jobs is the number of directories or test suites processed concurrently.
+	Jobs        int     `yaml:"jobs" json:"jobs"`
+	Binary      string  `yaml:"binary" json:"binary"`
+	TofuVersion string  `yaml:"tofu-version" json:"tofu-version"`
+	Reports     Reports `yaml:"reports" json:"reports"`
+	// Timeouts limits how long each step may run, keyed by step ("default"
+	// for every step) with durations such as "5m".
+	Timeouts map[string]string `yaml:"timeouts" json:"timeouts"`
+	// Directories overrides settings per directory, keyed by the directory's
+	// path relative to the repository root.
+	Directories map[string]Directory `yaml:"directories" json:"directories"`
+}
+
+// Reports selects the reports a hook writes.
+type Reports struct {
+	JSON              string `yaml:"json" json:"json"`
+	SARIF             string `yaml:"sarif" json:"sarif"`
+	JUnit             string `yaml:"junit" json:"junit"`
+	GitHubAnnotations *bool  `yaml:"github-annotations" json:"github-annotations"`
+}
+
+// Directory holds the settings of one directory.
+type Directory struct {
+	// Args are added to the tofu arguments used in the directory.
+	Args []string `yaml:"args" json:"args"`
+	// Skip skips the directory and everything below it.
+	Skip bool `yaml:"skip" json:"skip"`
+}
+
+// Load reads the first of FileNames found in dir. It returns an empty Config
+// when there is none.
+func Load(dir string) (Config, error) {
+	for _, name := range FileNames {
+		data, err := os.ReadFile(filepath.Join(dir, name))
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return Config{}, err
+		}
+		cfg, err := Parse(name, data)
+		if err != nil {
+			return Config{}, fmt.Errorf("invalid %s: %w", name, err)
+		}
+		return cfg, nil
+	}
+	return Config{}, nil
+}
+
+// LoadHook loads the configuration in dir and returns the named hook's
+// section: "fmt", "validate" or "test".
+func LoadHook(dir, name string) (Hook, error) {
+	cfg, err := Load(dir)
+	if err != nil {
+		return Hook{}, err
+	}
+	switch name {
+	case "fmt":
+		return cfg.Fmt, nil
+	case "validate":
+		return cfg.Validate, nil
+	case "test":
+		return cfg.Test, nil
+	}
+	return Hook{}, fmt.Errorf("unknown hook %q", name)
+}
+
+// Parse decodes a configuration file, as YAML unless name ends in .json, and
+// validates it. Unknown keys are an error.
+func Parse(name string, data []byte) (Config, error) {
+	var cfg Config
+	if strings.HasSuffix(name, ".json") {
+		dec := json.NewDecoder(bytes.NewReader(data))
+		dec.DisallowUnknownFields()
+		if err := dec.Decode(&cfg); err != nil {
+			return Config{}, err
+		}
+	} else {
+		dec := yaml.NewDecoder(bytes.NewReader(data))
+		dec.KnownFields(true)
+		if err := dec.Decode(&cfg); err != nil && !errors.Is(err, io.EOF) {
+			return Config{}, err
+		}
+	}
+	if err := errors.Join(
+		cfg.Fmt.validate("fmt"),
+		cfg.Validate.validate("validate"),
+		cfg.Test.validate("test"),
+	); err != nil {
+		return Config{}, err
+	}
+	for _, hook := range []*Hook{&cfg.Fmt, &cfg.Validate, &cfg.Test} {
+		hook.Directories = cleanKeys(hook.Directories)
+	}
+	return cfg, nil
+}
+
+// steps lists the steps of each hook that take a timeout
+var steps = map[string][]string{
+	"fmt":      {"fmt"},
+	"validate": {"init", "validate"},
+	"test":     {"init", "test"},
+}
+
+// validate rejects settings the named hook does not support
+func (h Hook) validate(name string) error {
+	var unsupported []string
+	for _, step := range sortedKeys(h.Timeouts) {
+		if step != "default" && !slices.Contains(steps[name], step) {
+			unsupported = append(unsupported, "timeouts."+step)
+		}
+	}
+	if h.Jobs < 0 {
+		return fmt.Errorf("%s.jobs must not be negative", name)
+	}
+	if name == "fmt" && h.Jobs != 0 {
+		unsupported = append(unsupported, "jobs")
+	}
+	if name != "test" && h.Reports.JUnit != "" {
+		unsupported = append(unsupported, "reports.junit")
+	}
+	if name == "test" && h.Reports.SARIF != "" {
+		unsupported = append(unsupported, "reports.sarif")
+	}
+	if len(unsupported) > 0 {
+		return fmt.Errorf("%s does not support %s", name, strings.Join(unsupported, ", "))
+	}
+	return nil
+}
+
+// cleanKeys normalizes directory keys such as "./modules/net/" to "modules/net"
+func cleanKeys(dirs map[string]Directory) map[string]Directory {
+	if len(dirs) == 0 {
+		return dirs
+	}
+	cleaned := make(map[string]Directory, len(dirs))
+	for dir, settings := range dirs {
+		cleaned[path.Clean(filepath.ToSlash(dir))] = settings
+	}
+	return cleaned
+}
+
+// Flags returns the hook-level settings as command line flags.
+func (h Hook) Flags() []string {
+	var flags []string
+	add := func(name, value string) {
+		if value != "" {
+			flags = append(flags, "-"+name+"="+value)
+		}
+	}
+	if h.Jobs > 0 {
+		add("jobs", strconv.Itoa(h.Jobs))
+	}
+	add("binary", h.Binary)
+	add("tofu-version", h.TofuVersion)
+	add("report-json", h.Reports.JSON)
+	add("report-sarif", h.Reports.SARIF)
+	add("report-junit", h.Reports.JUnit)
+	if h.Reports.GitHubAnnotations != nil {
+		add("github-annotations", strconv.FormatBool(*h.Reports.GitHubAnnotations))
+	}
+	for _, step := range sortedKeys(h.Timeouts) {
+		if step == "default" {
+			add("timeout", h.Timeouts[step])
+		} else {
+			add("timeout", step+"="+h.Timeouts[step])
+		}
+	}
+	for _, glob := range h.Exclude {
+		add("exclude", glob)
+	}
+	return flags
+}
+
+// sortedKeys returns the keys of m in order
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// Merge returns the command line to parse: the settings' flags and args
+// followed by args, so that flags given on the command line win.
+func (h Hook) Merge(args []string) []string {
+	merged := append(h.Flags(), h.Args...)
+	return append(merged, args...)
+}
+
+// Selected reports whether the slash-separated path, relative to the
+// repository root, passes the include and exclude globs.
+func (h Hook) Selected(name string) bool {
+	if len(h.Include) > 0 && !matchAny(h.Include, name) {
+		return false
+	}
+	return !matchAny(h.Exclude, name)
+}
+
+// Skipped reports whether the path is in the skip list or inside a directory
+// configured with skip: true.
+func (h Hook) Skipped(name string) bool {
+	if matchAny(h.Skip, name) {
+		return true
+	}
+	for _, dir := range parents(name) {
+		if h.Directories[dir].Skip {
+			return true
+		}
+	}
+	return false
+}
+
+// ArgsFor returns extra followed by the args configured for dir, a
+// slash-separated path relative to the repository root.
+func (h Hook) ArgsFor(dir string, extra []string) []string {
+	args := append([]string{}, extra...)
+	return append(args, h.Directories[path.Clean(filepath.ToSlash(dir))].Args...)
+}
+
+// matchAny reports whether any of the globs matches name or a parent of it
+func matchAny(patterns []string, name string) bool {
+	for _, pattern := range patterns {
+		for _, candidate := range parents(name) {
+			if Match(pattern, candidate) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// parents returns the cleaned path followed by each of its parent directories
+func parents(name string) []string {
+	name = path.Clean(filepath.ToSlash(name))
+	list := []string{name}
+	for dir := path.Dir(name); dir != "." && dir != "/"; dir = path.Dir(dir) {
+		list = append(list, dir)
+	}
+	return list
+}
+
+// Match reports whether the slash-separated name matches the glob. Segments
+// are matched with path.Match, and a "**" segment matches any number of
+// segments, including none. Leading "./" and trailing "/" are ignored.
+func Match(pattern, name string) bool {
+	pattern = strings.TrimSuffix(strings.TrimPrefix(pattern, "./"), "/")
+	return matchSegments(strings.Split(pattern, "/"), strings.Split(path.Clean(name), "/"))
+}
+
+func matchSegments(pattern, name []string) bool {
+	for len(pattern) > 0 {
+		if pattern[0] == "**" {
+			for i := 0; i <= len(name); i++ {
+				if matchSegments(pattern[1:], name[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+		if len(name) == 0 {
+			return false
+		}
+		if ok, err := path.Match(pattern[0], name[0]); err != nil || !ok {
+			return false
+		}
+		pattern, name = pattern[1:], name[1:]
+	}
+	return len(name) == 0
+}
@@ -0,0 +1,201 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const sampleYAML = `
+fmt:
+  exclude: ["vendor/**"]
+validate:
+  include: ["modules/**", "envs/*"]
+  exclude: ["modules/legacy"]
+  skip: ["envs/sandbox"]
+  args: ["-no-color"]
+  jobs: 4
+  reports:
+    json: reports/validate.json
+    github-annotations: false
+  directories:
+    ./envs/prod/:
+      args: ["-var-file=prod.tfvars"]
+    modules/net:
+      skip: true
+test:
+  tofu-version: ">= 1.8"
+  reports:
+    junit: reports/test.xml
+`
+
+func TestParse(t *testing.T) {
+	cfg, err := Parse(".tofu-hooks.yaml", []byte(sampleYAML))
+	if err != nil {
+		t.Fatalf("Parse() error: %v", err)
+	}
+	if cfg.Validate.Jobs != 4 || cfg.Validate.Reports.JSON != "reports/validate.json" {
+		t.Errorf("Validate = %+v", cfg.Validate)
+	}
+	if _, ok := cfg.Validate.Directories["envs/prod"]; !ok {
+		t.Errorf("Directories keys not cleaned: %v", cfg.Validate.Directories)
+	}
+	if cfg.Test.Reports.JUnit != "reports/test.xml" || cfg.Fmt.Exclude[0] != "vendor/**" {
+		t.Errorf("Parse() = %+v", cfg)
+	}
+
+	json := `{"validate": {"jobs": 2, "args": ["-no-color"]}}`
+	cfg, err = Parse(".tofu-hooks.json", []byte(json))
+	if err != nil || cfg.Validate.Jobs != 2 {
+		t.Errorf("Parse(json) = %+v, %v", cfg, err)
+	}
+
+	if _, err := Parse(".tofu-hooks.yaml", nil); err != nil {
+		t.Errorf("Parse(empty) error: %v", err)
+	}
+}
+
+func TestParse_Invalid(t *testing.T) {
+	cases := []struct {
+		name, file, content, want string
+	}{
+		{"unknown hook", ".tofu-hooks.yaml", "lint: {}\n", "field lint not found"},
+		{"unknown key", ".tofu-hooks.yaml", "validate:\n  parallel: 2\n", "field parallel not found"},
+		{"unknown nested key", ".tofu-hooks.yaml", "validate:\n  reports:\n    html: x\n", "field html not found"},
+		{"unknown json key", ".tofu-hooks.json", `{"fmt": {"recurse": true}}`, `unknown field "recurse"`},
+		{"negative jobs", ".tofu-hooks.yaml", "validate:\n  jobs: -1\n", "validate.jobs must not be negative"},
+		{"unsupported setting", ".tofu-hooks.yaml", "fmt:\n  jobs: 2\n  reports:\n    junit: x.xml\n", "fmt does not support jobs, reports.junit"},
+		{"test sarif", ".tofu-hooks.yaml", "test:\n  reports:\n    sarif: x.sarif\n", "test does not support reports.sarif"},
+		{"timeout step", ".tofu-hooks.yaml", "fmt:\n  timeouts:\n    init: 1m\n", "fmt does not support timeouts.init"},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			_, err := Parse(c.file, []byte(c.content))
+			if err == nil || !strings.Contains(err.Error(), c.want) {
+				t.Errorf("Parse() = %v, want error containing %q", err, c.want)
+			}
+		})
+	}
+}
+
+func TestLoadHook(t *testing.T) {
+	dir := t.TempDir()
+	hook, err := LoadHook(dir, "validate")
+	if err != nil || !reflect.DeepEqual(hook, Hook{}) {
+		t.Errorf("LoadHook() without a file = %+v, %v; want empty", hook, err)
+	}
+
+	if err := os.WriteFile(filepath.Join(dir, ".tofu-hooks.json"), []byte(`{"fmt": {"args": ["-list=true"]}}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, ".tofu-hooks.yaml"), []byte("fmt:\n  args: [-diff]\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	hook, err = LoadHook(dir, "fmt")
+	if err != nil || !reflect.DeepEqual(hook.Args, []string{"-diff"}) {
+		t.Errorf("LoadHook() = %+v, %v; want the YAML file to win", hook, err)
+	}
+
+	if err := os.WriteFile(filepath.Join(dir, ".tofu-hooks.yaml"), []byte("fmt:\n  bogus: 1\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadHook(dir, "fmt"); err == nil || !strings.HasPrefix(err.Error(), "invalid .tofu-hooks.yaml: ") {
+		t.Errorf("LoadHook() = %v, want error naming the file", err)
+	}
+	if _, err := LoadHook(t.TempDir(), "lint"); err == nil {
+		t.Error("LoadHook() expected error for unknown hook")
+	}
+}
+
+func TestHook_Merge(t *testing.T) {
+	annotations := false
+	hook := Hook{
+		Args:        []string{"-no-color"},
+		Jobs:        4,
+		Binary:      "terraform",
+		TofuVersion: ">= 1.8",
+		Reports:     Reports{JSON: "r.json", SARIF: "r.sarif", GitHubAnnotations: &annotations},
+		Timeouts:    map[string]string{"validate": "1m", "default": "10m", "init": "5m"},
+		Exclude:     []string{"vendor/**", "examples"},
+	}
+	got := hook.Merge([]string{"-jobs=2", "main.tf"})
+	want := []string{
+		"-jobs=4", "-binary=terraform", "-tofu-version=>= 1.8", "-report-json=r.json", "-report-sarif=r.sarif",
+		"-github-annotations=false", "-timeout=10m", "-timeout=init=5m", "-timeout=validate=1m",
+		"-exclude=vendor/**", "-exclude=examples", "-no-color", "-jobs=2", "main.tf",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Merge() = %q, want %q", got, want)
+	}
+	if got := (Hook{}).Merge([]string{"a.tf"}); !reflect.DeepEqual(got, []string{"a.tf"}) {
+		t.Errorf("empty Merge() = %q", got)
+	}
+}
+
+func TestHook_Paths(t *testing.T) {
+	cfg, err := Parse(".tofu-hooks.yaml", []byte(sampleYAML))
+	if err != nil {
+		t.Fatal(err)
+	}
+	hook := cfg.Validate
+	cases := []struct {
+		path              string
+		selected, skipped bool
+	}{
+		{"modules/net", true, true},
+		{"modules/net/sub", true, true},
+		{"modules/legacy", false, false},
+		{"modules/legacy/inner", false, false},
+		{"envs/prod", true, false},
+		{"envs/sandbox", true, true},
+		{"envs/prod/nested", true, false},
+		{".", false, false},
+	}
+	for _, c := range cases {
+		if got := hook.Selected(c.path); got != c.selected {
+			t.Errorf("Selected(%q) = %v, want %v", c.path, got, c.selected)
+		}
+		if got := hook.Skipped(c.path); got != c.skipped {
+			t.Errorf("Skipped(%q) = %v, want %v", c.path, got, c.skipped)
+		}
+	}
+	if !cfg.Fmt.Selected("main.tf") || cfg.Fmt.Selected("vendor/mod/main.tf") {
+		t.Error("fmt exclude glob not applied to files")
+	}
+
+	extra := []string{"-no-color"}
+	if got := hook.ArgsFor("envs/prod", extra); !reflect.DeepEqual(got, []string{"-no-color", "-var-file=prod.tfvars"}) {
+		t.Errorf("ArgsFor() = %q", got)
+	}
+	if got := hook.ArgsFor("envs/dev", extra); !reflect.DeepEqual(got, extra) {
+		t.Errorf("ArgsFor() = %q, want %q", got, extra)
+	}
+	if len(extra) != 1 {
+		t.Errorf("ArgsFor() modified its argument: %q", extra)
+	}
+}
+
+func TestMatch(t *testing.T) {
+	cases := []struct {
+		pattern, name string
+		want          bool
+	}{
+		{"modules/*", "modules/net", true},
+		{"modules/*", "modules/net/sub", false},
+		{"modules/**", "modules/net/sub", true},
+		{"**/*.tofu", "main.tofu", true},
+		{"**/*.tofu", "a/b/main.tofu", true},
+		{"a/**/z", "a/z", true},
+		{"a/**/z", "a/b/c/z", true},
+		{"./envs/", "envs", true},
+		{"env?", "envs", true},
+		{"[", "[", false},
+	}
+	for _, c := range cases {
+		if got := Match(c.pattern, c.name); got != c.want {
+			t.Errorf("Match(%q, %q) = %v, want %v", c.pattern, c.name, got, c.want)
+		}
+	}
+}
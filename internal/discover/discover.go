@@ -0,0 +1,256 @@
+// Package discover walks a repository for the files the hooks work on,
+// honouring .tofuignore files, exclude globs and, optionally, git.
+package discover
+
+import (
+	"bufio"
+	"errors"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"pre-commit-hooks/internal/config"
+)
+
+// IgnoreFile is the name of the pattern files read at every directory level.
+// They use gitignore syntax and apply to the directory holding them and
+// everything below it.
+const IgnoreFile = ".tofuignore"
+
+// Options narrow down a walk.
+type Options struct {
+	// Exclude lists globs of paths, relative to the walked root, to leave out.
+	Exclude []string
+	// Git selects the files considered when the root is in a git work tree.
+	Git GitMode
+}
+
+// Skipped is a directory or file left out by an ignore rule, an exclude glob
+// or git.
+type Skipped struct {
+	// Path is the skipped path, joined with the walked root.
+	Path string
+	// Reason names the rule, such as "ignored by modules/.tofuignore" or
+	// "excluded by --exclude examples/**".
+	Reason string
+}
+
+// Result lists what a walk found.
+type Result struct {
+	// Dirs are the directories holding at least one matching file, children
+	// before their parents.
+	Dirs []string
+	// Files are the matching files, in walk order.
+	Files []string
+	// Skipped are the ignored or excluded paths that hold matching files.
+	Skipped []Skipped
+}
+
+// rule is one line of an ignore file
+type rule struct {
+	// base is the slash-separated directory of the ignore file, relative to
+	// the walked root; "." for the root
+	base    string
+	glob    string
+	negate  bool
+	dirOnly bool
+	source  string
+}
+
+// Walk visits root and every directory below it, except hidden directories
+// (such as .git and .terraform), ignored ones, those matching an exclude glob
+// and, with a git mode, those git leaves out. match selects the files of
+// interest by name. Read errors, and a failure to list the git files, are
+// joined into the returned error; the walk continues past them, without git
+// in the latter case.
+func Walk(root string, opts Options, match func(name string) bool) (Result, error) {
+	w := walker{root: root, exclude: opts.Exclude, match: match}
+	var errs []error
+	if opts.Git != GitOff {
+		git, err := listGitFiles(root, opts.Git)
+		if err != nil {
+			errs = append(errs, err)
+		}
+		w.git = git
+	}
+	errs = append(errs, w.walk(root, nil))
+	return w.result, errors.Join(errs...)
+}
+
+type walker struct {
+	root    string
+	exclude []string
+	git     *gitPaths
+	match   func(string) bool
+	result  Result
+}
+
+func (w *walker) walk(dir string, rules []rule) error {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+	rel := w.rel(dir)
+	rules, err = readIgnoreFile(rules, dir, rel)
+	var errs []error
+	if err != nil {
+		errs = append(errs, err)
+	}
+
+	found := false
+	for _, entry := range entries {
+		name := entry.Name()
+		entryPath := filepath.Join(dir, name)
+		entryRel := path.Join(rel, name)
+		if entry.IsDir() {
+			if strings.HasPrefix(name, ".") {
+				continue
+			}
+			if reason := w.skipReason(entryRel, true, rules); reason != "" {
+				if w.holdsMatches(entryPath) {
+					w.result.Skipped = append(w.result.Skipped, Skipped{Path: entryPath, Reason: reason})
+				}
+				continue
+			}
+			if err := w.walk(entryPath, rules); err != nil {
+				errs = append(errs, err)
+			}
+			continue
+		}
+		if !w.match(name) {
+			continue
+		}
+		if reason := w.skipReason(entryRel, false, rules); reason != "" {
+			w.result.Skipped = append(w.result.Skipped, Skipped{Path: entryPath, Reason: reason})
+			continue
+		}
+		found = true
+		w.result.Files = append(w.result.Files, entryPath)
+	}
+	if found {
+		w.result.Dirs = append(w.result.Dirs, dir)
+	}
+	return errors.Join(errs...)
+}
+
+// rel returns the slash-separated path of p relative to the root
+func (w *walker) rel(p string) string {
+	rel, err := filepath.Rel(w.root, p)
+	if err != nil {
+		return filepath.ToSlash(p)
+	}
+	return filepath.ToSlash(rel)
+}
+
+// skipReason returns why the path is left out, or "" when it is not
+func (w *walker) skipReason(rel string, isDir bool, rules []rule) string {
+	for _, pattern := range w.exclude {
+		if config.Match(pattern, rel) {
+			return "excluded by --exclude " + pattern
+		}
+	}
+	reason := ""
+	for _, r := range rules {
+		if r.matches(rel, isDir) {
+			reason = ""
+			if !r.negate {
+				reason = "ignored by " + r.source
+			}
+		}
+	}
+	if reason == "" {
+		reason = w.git.skipReason(rel, isDir)
+	}
+	return reason
+}
+
+// holdsMatches reports whether any non-hidden directory below dir holds a
+// matching file, so that skipping it is worth reporting
+func (w *walker) holdsMatches(dir string) bool {
+	found := false
+	filepath.WalkDir(dir, func(p string, d fs.DirEntry, err error) error {
+		switch {
+		case err != nil:
+			return nil
+		case d.IsDir() && p != dir && strings.HasPrefix(d.Name(), "."):
+			return filepath.SkipDir
+		case !d.IsDir() && w.match(d.Name()):
+			found = true
+			return filepath.SkipAll
+		}
+		return nil
+	})
+	return found
+}
+
+// matches reports whether the rule applies to the slash-separated path,
+// relative to the walked root
+func (r rule) matches(rel string, isDir bool) bool {
+	if r.dirOnly && !isDir {
+		return false
+	}
+	if r.base != "." {
+		var ok bool
+		if rel, ok = strings.CutPrefix(rel, r.base+"/"); !ok {
+			return false
+		}
+	}
+	return config.Match(r.glob, rel)
+}
+
+// readIgnoreFile appends the rules of dir's ignore file, if any, to a copy of
+// rules
+func readIgnoreFile(rules []rule, dir, rel string) ([]rule, error) {
+	file, err := os.Open(filepath.Join(dir, IgnoreFile))
+	if errors.Is(err, os.ErrNotExist) {
+		return rules, nil
+	}
+	if err != nil {
+		return rules, err
+	}
+	defer file.Close()
+
+	source := path.Join(rel, IgnoreFile)
+	parsed := append([]rule(nil), rules...)
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		if r, ok := parseRule(scanner.Text()); ok {
+			r.base, r.source = rel, source
+			parsed = append(parsed, r)
+		}
+	}
+	return parsed, scanner.Err()
+}
+
+// parseRule parses one gitignore-style line: blank lines and # comments are
+// skipped, ! negates, a trailing / matches only directories, and a pattern
+// holding a / other than a trailing one is anchored to the ignore file's
+// directory; otherwise it matches at any depth.
+func parseRule(line string) (rule, bool) {
+	line = strings.TrimRight(line, " \t\r")
+	if line == "" || strings.HasPrefix(line, "#") {
+		return rule{}, false
+	}
+	var r rule
+	if strings.HasPrefix(line, "!") {
+		r.negate = true
+		line = line[1:]
+	} else if strings.HasPrefix(line, `\#`) || strings.HasPrefix(line, `\!`) {
+		line = line[1:]
+	}
+	if strings.HasSuffix(line, "/") {
+		r.dirOnly = true
+		line = strings.TrimSuffix(line, "/")
+	}
+	if line == "" {
+		return rule{}, false
+	}
+	if strings.Contains(line, "/") {
+		r.glob = strings.TrimPrefix(line, "/")
+	} else {
+		r.glob = "**/" + line
+	}
+	return r, true
+}
@@ -0,0 +1,121 @@
+package discover
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+)
+
+// writeTree creates each file (relative to root) with the given content
+func writeTree(t *testing.T, root string, files map[string]string) {
+	t.Helper()
+	for rel, content := range files {
+		p := filepath.Join(root, filepath.FromSlash(rel))
+		if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func isTF(name string) bool {
+	return strings.HasSuffix(name, ".tf")
+}
+
+// rels returns paths relative to root with forward slashes
+func rels(root string, paths []string) []string {
+	out := []string{}
+	for _, p := range paths {
+		rel, _ := filepath.Rel(root, p)
+		out = append(out, filepath.ToSlash(rel))
+	}
+	return out
+}
+
+func TestWalk(t *testing.T) {
+	root := t.TempDir()
+	writeTree(t, root, map[string]string{
+		"main.tf":                       "",
+		".tofuignore":                   "# scratch work\nscratch/\nexamples/*\n!examples/keep\n",
+		"modules/net/main.tf":           "",
+		"modules/net/.tofuignore":       "broken.tf\n/fixtures/\n",
+		"modules/net/broken.tf":         "",
+		"modules/net/fixtures/main.tf":  "",
+		"modules/net/sub/fixtures/a.tf": "",
+		"scratch/main.tf":               "",
+		"deep/scratch/main.tf":          "",
+		"examples/basic/main.tf":        "",
+		"examples/keep/main.tf":         "",
+		"vendor/mod/main.tf":            "",
+		"docs/readme.md":                "",
+		"notes/.tofuignore":             "*\n",
+		"notes/todo.txt":                "",
+		".terraform/modules/x/main.tf":  "",
+	})
+
+	got, err := Walk(root, Options{Exclude: []string{"vendor/**"}}, isTF)
+	if err != nil {
+		t.Fatalf("Walk() error: %v", err)
+	}
+	wantDirs := []string{"examples/keep", "modules/net/sub/fixtures", "modules/net", "."}
+	if dirs := rels(root, got.Dirs); !reflect.DeepEqual(dirs, wantDirs) {
+		t.Errorf("Dirs = %v, want %v", dirs, wantDirs)
+	}
+	wantFiles := []string{"examples/keep/main.tf", "main.tf", "modules/net/main.tf", "modules/net/sub/fixtures/a.tf"}
+	files := rels(root, got.Files)
+	sort.Strings(files)
+	if !reflect.DeepEqual(files, wantFiles) {
+		t.Errorf("Files = %v, want %v", files, wantFiles)
+	}
+
+	skipped := map[string]string{}
+	for _, s := range got.Skipped {
+		rel, _ := filepath.Rel(root, s.Path)
+		skipped[filepath.ToSlash(rel)] = s.Reason
+	}
+	wantSkipped := map[string]string{
+		"deep/scratch":          "ignored by .tofuignore",
+		"examples/basic":        "ignored by .tofuignore",
+		"modules/net/broken.tf": "ignored by modules/net/.tofuignore",
+		"modules/net/fixtures":  "ignored by modules/net/.tofuignore",
+		"scratch":               "ignored by .tofuignore",
+		"vendor":                "excluded by --exclude vendor/**",
+	}
+	if !reflect.DeepEqual(skipped, wantSkipped) {
+		t.Errorf("Skipped = %v, want %v", skipped, wantSkipped)
+	}
+}
+
+func TestWalk_Errors(t *testing.T) {
+	if _, err := Walk(filepath.Join(t.TempDir(), "missing"), Options{}, isTF); err == nil {
+		t.Error("Walk() expected error for missing root")
+	}
+}
+
+func TestParseRule(t *testing.T) {
+	cases := []struct {
+		line string
+		want rule
+		ok   bool
+	}{
+		{"", rule{}, false},
+		{"# comment", rule{}, false},
+		{"scratch/", rule{glob: "**/scratch", dirOnly: true}, true},
+		{"/fixtures", rule{glob: "fixtures"}, true},
+		{"examples/*  ", rule{glob: "examples/*"}, true},
+		{"!keep", rule{glob: "**/keep", negate: true}, true},
+		{`\#literal`, rule{glob: "**/#literal"}, true},
+		{"/", rule{}, false},
+	}
+	for _, c := range cases {
+		got, ok := parseRule(c.line)
+		if ok != c.ok || got != c.want {
+			t.Errorf("parseRule(%q) = %+v, %v; want %+v, %v", c.line, got, ok, c.want, c.ok)
+		}
+	}
+}
@@ -0,0 +1,93 @@
+package discover
+
+import (
+	"bytes"
+	"fmt"
+	"os/exec"
+	"path"
+	"strings"
+)
+
+// GitMode selects which files a walk considers when the root is in a git
+// work tree.
+type GitMode string
+
+const (
+	// GitOff walks the filesystem without consulting git.
+	GitOff GitMode = ""
+	// GitTracked considers only files in the git index, which includes
+	// staged new files.
+	GitTracked GitMode = "tracked"
+	// GitNotIgnored considers tracked files and untracked files that are not
+	// ignored by .gitignore rules.
+	GitNotIgnored GitMode = "not-ignored"
+)
+
+// ParseGitMode returns the mode named by s; "off" and "" disable git.
+func ParseGitMode(s string) (GitMode, error) {
+	switch mode := GitMode(s); mode {
+	case GitOff, GitTracked, GitNotIgnored:
+		return mode, nil
+	case "off":
+		return GitOff, nil
+	}
+	return GitOff, fmt.Errorf("invalid git discovery mode %q: want %q, %q or \"off\"", s, GitTracked, GitNotIgnored)
+}
+
+// reason describes a path the mode leaves out
+func (m GitMode) reason() string {
+	if m == GitTracked {
+		return "not tracked by git"
+	}
+	return "ignored by git"
+}
+
+// gitPaths holds the slash-separated files git lists below the walked root,
+// relative to it, and every directory holding them
+type gitPaths struct {
+	mode  GitMode
+	files map[string]bool
+	dirs  map[string]bool
+}
+
+// listGitFiles runs git ls-files in root for the given mode
+func listGitFiles(root string, mode GitMode) (*gitPaths, error) {
+	args := []string{"ls-files", "-z", "--cached"}
+	if mode == GitNotIgnored {
+		args = append(args, "--others", "--exclude-standard")
+	}
+	cmd := exec.Command("git", args...)
+	cmd.Dir = root
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return nil, fmt.Errorf("listing git files: %s", msg)
+		}
+		return nil, fmt.Errorf("listing git files: %w", err)
+	}
+
+	paths := &gitPaths{mode: mode, files: map[string]bool{}, dirs: map[string]bool{".": true}}
+	for _, file := range strings.Split(string(out), "\x00") {
+		if file == "" {
+			continue
+		}
+		paths.files[file] = true
+		for dir := path.Dir(file); dir != "." && !paths.dirs[dir]; dir = path.Dir(dir) {
+			paths.dirs[dir] = true
+		}
+	}
+	return paths, nil
+}
+
+// skipReason returns why git leaves the path out, or "" when it does not
+func (g *gitPaths) skipReason(rel string, isDir bool) string {
+	if g == nil {
+		return ""
+	}
+	if isDir && !g.dirs[rel] || !isDir && !g.files[rel] {
+		return g.mode.reason()
+	}
+	return ""
+}
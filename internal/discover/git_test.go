@@ -0,0 +1,106 @@
+package discover
+
+import (
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// initRepo makes root a git work tree with the given files staged
+func initRepo(t *testing.T, root string, staged ...string) {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+	for _, args := range [][]string{{"init", "-q"}, append([]string{"add", "--"}, staged...)} {
+		cmd := exec.Command("git", args...)
+		cmd.Dir = root
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %v\n%s", args, err, out)
+		}
+	}
+}
+
+func TestWalk_Git(t *testing.T) {
+	root := t.TempDir()
+	writeTree(t, root, map[string]string{
+		".gitignore":          "build/\n",
+		"main.tf":             "",
+		"modules/net/main.tf": "",
+		"modules/net/wip.tf":  "",
+		"scratch/main.tf":     "",
+		"build/gen/main.tf":   "",
+	})
+	initRepo(t, root, ".gitignore", "main.tf", "modules/net/main.tf")
+
+	cases := []struct {
+		mode        GitMode
+		wantDirs    []string
+		wantSkipped map[string]string
+	}{
+		{GitOff, []string{"build/gen", "modules/net", "scratch", "."}, map[string]string{}},
+		{GitTracked, []string{"modules/net", "."}, map[string]string{
+			"build":              "not tracked by git",
+			"modules/net/wip.tf": "not tracked by git",
+			"scratch":            "not tracked by git",
+		}},
+		{GitNotIgnored, []string{"modules/net", "scratch", "."}, map[string]string{
+			"build": "ignored by git",
+		}},
+	}
+	for _, c := range cases {
+		got, err := Walk(root, Options{Git: c.mode}, isTF)
+		if err != nil {
+			t.Fatalf("Walk(%q) error: %v", c.mode, err)
+		}
+		if dirs := rels(root, got.Dirs); !reflect.DeepEqual(dirs, c.wantDirs) {
+			t.Errorf("Walk(%q) Dirs = %v, want %v", c.mode, dirs, c.wantDirs)
+		}
+		skipped := map[string]string{}
+		for _, s := range got.Skipped {
+			rel, _ := filepath.Rel(root, s.Path)
+			skipped[filepath.ToSlash(rel)] = s.Reason
+		}
+		if !reflect.DeepEqual(skipped, c.wantSkipped) {
+			t.Errorf("Walk(%q) Skipped = %v, want %v", c.mode, skipped, c.wantSkipped)
+		}
+	}
+
+	// .tofuignore and exclude reasons take precedence over git
+	got, _ := Walk(root, Options{Exclude: []string{"scratch"}, Git: GitTracked}, isTF)
+	for _, s := range got.Skipped {
+		if strings.HasSuffix(s.Path, "scratch") && s.Reason != "excluded by --exclude scratch" {
+			t.Errorf("scratch reason = %q, want the exclude glob", s.Reason)
+		}
+	}
+}
+
+func TestWalk_GitOutsideWorkTree(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+	root := t.TempDir()
+	writeTree(t, root, map[string]string{"main.tf": ""})
+	t.Setenv("GIT_CEILING_DIRECTORIES", filepath.Dir(root))
+
+	got, err := Walk(root, Options{Git: GitTracked}, isTF)
+	if err == nil || !strings.Contains(err.Error(), "listing git files") {
+		t.Errorf("Walk() error = %v, want a git listing error", err)
+	}
+	if len(got.Dirs) != 1 {
+		t.Errorf("Walk() Dirs = %v, want the walk to continue without git", got.Dirs)
+	}
+}
+
+func TestParseGitMode(t *testing.T) {
+	for in, want := range map[string]GitMode{"": GitOff, "off": GitOff, "tracked": GitTracked, "not-ignored": GitNotIgnored} {
+		if got, err := ParseGitMode(in); err != nil || got != want {
+			t.Errorf("ParseGitMode(%q) = %q, %v; want %q", in, got, err, want)
+		}
+	}
+	if _, err := ParseGitMode("staged"); err == nil {
+		t.Error("ParseGitMode(\"staged\") expected an error")
+	}
+}
@@ -0,0 +1,141 @@
+// Package hookutil holds the helpers the hook commands share: resolving and
+// displaying the paths they work on, running a step in a directory, and
+// buffering the log of a directory processed in parallel so it can be
+// printed in order.
+package hookutil
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"pre-commit-hooks/internal/output"
+	"pre-commit-hooks/internal/runner"
+)
+
+// AbsPaths resolves paths relative to rootDir and cleans them
+func AbsPaths(rootDir string, paths []string) []string {
+	abs := make([]string, 0, len(paths))
+	for _, path := range paths {
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(rootDir, path)
+		}
+		abs = append(abs, filepath.Clean(path))
+	}
+	return abs
+}
+
+// RelPath returns path relative to rootDir with forward slashes, or path
+// itself when it is not below rootDir
+func RelPath(rootDir, path string) string {
+	rel, err := filepath.Rel(rootDir, path)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return path
+	}
+	return filepath.ToSlash(rel)
+}
+
+// DisplayPath returns dir relative to rootDir, prefixed with the root's base
+// name, or the base name of dir when it is not below rootDir
+func DisplayPath(rootDir, baseDir, dir string) string {
+	rel := RelPath(rootDir, dir)
+	if rel == "." {
+		return baseDir
+	}
+	if rel == dir {
+		return filepath.Base(dir)
+	}
+	return baseDir + "/" + rel
+}
+
+// Timeout returns the limit a step exceeded when err is a timeout, or ""
+func Timeout(err error) string {
+	if limit, ok := runner.TimedOut(err); ok {
+		return limit.String()
+	}
+	return ""
+}
+
+// RunCmdInDir runs a command in the specified directory with env added to the
+// inherited environment, returns all output and error. The command's first
+// arg names the step whose timeout applies.
+func RunCmdInDir(dir string, env []string, args []string) (string, error) {
+	return runner.Step{Name: args[0], Dir: dir, Env: env, Args: args}.CombinedOutput()
+}
+
+// FinishEarly lists the skipped paths and writes the reports when there is
+// nothing left to run, exiting 1 when the reports cannot be written
+func FinishEarly(skipped []output.Result, writeReports func() error, exit func(int)) error {
+	output.PrintSkippedSummary(SkippedMessages(skipped))
+	if err := writeReports(); err != nil {
+		exit(1)
+		return err
+	}
+	exit(0)
+	return nil
+}
+
+// SkippedMessages returns the messages of the skipped results for the
+// skipped summary
+func SkippedMessages(results []output.Result) []output.TofuMessage {
+	messages := make([]output.TofuMessage, len(results))
+	for i, result := range results {
+		messages[i] = result.TofuMessage
+	}
+	return messages
+}
+
+// Log buffers the status lines and command output of one directory while it
+// runs alongside others, so the output can be printed in order afterwards
+type Log struct {
+	entries []logEntry
+}
+
+// logEntry is either a status line or a block of command output
+type logEntry struct {
+	emoji  string
+	msg    string
+	output string
+}
+
+// AddStatus buffers a status line
+func (l *Log) AddStatus(emoji, msg string) {
+	l.entries = append(l.entries, logEntry{emoji: emoji, msg: msg})
+}
+
+// AddOutput buffers a block of command output
+func (l *Log) AddOutput(out string) {
+	l.entries = append(l.entries, logEntry{output: out})
+}
+
+// Replay prints the buffered log, sending status lines through printStatus
+// and indenting command output
+func (l *Log) Replay(printStatus func(string, string)) {
+	for _, entry := range l.entries {
+		if entry.msg != "" {
+			printStatus(entry.emoji, entry.msg)
+		} else {
+			PrintIndentedOutput(entry.output, true)
+		}
+	}
+}
+
+// PrintIndentedOutput prints each line of output indented for better readability
+func PrintIndentedOutput(output string, addNewline bool) {
+	lines := strings.Split(output, "\n")
+	lastNonEmpty := -1
+	for idx := range lines {
+		if strings.TrimSpace(lines[idx]) != "" {
+			lastNonEmpty = idx
+		}
+	}
+	for _, line := range lines {
+		if strings.TrimSpace(line) != "" {
+			fmt.Printf("    %s\n", line)
+		}
+	}
+	// Only add newline if not already present at the end
+	if addNewline && lastNonEmpty != len(lines)-1 {
+		fmt.Println()
+	}
+}
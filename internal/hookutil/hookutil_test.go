@@ -0,0 +1,60 @@
+package hookutil
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestRunCmdInDir(t *testing.T) {
+	if _, err := RunCmdInDir(".", nil, []string{"nonexistentcmd"}); err == nil {
+		t.Error("Expected error for nonexistent command")
+	}
+}
+
+func TestAbsPaths(t *testing.T) {
+	got := AbsPaths("/repo", []string{"modules/a/main.tf", "/repo/modules/b/../c/main.tf", "./main.tf"})
+	want := []string{"/repo/modules/a/main.tf", "/repo/modules/c/main.tf", "/repo/main.tf"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("AbsPaths() = %v, want %v", got, want)
+	}
+}
+
+func TestRelPath(t *testing.T) {
+	cases := []struct{ path, want string }{
+		{"/repo", "."},
+		{"/repo/modules/net", "modules/net"},
+		{"/repo/..net", "..net"},
+		{"/elsewhere/mod", "/elsewhere/mod"},
+	}
+	for _, c := range cases {
+		if got := RelPath("/repo", c.path); got != c.want {
+			t.Errorf("RelPath(%q) = %q, want %q", c.path, got, c.want)
+		}
+	}
+}
+
+func TestDisplayPath(t *testing.T) {
+	cases := []struct{ dir, want string }{
+		{"/repo", "repo"},
+		{"/repo/modules/net", "repo/modules/net"},
+		{"/elsewhere/mod", "mod"},
+	}
+	for _, c := range cases {
+		if got := DisplayPath("/repo", "repo", c.dir); got != c.want {
+			t.Errorf("DisplayPath(%q) = %q, want %q", c.dir, got, c.want)
+		}
+	}
+}
+
+func TestLogReplay(t *testing.T) {
+	var log Log
+	log.AddStatus("🔍", "first")
+	log.AddOutput("ignored by printStatus")
+	log.AddStatus("✅", "second")
+
+	var statuses []string
+	log.Replay(func(emoji, msg string) { statuses = append(statuses, emoji+" "+msg) })
+	if want := []string{"🔍 first", "✅ second"}; !reflect.DeepEqual(statuses, want) {
+		t.Errorf("Replay() statuses = %v, want %v", statuses, want)
+	}
+}
@@ -0,0 +1,250 @@
+package modules
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"pre-commit-hooks/internal/tofubin"
+)
+
+var (
+	moduleBlock     = regexp.MustCompile(`^module\s+"[^"]*"\s*\{`)
+	sourceAttr      = regexp.MustCompile(`(?:^|[\s{])source\s*=\s*"([^"]*)"`)
+	terraformBlock  = regexp.MustCompile(`^terraform\s*\{`)
+	requiredVersion = regexp.MustCompile(`(?:^|[\s{])required_version\s*=\s*"([^"]*)"`)
+)
+
+// Sources returns the source of every module block in the configuration
+// files directly inside dir, as written.
+func Sources(dir string) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	var sources []string
+	for _, entry := range entries {
+		if entry.IsDir() || !tofubin.Current().IsConfigFile(entry.Name()) {
+			continue
+		}
+		content, err := os.ReadFile(filepath.Join(dir, entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+		sources = append(sources, moduleSources(string(content))...)
+	}
+	return sources, nil
+}
+
+// LocalSources returns the directories referenced by local module sources
+// ("./..." or "../...") in the configuration files directly inside dir.
+// Returned paths are joined with dir and cleaned.
+func LocalSources(dir string) ([]string, error) {
+	all, err := Sources(dir)
+	if err != nil {
+		return nil, err
+	}
+	var sources []string
+	seen := map[string]bool{}
+	for _, source := range all {
+		if !strings.HasPrefix(source, "./") && !strings.HasPrefix(source, "../") {
+			continue
+		}
+		path := filepath.Join(dir, source)
+		if !seen[path] {
+			seen[path] = true
+			sources = append(sources, path)
+		}
+	}
+	return sources, nil
+}
+
+// RequiredVersions returns the required_version constraints of the terraform
+// blocks in the configuration files directly inside dir.
+func RequiredVersions(dir string) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	var constraints []string
+	for _, entry := range entries {
+		if entry.IsDir() || !tofubin.Current().IsConfigFile(entry.Name()) {
+			continue
+		}
+		content, err := os.ReadFile(filepath.Join(dir, entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+		constraints = append(constraints, blockAttrs(string(content), terraformBlock, requiredVersion)...)
+	}
+	return constraints, nil
+}
+
+// moduleSources returns the source attribute of every top-level module block.
+func moduleSources(content string) []string {
+	return blockAttrs(content, moduleBlock, sourceAttr)
+}
+
+// blockAttrs returns the string values of attr set directly inside top-level
+// blocks matching block. This is a lightweight scan rather than a full HCL
+// parse: it tracks brace depth outside strings and comments, which is enough
+// for simple attributes such as module sources.
+func blockAttrs(content string, block, attr *regexp.Regexp) []string {
+	var values []string
+	depth := 0
+	inBlock := false
+	for _, line := range strings.Split(stripComments(content), "\n") {
+		trimmed := strings.TrimSpace(line)
+		if depth == 0 && block.MatchString(trimmed) {
+			inBlock = true
+		}
+		if inBlock && depth <= 1 {
+			if m := attr.FindStringSubmatch(trimmed); m != nil {
+				values = append(values, m[1])
+			}
+		}
+		depth += braceDelta(line)
+		if depth <= 0 {
+			depth = 0
+			inBlock = false
+		}
+	}
+	return values
+}
+
+// stripComments removes #, // and /* */ comments outside of strings.
+func stripComments(content string) string {
+	var b strings.Builder
+	inString, inBlock := false, false
+	for i := 0; i < len(content); i++ {
+		c := content[i]
+		switch {
+		case inBlock:
+			if c == '*' && i+1 < len(content) && content[i+1] == '/' {
+				inBlock = false
+				i++
+			} else if c == '\n' {
+				b.WriteByte(c)
+			}
+		case inString:
+			b.WriteByte(c)
+			if c == '\\' && i+1 < len(content) {
+				b.WriteByte(content[i+1])
+				i++
+			} else if c == '"' || c == '\n' {
+				inString = false
+			}
+		case c == '"':
+			inString = true
+			b.WriteByte(c)
+		case c == '#' || (c == '/' && i+1 < len(content) && content[i+1] == '/'):
+			for i < len(content) && content[i] != '\n' {
+				i++
+			}
+			if i < len(content) {
+				b.WriteByte('\n')
+			}
+		case c == '/' && i+1 < len(content) && content[i+1] == '*':
+			inBlock = true
+			i++
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return b.String()
+}
+
+// braceDelta returns the change in block depth caused by line, ignoring
+// braces inside strings.
+func braceDelta(line string) int {
+	delta := 0
+	inString := false
+	for i := 0; i < len(line); i++ {
+		switch c := line[i]; {
+		case inString && c == '\\':
+			i++
+		case c == '"':
+			inString = !inString
+		case !inString && c == '{':
+			delta++
+		case !inString && c == '}':
+			delta--
+		}
+	}
+	return delta
+}
+
+// OwningDir returns the deepest directory in dirs that contains path, walking
+// up from the file's directory. All paths must be absolute and clean.
+func OwningDir(path string, dirs []string) (string, bool) {
+	known := make(map[string]bool, len(dirs))
+	for _, dir := range dirs {
+		known[dir] = true
+	}
+	return owningDir(path, known)
+}
+
+func owningDir(path string, known map[string]bool) (string, bool) {
+	dir := filepath.Dir(path)
+	for {
+		if known[dir] {
+			return dir, true
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", false
+		}
+		dir = parent
+	}
+}
+
+// Affected returns the directories in dirs affected by the changed files: the
+// directory owning each file, plus every directory that references an
+// affected directory through a local module source, transitively. The result
+// keeps the order of dirs. All paths must be absolute and clean.
+func Affected(dirs []string, files []string) []string {
+	known := make(map[string]bool, len(dirs))
+	for _, dir := range dirs {
+		known[dir] = true
+	}
+
+	// dependents maps a module directory to the directories that call it
+	dependents := map[string][]string{}
+	for _, dir := range dirs {
+		sources, err := LocalSources(dir)
+		if err != nil {
+			continue
+		}
+		for _, source := range sources {
+			dependents[source] = append(dependents[source], dir)
+		}
+	}
+
+	affected := map[string]bool{}
+	var queue []string
+	for _, file := range files {
+		if dir, ok := owningDir(file, known); ok && !affected[dir] {
+			affected[dir] = true
+			queue = append(queue, dir)
+		}
+	}
+	for len(queue) > 0 {
+		dir := queue[0]
+		queue = queue[1:]
+		for _, dependent := range dependents[dir] {
+			if !affected[dependent] {
+				affected[dependent] = true
+				queue = append(queue, dependent)
+			}
+		}
+	}
+
+	var result []string
+	for _, dir := range dirs {
+		if affected[dir] {
+			result = append(result, dir)
+		}
+	}
+	return result
+}
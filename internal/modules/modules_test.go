@@ -0,0 +1,167 @@
+package modules
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"pre-commit-hooks/internal/testutil"
+	"pre-commit-hooks/internal/tofubin"
+)
+
+// writeFiles creates each file (relative to root) with the given content
+func writeFiles(t *testing.T, root string, files map[string]string) {
+	t.Helper()
+	for rel, content := range files {
+		path := filepath.Join(root, rel)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("Failed to create directory for %s: %v", rel, err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write %s: %v", rel, err)
+		}
+	}
+}
+
+func TestModuleSources(t *testing.T) {
+	content := `
+# module "commented" { source = "./nope" }
+module "network" {
+  source = "./modules/network" // trailing comment
+  name   = "a { b"
+
+  providers = {
+    source = "not-a-module-source"
+  }
+}
+
+/*
+module "disabled" {
+  source = "../disabled"
+}
+*/
+
+module "inline" { source = "../shared" }
+
+resource "null_resource" "x" {
+  source = "./not-a-module"
+}
+
+module "registry" {
+  source  = "terraform-aws-modules/vpc/aws"
+  version = "5.0.0"
+}
+`
+	got := moduleSources(content)
+	want := []string{"./modules/network", "../shared", "terraform-aws-modules/vpc/aws"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("moduleSources() = %v, want %v", got, want)
+	}
+}
+
+func TestLocalSources(t *testing.T) {
+	root, cleanup := testutil.CreateTempDir(t, "modules_local_sources")
+	defer cleanup()
+	writeFiles(t, root, map[string]string{
+		"main.tf":     `module "a" { source = "./modules/a" }`,
+		"extra.tofu":  "module \"b\" {\n  source = \"../b\"\n}\nmodule \"a2\" {\n  source = \"./modules/a\"\n}\n",
+		"remote.tf":   `module "r" { source = "git::https://example.com/mod.git" }`,
+		"notes.txt":   `module "n" { source = "./ignored" }`,
+		"sub/main.tf": `module "deep" { source = "./deeper" }`,
+	})
+	got, err := LocalSources(root)
+	if err != nil {
+		t.Fatalf("LocalSources() error: %v", err)
+	}
+	want := []string{filepath.Join(filepath.Dir(root), "b"), filepath.Join(root, "modules", "a")}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("LocalSources() = %v, want %v", got, want)
+	}
+	if _, err := LocalSources(filepath.Join(root, "missing")); err == nil {
+		t.Error("Expected error for missing directory")
+	}
+}
+
+func TestRequiredVersions(t *testing.T) {
+	root, cleanup := testutil.CreateTempDir(t, "modules_required_versions")
+	defer cleanup()
+	writeFiles(t, root, map[string]string{
+		"versions.tf": "terraform {\n  required_version = \">= 1.6.0\" # minimum\n\n  required_providers {\n    google = {\n      required_version = \"nested\"\n    }\n  }\n}\n",
+		"main.tofu":   `terraform { required_version = "< 2.0.0" }`,
+		"other.tf":    "# terraform { required_version = \"commented\" }\nlocals {\n  required_version = \"not-terraform\"\n}\n",
+		"sub/main.tf": `terraform { required_version = "1.0.0" }`,
+	})
+	got, err := RequiredVersions(root)
+	if err != nil {
+		t.Fatalf("RequiredVersions() error: %v", err)
+	}
+	want := []string{"< 2.0.0", ">= 1.6.0"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("RequiredVersions() = %v, want %v", got, want)
+	}
+
+	// Terraform does not read .tofu files
+	defer tofubin.Set(tofubin.Set(tofubin.Binary{Name: "terraform", Path: "terraform"}))
+	if got, _ := RequiredVersions(root); !reflect.DeepEqual(got, []string{">= 1.6.0"}) {
+		t.Errorf("RequiredVersions() under terraform = %v, want [>= 1.6.0]", got)
+	}
+	if _, err := RequiredVersions(filepath.Join(root, "missing")); err == nil {
+		t.Error("Expected error for missing directory")
+	}
+}
+
+func TestOwningDir(t *testing.T) {
+	dirs := []string{"/repo", "/repo/modules/net"}
+	cases := []struct {
+		path   string
+		want   string
+		wantOK bool
+	}{
+		{"/repo/main.tf", "/repo", true},
+		{"/repo/modules/net/main.tf", "/repo/modules/net", true},
+		{"/repo/modules/net/tests/unit.tftest.hcl", "/repo/modules/net", true},
+		{"/repo/modules/other/README.md", "/repo", true},
+		{"/elsewhere/main.tf", "", false},
+	}
+	for _, c := range cases {
+		got, ok := OwningDir(c.path, dirs)
+		if got != c.want || ok != c.wantOK {
+			t.Errorf("OwningDir(%q) = %q, %v; want %q, %v", c.path, got, ok, c.want, c.wantOK)
+		}
+	}
+}
+
+func TestAffected(t *testing.T) {
+	root, cleanup := testutil.CreateTempDir(t, "modules_affected")
+	defer cleanup()
+	writeFiles(t, root, map[string]string{
+		"modules/base/main.tf":    `variable "x" {}`,
+		"modules/network/main.tf": `module "base" { source = "../base" }`,
+		"envs/prod/main.tf":       `module "network" { source = "../../modules/network" }`,
+		"envs/dev/main.tf":        `module "base" { source = "../../modules/base" }`,
+		"standalone/main.tf":      `variable "y" {}`,
+	})
+	dir := func(rel string) string { return filepath.Join(root, rel) }
+	dirs := []string{dir("envs/dev"), dir("envs/prod"), dir("modules/base"), dir("modules/network"), dir("standalone")}
+
+	cases := []struct {
+		name  string
+		files []string
+		want  []string
+	}{
+		{"leaf module change cascades", []string{dir("modules/base/main.tf")}, []string{dir("envs/dev"), dir("envs/prod"), dir("modules/base"), dir("modules/network")}},
+		{"middle module change", []string{dir("modules/network/main.tf")}, []string{dir("envs/prod"), dir("modules/network")}},
+		{"root config change", []string{dir("envs/dev/main.tf")}, []string{dir("envs/dev")}},
+		{"unrelated file", []string{filepath.Join(root, "README.md")}, nil},
+		{"multiple files", []string{dir("standalone/main.tf"), dir("envs/dev/terraform.tfvars")}, []string{dir("envs/dev"), dir("standalone")}},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			got := Affected(dirs, c.files)
+			if !reflect.DeepEqual(got, c.want) {
+				t.Errorf("Affected() = %v, want %v", got, c.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,74 @@
+package output
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Diagnostic severities as reported by tofu -json output.
+const (
+	SeverityError   = "error"
+	SeverityWarning = "warning"
+)
+
+// Diagnostic is a structured error or warning reported by tofu -json output.
+type Diagnostic struct {
+	Severity string `json:"severity"`
+	Summary  string `json:"summary"`
+	Detail   string `json:"detail,omitempty"`
+	Range    *Range `json:"range,omitempty"`
+}
+
+// Range is the source location a diagnostic refers to.
+type Range struct {
+	Filename string `json:"filename"`
+	Start    Pos    `json:"start"`
+	End      Pos    `json:"end"`
+}
+
+// Pos is a position in a source file. Line and Column are 1-based.
+type Pos struct {
+	Line   int `json:"line"`
+	Column int `json:"column"`
+	Byte   int `json:"byte"`
+}
+
+// FilterDiagnostics returns the diagnostics with the given severity.
+func FilterDiagnostics(diags []Diagnostic, severity string) []Diagnostic {
+	var filtered []Diagnostic
+	for _, diag := range diags {
+		if diag.Severity == severity {
+			filtered = append(filtered, diag)
+		}
+	}
+	return filtered
+}
+
+// FormatDiagnostic renders a diagnostic the way tofu's text output does:
+// a severity and summary line, the source location, then the detail.
+func FormatDiagnostic(diag Diagnostic) string {
+	var b strings.Builder
+	severity := "Error"
+	if diag.Severity == SeverityWarning {
+		severity = "Warning"
+	}
+	fmt.Fprintf(&b, "%s: %s\n", severity, diag.Summary)
+	if diag.Range != nil && diag.Range.Filename != "" {
+		fmt.Fprintf(&b, "  on %s line %d\n", diag.Range.Filename, diag.Range.Start.Line)
+	}
+	if diag.Detail != "" {
+		for _, line := range strings.Split(diag.Detail, "\n") {
+			fmt.Fprintf(&b, "  %s\n", line)
+		}
+	}
+	return b.String()
+}
+
+// FormatDiagnostics renders each diagnostic with FormatDiagnostic, separated by blank lines.
+func FormatDiagnostics(diags []Diagnostic) string {
+	parts := make([]string, 0, len(diags))
+	for _, diag := range diags {
+		parts = append(parts, FormatDiagnostic(diag))
+	}
+	return strings.Join(parts, "\n")
+}
@@ -0,0 +1,50 @@
+package output
+
+import "testing"
+
+func TestFormatDiagnostic(t *testing.T) {
+	cases := []struct {
+		name string
+		diag Diagnostic
+		want string
+	}{
+		{
+			"error with range and detail",
+			Diagnostic{Severity: SeverityError, Summary: "Unsupported argument", Detail: "An argument named \"foo\" is not expected here.", Range: &Range{Filename: "main.tf", Start: Pos{Line: 3, Column: 3}}},
+			"Error: Unsupported argument\n  on main.tf line 3\n  An argument named \"foo\" is not expected here.\n",
+		},
+		{
+			"warning without range",
+			Diagnostic{Severity: SeverityWarning, Summary: "Deprecated", Detail: "line one\nline two"},
+			"Warning: Deprecated\n  line one\n  line two\n",
+		},
+		{
+			"summary only",
+			Diagnostic{Severity: SeverityError, Summary: "Boom"},
+			"Error: Boom\n",
+		},
+	}
+	for _, c := range cases {
+		if got := FormatDiagnostic(c.diag); got != c.want {
+			t.Errorf("%s: FormatDiagnostic() = %q, want %q", c.name, got, c.want)
+		}
+	}
+}
+
+func TestFilterAndFormatDiagnostics(t *testing.T) {
+	diags := []Diagnostic{
+		{Severity: SeverityWarning, Summary: "w1"},
+		{Severity: SeverityError, Summary: "e1"},
+		{Severity: SeverityWarning, Summary: "w2"},
+	}
+	warnings := FilterDiagnostics(diags, SeverityWarning)
+	if len(warnings) != 2 || warnings[0].Summary != "w1" || warnings[1].Summary != "w2" {
+		t.Errorf("FilterDiagnostics(warning) = %+v", warnings)
+	}
+	if errs := FilterDiagnostics(diags, SeverityError); len(errs) != 1 {
+		t.Errorf("FilterDiagnostics(error) = %+v, want one", errs)
+	}
+	if got, want := FormatDiagnostics(warnings), "Warning: w1\n\nWarning: w2\n"; got != want {
+		t.Errorf("FormatDiagnostics() = %q, want %q", got, want)
+	}
+}
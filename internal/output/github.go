@@ -0,0 +1,120 @@
+package output
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+// GitHubActionsEnv is set to "true" by GitHub Actions on every runner.
+const GitHubActionsEnv = "GITHUB_ACTIONS"
+
+// Annotation is a GitHub Actions ::error or ::warning workflow command.
+type Annotation struct {
+	// Level is "error", "warning" or "notice".
+	Level     string
+	File      string
+	Line      int
+	EndLine   int
+	Column    int
+	EndColumn int
+	Title     string
+	Message   string
+}
+
+// String formats the annotation as a workflow command, escaping its
+// properties and message. Zero line and column values are omitted.
+func (a Annotation) String() string {
+	var props []string
+	add := func(key, value string) {
+		if value != "" {
+			props = append(props, key+"="+escapeProperty(value))
+		}
+	}
+	addInt := func(key string, value int) {
+		if value > 0 {
+			add(key, fmt.Sprint(value))
+		}
+	}
+	add("file", filepath.ToSlash(a.File))
+	addInt("line", a.Line)
+	addInt("endLine", a.EndLine)
+	addInt("col", a.Column)
+	addInt("endColumn", a.EndColumn)
+	add("title", a.Title)
+
+	command := "::" + a.Level
+	if len(props) > 0 {
+		command += " " + strings.Join(props, ",")
+	}
+	return command + "::" + escapeData(a.Message)
+}
+
+// DiagnosticAnnotation returns the annotation for a diagnostic, located at
+// its range or, when it has none, at file.
+func DiagnosticAnnotation(diag Diagnostic, file string) Annotation {
+	annotation := Annotation{Level: sarifLevel(diag.Severity), File: file, Title: diag.Summary, Message: sarifMessage(diag)}
+	if diag.Range != nil && diag.Range.Filename != "" {
+		annotation.File = diag.Range.Filename
+		annotation.Line = diag.Range.Start.Line
+		annotation.EndLine = diag.Range.End.Line
+		// Columns are only meaningful on a single line
+		if diag.Range.Start.Line == diag.Range.End.Line {
+			annotation.Column = diag.Range.Start.Column
+			annotation.EndColumn = diag.Range.End.Column
+		}
+	}
+	return annotation
+}
+
+// ResultAnnotations returns one annotation per diagnostic in results, and one
+// error annotation for each failed result without diagnostics, mirroring
+// NewSARIFLog. Paths should be relative to the repository root.
+func ResultAnnotations(results []Result) []Annotation {
+	var annotations []Annotation
+	for _, result := range results {
+		if len(result.Diagnostics) == 0 {
+			if result.Status == StatusFailed {
+				message := strings.TrimSpace(result.Output)
+				if message == "" {
+					message = "tofu " + result.Step + " failed"
+				}
+				annotations = append(annotations, Annotation{Level: "error", File: result.RelPath, Title: "tofu " + result.Step + " failed", Message: message})
+			}
+			continue
+		}
+		for _, diag := range result.Diagnostics {
+			annotations = append(annotations, DiagnosticAnnotation(diag, result.RelPath))
+		}
+	}
+	return annotations
+}
+
+// PrintAnnotations prints each annotation as a workflow command.
+func PrintAnnotations(annotations []Annotation) {
+	for _, annotation := range annotations {
+		fmt.Println(annotation)
+	}
+}
+
+// GroupStart returns the workflow command that opens a collapsible log group.
+func GroupStart(title string) string {
+	return "::group::" + escapeData(title)
+}
+
+// GroupEnd is the workflow command that closes the current log group.
+const GroupEnd = "::endgroup::"
+
+// escapeData escapes a workflow command message
+func escapeData(s string) string {
+	s = strings.ReplaceAll(s, "%", "%25")
+	s = strings.ReplaceAll(s, "\r", "%0D")
+	return strings.ReplaceAll(s, "\n", "%0A")
+}
+
+// escapeProperty escapes a workflow command property value
+func escapeProperty(s string) string {
+	s = escapeData(s)
+	s = strings.ReplaceAll(s, ":", "%3A")
+	return strings.ReplaceAll(s, ",", "%2C")
+}
@@ -0,0 +1,66 @@
+package output
+
+import (
+	"testing"
+)
+
+func TestAnnotation_String(t *testing.T) {
+	cases := []struct {
+		name       string
+		annotation Annotation
+		want       string
+	}{
+		{"message only", Annotation{Level: "error", Message: "boom"}, "::error::boom"},
+		{
+			"located",
+			Annotation{Level: "warning", File: "modules/net/main.tf", Line: 3, EndLine: 3, Column: 5, EndColumn: 9, Title: "Deprecated", Message: "Use foo instead"},
+			"::warning file=modules/net/main.tf,line=3,endLine=3,col=5,endColumn=9,title=Deprecated::Use foo instead",
+		},
+		{
+			"escaped",
+			Annotation{Level: "error", File: "a,b.tf", Title: "Error: 100%", Message: "line one\nline two 50%"},
+			"::error file=a%2Cb.tf,title=Error%3A 100%25::line one%0Aline two 50%25",
+		},
+	}
+	for _, c := range cases {
+		if got := c.annotation.String(); got != c.want {
+			t.Errorf("%s: String() = %q, want %q", c.name, got, c.want)
+		}
+	}
+}
+
+func TestResultAnnotations(t *testing.T) {
+	results := []Result{
+		{TofuMessage: TofuMessage{Step: "init", RelPath: "ok"}, Status: StatusPassed},
+		{TofuMessage: TofuMessage{Step: "init", RelPath: "broken", Output: "Error: no registry\n"}, Status: StatusFailed},
+		{
+			TofuMessage: TofuMessage{Step: "validate", RelPath: "net", Diagnostics: []Diagnostic{
+				{Severity: SeverityError, Summary: "Unsupported argument", Detail: "Not expected here.", Range: &Range{Filename: "net/main.tf", Start: Pos{Line: 4, Column: 3}, End: Pos{Line: 4, Column: 6}}},
+				{Severity: SeverityWarning, Summary: "Spanning", Range: &Range{Filename: "net/main.tf", Start: Pos{Line: 1, Column: 2}, End: Pos{Line: 2, Column: 3}}},
+				{Severity: SeverityWarning, Summary: "No range"},
+			}},
+			Status: StatusFailed,
+		},
+	}
+	got := ResultAnnotations(results)
+	want := []string{
+		"::error file=broken,title=tofu init failed::Error: no registry",
+		"::error file=net/main.tf,line=4,endLine=4,col=3,endColumn=6,title=Unsupported argument::Unsupported argument: Not expected here.",
+		"::warning file=net/main.tf,line=1,endLine=2,title=Spanning::Spanning",
+		"::warning file=net,title=No range::No range",
+	}
+	if len(got) != len(want) {
+		t.Fatalf("got %d annotations, want %d: %v", len(got), len(want), got)
+	}
+	for i := range want {
+		if got[i].String() != want[i] {
+			t.Errorf("annotation %d = %q, want %q", i, got[i].String(), want[i])
+		}
+	}
+}
+
+func TestGroupStart(t *testing.T) {
+	if got := GroupStart("repo/modules\nnet"); got != "::group::repo/modules%0Anet" {
+		t.Errorf("GroupStart() = %q", got)
+	}
+}
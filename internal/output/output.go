@@ -2,6 +2,7 @@ package output
 
 import (
 	"fmt"
+	"regexp"
 )
 
 // ANSI escape codes for color
@@ -12,6 +13,15 @@ const (
 	Yellow = "\033[33m"
 )
 
+// ansiCode matches ANSI escape sequences, such as the colors and bold text
+// tofu prints
+var ansiCode = regexp.MustCompile(`\x1b\[[0-9;?]*[A-Za-z]`)
+
+// StripANSI removes ANSI escape sequences from text
+func StripANSI(text string) string {
+	return ansiCode.ReplaceAllString(text, "")
+}
+
 // Colorize function to wrap text with given color
 func Colorize(text, color string) string {
 	return fmt.Sprintf("%s%s%s", color, text, Reset)
@@ -59,3 +59,13 @@ func TestColorize_AllColors(t *testing.T) {
 		}
 	}
 }
+
+func TestStripANSI(t *testing.T) {
+	colored := "\x1b[1m\x1b[31mError: \x1b[0m\x1b[0m\x1b[1mBroken\x1b[0m " + Colorize("fail", Red)
+	if got := StripANSI(colored); got != "Error: Broken fail" {
+		t.Errorf("StripANSI() = %q", got)
+	}
+	if got := StripANSI("plain"); got != "plain" {
+		t.Errorf("StripANSI(plain) = %q", got)
+	}
+}
@@ -0,0 +1,110 @@
+package output
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+)
+
+// Renderer formats status lines for the terminal. With Color off no ANSI
+// escape codes are written, and in ASCII mode emoji are replaced by plain
+// text tags such as "[ok]", for log aggregators and CI logs captured to files.
+type Renderer struct {
+	Color bool
+	ASCII bool
+}
+
+// asciiEmoji maps the emoji used in status lines to their ASCII mode tags.
+var asciiEmoji = map[string]string{
+	Error:    "[error]",
+	Warning:  "[warn]",
+	Running:  "[run]",
+	ThumbsUp: "[ok]",
+	"⚠️":     "[warn]",
+	"❗":      "[error]",
+}
+
+// Colorize wraps text with color when color output is enabled.
+func (r Renderer) Colorize(text, color string) string {
+	if !r.Color {
+		return text
+	}
+	return Colorize(text, color)
+}
+
+// Emoji returns emoji, or its ASCII tag in ASCII mode.
+func (r Renderer) Emoji(emoji string) string {
+	if !r.ASCII {
+		return emoji
+	}
+	if tag, ok := asciiEmoji[emoji]; ok {
+		return tag
+	}
+	return "*"
+}
+
+// EmojiColorText combines an emoji and colored text, honoring the renderer's
+// modes.
+func (r Renderer) EmojiColorText(emoji, text, color string) string {
+	return fmt.Sprintf("%s %s", r.Emoji(emoji), r.Colorize(text, color))
+}
+
+// DetectRenderer returns the renderer for output written to f. Color and
+// emoji are used when f is a terminal and plain ASCII otherwise. NO_COLOR
+// disables color; FORCE_COLOR (set to anything but "0" or "false") enables
+// both color and emoji regardless of the terminal and NO_COLOR.
+func DetectRenderer(f *os.File) Renderer {
+	tty := isTerminal(f)
+	r := Renderer{Color: tty, ASCII: !tty}
+	if os.Getenv("NO_COLOR") != "" {
+		r.Color = false
+	}
+	if forceColor() {
+		r = Renderer{Color: true}
+	}
+	return r
+}
+
+// renderer is used by PrintStatus and Paint.
+var renderer = DetectRenderer(os.Stdout)
+
+// SetRenderer replaces the renderer used by PrintStatus and Paint and returns
+// the previous one, so callers and tests can restore it.
+func SetRenderer(r Renderer) Renderer {
+	previous := renderer
+	renderer = r
+	return previous
+}
+
+// CurrentRenderer returns the renderer used by PrintStatus and Paint.
+func CurrentRenderer() Renderer {
+	return renderer
+}
+
+// PrintStatus prints an emoji status line to stdout through the renderer.
+func PrintStatus(emoji, text, color string) {
+	fmt.Println(renderer.EmojiColorText(emoji, text, color))
+}
+
+// Paint colors text through the renderer.
+func Paint(text, color string) string {
+	return renderer.Colorize(text, color)
+}
+
+// isTerminal reports whether f is a character device such as a terminal
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// forceColor reports whether FORCE_COLOR asks for color output
+func forceColor() bool {
+	value := os.Getenv("FORCE_COLOR")
+	if value == "" {
+		return false
+	}
+	if enabled, err := strconv.ParseBool(value); err == nil {
+		return enabled
+	}
+	return true // e.g. FORCE_COLOR=2 or 3 for richer palettes
+}
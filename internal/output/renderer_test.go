@@ -0,0 +1,81 @@
+package output
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestRenderer_EmojiColorText(t *testing.T) {
+	cases := []struct {
+		name     string
+		renderer Renderer
+		emoji    string
+		want     string
+	}{
+		{"color and emoji", Renderer{Color: true}, ThumbsUp, EmojiColorText(ThumbsUp, "done", Green)},
+		{"no color", Renderer{}, ThumbsUp, ThumbsUp + " done"},
+		{"ascii", Renderer{ASCII: true}, ThumbsUp, "[ok] done"},
+		{"ascii with color", Renderer{Color: true, ASCII: true}, Error, "[error] " + Colorize("done", Green)},
+		{"ascii summary emoji", Renderer{ASCII: true}, "⚠️", "[warn] done"},
+		{"ascii unknown emoji", Renderer{ASCII: true}, "🎉", "* done"},
+	}
+	for _, c := range cases {
+		if got := c.renderer.EmojiColorText(c.emoji, "done", Green); got != c.want {
+			t.Errorf("%s: got %q, want %q", c.name, got, c.want)
+		}
+	}
+}
+
+func TestDetectRenderer(t *testing.T) {
+	file, err := os.Create(filepath.Join(t.TempDir(), "log.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	cases := []struct {
+		name       string
+		noColor    string
+		forceColor string
+		want       Renderer
+	}{
+		{"file is plain", "", "", Renderer{ASCII: true}},
+		{"NO_COLOR stays plain", "1", "", Renderer{ASCII: true}},
+		{"FORCE_COLOR", "", "1", Renderer{Color: true}},
+		{"FORCE_COLOR level", "", "3", Renderer{Color: true}},
+		{"FORCE_COLOR beats NO_COLOR", "1", "true", Renderer{Color: true}},
+		{"FORCE_COLOR disabled", "", "0", Renderer{ASCII: true}},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			t.Setenv("NO_COLOR", c.noColor)
+			t.Setenv("FORCE_COLOR", c.forceColor)
+			if got := DetectRenderer(file); got != c.want {
+				t.Errorf("DetectRenderer() = %+v, want %+v", got, c.want)
+			}
+		})
+	}
+}
+
+func TestPrintStatus(t *testing.T) {
+	defer SetRenderer(SetRenderer(Renderer{ASCII: true}))
+	r, w, _ := os.Pipe()
+	oldStdout := os.Stdout
+	os.Stdout = w
+	defer func() { os.Stdout = oldStdout }()
+
+	PrintStatus(Warning, "careful", Yellow)
+	if got := Paint("plain", Red); got != "plain" {
+		t.Errorf("Paint() = %q, want uncolored text", got)
+	}
+	w.Close()
+	out, _ := io.ReadAll(r)
+	if string(out) != "[warn] careful\n" {
+		t.Errorf("PrintStatus() wrote %q, want %q", out, "[warn] careful\n")
+	}
+	if CurrentRenderer() != (Renderer{ASCII: true}) {
+		t.Errorf("CurrentRenderer() = %+v", CurrentRenderer())
+	}
+}
@@ -0,0 +1,71 @@
+package output
+
+import (
+	"encoding/json"
+	"os"
+	"time"
+)
+
+// Result statuses, ordered from best to worst for Report.Status.
+const (
+	StatusSkipped = "skipped"
+	StatusPassed  = "passed"
+	StatusFixed   = "fixed"
+	StatusWarning = "warning"
+	StatusFailed  = "failed"
+)
+
+var statusRank = map[string]int{
+	StatusSkipped: 0,
+	StatusPassed:  1,
+	StatusFixed:   2,
+	StatusWarning: 3,
+	StatusFailed:  4,
+}
+
+// Result is the outcome of one hook step on one directory or file.
+type Result struct {
+	TofuMessage
+	Status   string        `json:"status"`
+	Duration time.Duration `json:"-"`
+}
+
+// MarshalJSON encodes the result with its duration in seconds.
+func (r Result) MarshalJSON() ([]byte, error) {
+	type result Result // drops the MarshalJSON method
+	return json.Marshal(struct {
+		result
+		DurationSeconds float64 `json:"duration_seconds"`
+	}{result(r), r.Duration.Seconds()})
+}
+
+// Report is the machine-readable summary of a hook run.
+type Report struct {
+	Hook    string   `json:"hook"`
+	Status  string   `json:"status"`
+	Results []Result `json:"results"`
+}
+
+// NewReport builds a report for hook whose overall status is the worst
+// status among results, or skipped when there are none.
+func NewReport(hook string, results []Result) Report {
+	status := StatusSkipped
+	for _, result := range results {
+		if statusRank[result.Status] > statusRank[status] {
+			status = result.Status
+		}
+	}
+	if results == nil {
+		results = []Result{}
+	}
+	return Report{Hook: hook, Status: status, Results: results}
+}
+
+// WriteJSONReport writes the report as indented JSON to path.
+func WriteJSONReport(path string, report Report) error {
+	data, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0644)
+}
@@ -0,0 +1,86 @@
+package output
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestNewReport_Status(t *testing.T) {
+	cases := []struct {
+		name     string
+		statuses []string
+		want     string
+	}{
+		{"no results", nil, StatusSkipped},
+		{"all passed", []string{StatusPassed, StatusPassed}, StatusPassed},
+		{"fixed beats passed", []string{StatusPassed, StatusFixed}, StatusFixed},
+		{"warning beats fixed", []string{StatusFixed, StatusWarning, StatusSkipped}, StatusWarning},
+		{"failed wins", []string{StatusFailed, StatusWarning, StatusPassed}, StatusFailed},
+	}
+	for _, c := range cases {
+		var results []Result
+		for _, status := range c.statuses {
+			results = append(results, Result{Status: status})
+		}
+		if got := NewReport("tofuvalidate", results).Status; got != c.want {
+			t.Errorf("%s: Status = %q, want %q", c.name, got, c.want)
+		}
+	}
+}
+
+func TestWriteJSONReport(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "report.json")
+	report := NewReport("tofuvalidate", []Result{
+		{TofuMessage: TofuMessage{Step: "init", RelPath: "repo"}, Status: StatusPassed, Duration: 1500 * time.Millisecond},
+		{
+			TofuMessage: TofuMessage{
+				Step:        "validate",
+				RelPath:     "repo",
+				Output:      "Error: boom",
+				Diagnostics: []Diagnostic{{Severity: SeverityError, Summary: "boom", Range: &Range{Filename: "main.tf", Start: Pos{Line: 2}}}},
+			},
+			Status:   StatusFailed,
+			Duration: 250 * time.Millisecond,
+		},
+	})
+	if err := WriteJSONReport(path, report); err != nil {
+		t.Fatalf("WriteJSONReport() error: %v", err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Failed to read report: %v", err)
+	}
+
+	var decoded struct {
+		Hook    string `json:"hook"`
+		Status  string `json:"status"`
+		Results []struct {
+			Step            string       `json:"step"`
+			Path            string       `json:"path"`
+			Status          string       `json:"status"`
+			Output          string       `json:"output"`
+			DurationSeconds float64      `json:"duration_seconds"`
+			Diagnostics     []Diagnostic `json:"diagnostics"`
+		} `json:"results"`
+	}
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("Report is not valid JSON: %v\n%s", err, data)
+	}
+	if decoded.Hook != "tofuvalidate" || decoded.Status != StatusFailed || len(decoded.Results) != 2 {
+		t.Fatalf("Unexpected report: %s", data)
+	}
+	first, second := decoded.Results[0], decoded.Results[1]
+	if first.Step != "init" || first.Path != "repo" || first.Status != StatusPassed || first.DurationSeconds != 1.5 {
+		t.Errorf("Unexpected first result: %+v", first)
+	}
+	if second.Output != "Error: boom" || len(second.Diagnostics) != 1 || second.Diagnostics[0].Range.Start.Line != 2 {
+		t.Errorf("Unexpected second result: %+v", second)
+	}
+
+	if err := WriteJSONReport(filepath.Join(t.TempDir(), "missing", "report.json"), report); err == nil {
+		t.Error("Expected error writing to a missing directory")
+	}
+}
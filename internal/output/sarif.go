@@ -0,0 +1,197 @@
+package output
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// SARIF 2.1.0 identifiers written at the top of every log.
+const (
+	sarifSchema  = "https://json.schemastore.org/sarif-2.1.0.json"
+	sarifVersion = "2.1.0"
+	toolInfoURI  = "https://github.com/osinfra-io/pre-commit-hooks"
+)
+
+// SARIFLog is the subset of a SARIF 2.1.0 log written by the hooks.
+type SARIFLog struct {
+	Schema  string     `json:"$schema"`
+	Version string     `json:"version"`
+	Runs    []SARIFRun `json:"runs"`
+}
+
+// SARIFRun holds the results of one tool invocation.
+type SARIFRun struct {
+	Tool    SARIFTool     `json:"tool"`
+	Results []SARIFResult `json:"results"`
+}
+
+// SARIFTool describes the hook and the rules its results refer to.
+type SARIFTool struct {
+	Driver SARIFDriver `json:"driver"`
+}
+
+// SARIFDriver is the tool component that produced the results.
+type SARIFDriver struct {
+	Name           string      `json:"name"`
+	InformationURI string      `json:"informationUri"`
+	Rules          []SARIFRule `json:"rules"`
+}
+
+// SARIFRule is a reporting descriptor for one kind of finding.
+type SARIFRule struct {
+	ID               string       `json:"id"`
+	ShortDescription SARIFMessage `json:"shortDescription"`
+}
+
+// SARIFResult is a single finding.
+type SARIFResult struct {
+	RuleID    string          `json:"ruleId"`
+	Level     string          `json:"level"`
+	Message   SARIFMessage    `json:"message"`
+	Locations []SARIFLocation `json:"locations,omitempty"`
+}
+
+// SARIFMessage is a plain text message.
+type SARIFMessage struct {
+	Text string `json:"text"`
+}
+
+// SARIFLocation points a result at a file and, optionally, a region in it.
+type SARIFLocation struct {
+	PhysicalLocation SARIFPhysicalLocation `json:"physicalLocation"`
+}
+
+// SARIFPhysicalLocation is a file and region.
+type SARIFPhysicalLocation struct {
+	ArtifactLocation SARIFArtifactLocation `json:"artifactLocation"`
+	Region           *SARIFRegion          `json:"region,omitempty"`
+}
+
+// SARIFArtifactLocation is a file URI relative to the repository root.
+type SARIFArtifactLocation struct {
+	URI string `json:"uri"`
+}
+
+// SARIFRegion is a range of lines and columns; zero values are omitted.
+type SARIFRegion struct {
+	StartLine   int `json:"startLine,omitempty"`
+	StartColumn int `json:"startColumn,omitempty"`
+	EndLine     int `json:"endLine,omitempty"`
+	EndColumn   int `json:"endColumn,omitempty"`
+}
+
+// NewSARIFLog converts the diagnostics in results into a SARIF log for tool.
+//
+// Each diagnostic becomes one SARIF result located at its range, with a rule
+// ID derived from the step and the diagnostic summary. A failed result without
+// diagnostics, such as a failed init, becomes a single "<step>/failed" result
+// located at the result's path. Diagnostic filenames and result paths should
+// be relative to the repository root.
+func NewSARIFLog(tool string, results []Result) SARIFLog {
+	run := SARIFRun{
+		Tool:    SARIFTool{Driver: SARIFDriver{Name: tool, InformationURI: toolInfoURI, Rules: []SARIFRule{}}},
+		Results: []SARIFResult{},
+	}
+	seen := map[string]bool{}
+	addRule := func(id, description string) {
+		if !seen[id] {
+			seen[id] = true
+			run.Tool.Driver.Rules = append(run.Tool.Driver.Rules, SARIFRule{ID: id, ShortDescription: SARIFMessage{Text: description}})
+		}
+	}
+
+	for _, result := range results {
+		if len(result.Diagnostics) == 0 {
+			if result.Status != StatusFailed {
+				continue
+			}
+			id := RuleID(result.Step, "failed")
+			addRule(id, "tofu "+result.Step+" failed")
+			message := strings.TrimSpace(result.Output)
+			if message == "" {
+				message = "tofu " + result.Step + " failed"
+			}
+			run.Results = append(run.Results, SARIFResult{
+				RuleID:    id,
+				Level:     "error",
+				Message:   SARIFMessage{Text: message},
+				Locations: []SARIFLocation{{PhysicalLocation: SARIFPhysicalLocation{ArtifactLocation: SARIFArtifactLocation{URI: filepath.ToSlash(result.RelPath)}}}},
+			})
+			continue
+		}
+		for _, diag := range result.Diagnostics {
+			id := RuleID(result.Step, diag.Summary)
+			addRule(id, diag.Summary)
+			run.Results = append(run.Results, SARIFResult{
+				RuleID:    id,
+				Level:     sarifLevel(diag.Severity),
+				Message:   SARIFMessage{Text: sarifMessage(diag)},
+				Locations: []SARIFLocation{sarifLocation(result.RelPath, diag.Range)},
+			})
+		}
+	}
+	return SARIFLog{Schema: sarifSchema, Version: sarifVersion, Runs: []SARIFRun{run}}
+}
+
+// WriteSARIF writes the log as indented JSON to path.
+func WriteSARIF(path string, log SARIFLog) error {
+	data, err := json.MarshalIndent(log, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0644)
+}
+
+// RuleID returns a stable rule identifier such as "validate/unsupported-argument"
+// built from the step and a slug of the summary.
+func RuleID(step, summary string) string {
+	var slug strings.Builder
+	dash := false
+	for _, r := range strings.ToLower(summary) {
+		if (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') {
+			if dash && slug.Len() > 0 {
+				slug.WriteByte('-')
+			}
+			slug.WriteRune(r)
+			dash = false
+		} else {
+			dash = true
+		}
+	}
+	return step + "/" + slug.String()
+}
+
+// sarifLevel maps a diagnostic severity to a SARIF result level
+func sarifLevel(severity string) string {
+	if severity == SeverityWarning {
+		return "warning"
+	}
+	return "error"
+}
+
+// sarifMessage joins a diagnostic's summary and detail
+func sarifMessage(diag Diagnostic) string {
+	if diag.Detail == "" {
+		return diag.Summary
+	}
+	return diag.Summary + ": " + diag.Detail
+}
+
+// sarifLocation locates a diagnostic at its range, falling back to path when
+// it has none
+func sarifLocation(path string, r *Range) SARIFLocation {
+	if r == nil || r.Filename == "" {
+		return SARIFLocation{PhysicalLocation: SARIFPhysicalLocation{ArtifactLocation: SARIFArtifactLocation{URI: filepath.ToSlash(path)}}}
+	}
+	return SARIFLocation{PhysicalLocation: SARIFPhysicalLocation{
+		ArtifactLocation: SARIFArtifactLocation{URI: filepath.ToSlash(r.Filename)},
+		Region: &SARIFRegion{
+			StartLine:   r.Start.Line,
+			StartColumn: r.Start.Column,
+			EndLine:     r.End.Line,
+			EndColumn:   r.End.Column,
+		},
+	}}
+}
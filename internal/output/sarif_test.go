@@ -0,0 +1,95 @@
+package output
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestRuleID(t *testing.T) {
+	cases := []struct{ step, summary, want string }{
+		{"validate", "Unsupported argument", "validate/unsupported-argument"},
+		{"validate", "Missing required argument: \"name\"", "validate/missing-required-argument-name"},
+		{"fmt", "File is not formatted", "fmt/file-is-not-formatted"},
+		{"init", "failed", "init/failed"},
+	}
+	for _, c := range cases {
+		if got := RuleID(c.step, c.summary); got != c.want {
+			t.Errorf("RuleID(%q, %q) = %q, want %q", c.step, c.summary, got, c.want)
+		}
+	}
+}
+
+func TestNewSARIFLog(t *testing.T) {
+	results := []Result{
+		{TofuMessage: TofuMessage{Step: "init", RelPath: "modules/a"}, Status: StatusPassed},
+		{TofuMessage: TofuMessage{Step: "init", RelPath: "modules/b", Output: "Error: registry unreachable\n"}, Status: StatusFailed},
+		{
+			TofuMessage: TofuMessage{
+				Step:    "validate",
+				RelPath: "modules/a",
+				Diagnostics: []Diagnostic{
+					{Severity: SeverityError, Summary: "Unsupported argument", Detail: "An argument named \"foo\" is not expected here.", Range: &Range{Filename: "modules/a/main.tf", Start: Pos{Line: 3, Column: 3}, End: Pos{Line: 3, Column: 6}}},
+					{Severity: SeverityWarning, Summary: "Unsupported argument"},
+				},
+			},
+			Status: StatusFailed,
+		},
+	}
+	log := NewSARIFLog("tofuvalidate", results)
+	if log.Version != "2.1.0" || len(log.Runs) != 1 {
+		t.Fatalf("log = %+v, want one 2.1.0 run", log)
+	}
+	run := log.Runs[0]
+	if run.Tool.Driver.Name != "tofuvalidate" {
+		t.Errorf("driver name = %q, want tofuvalidate", run.Tool.Driver.Name)
+	}
+	if len(run.Tool.Driver.Rules) != 2 || run.Tool.Driver.Rules[0].ID != "init/failed" || run.Tool.Driver.Rules[1].ID != "validate/unsupported-argument" {
+		t.Errorf("rules = %+v, want init/failed and validate/unsupported-argument once each", run.Tool.Driver.Rules)
+	}
+	if len(run.Results) != 3 {
+		t.Fatalf("got %d results, want 3", len(run.Results))
+	}
+
+	failed := run.Results[0]
+	if failed.Level != "error" || failed.Message.Text != "Error: registry unreachable" || failed.Locations[0].PhysicalLocation.ArtifactLocation.URI != "modules/b" {
+		t.Errorf("failed init result = %+v", failed)
+	}
+
+	located := run.Results[1]
+	region := located.Locations[0].PhysicalLocation.Region
+	if located.Locations[0].PhysicalLocation.ArtifactLocation.URI != "modules/a/main.tf" || region == nil || region.StartLine != 3 || region.EndColumn != 6 {
+		t.Errorf("located result = %+v", located)
+	}
+	if located.Message.Text != "Unsupported argument: An argument named \"foo\" is not expected here." {
+		t.Errorf("message = %q", located.Message.Text)
+	}
+
+	unlocated := run.Results[2]
+	if unlocated.Level != "warning" || unlocated.Locations[0].PhysicalLocation.ArtifactLocation.URI != "modules/a" || unlocated.Locations[0].PhysicalLocation.Region != nil {
+		t.Errorf("unlocated result = %+v", unlocated)
+	}
+}
+
+func TestWriteSARIF(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "results.sarif")
+	if err := WriteSARIF(path, NewSARIFLog("tofufmt", nil)); err != nil {
+		t.Fatalf("WriteSARIF() error: %v", err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decoded map[string]any
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("invalid JSON: %v", err)
+	}
+	if decoded["$schema"] == nil || decoded["version"] != "2.1.0" {
+		t.Errorf("decoded = %v, want $schema and version 2.1.0", decoded)
+	}
+	runs := decoded["runs"].([]any)
+	if results := runs[0].(map[string]any)["results"].([]any); len(results) != 0 {
+		t.Errorf("results = %v, want empty array", results)
+	}
+}
@@ -3,22 +3,37 @@ package output
 import (
 	"fmt"
 	"strings"
+
+	"pre-commit-hooks/internal/tofubin"
 )
 
 type TofuMessage struct {
-	Step    string
-	RelPath string
-	Output  string
+	Step    string `json:"step"`
+	RelPath string `json:"path"`
+	Output  string `json:"output,omitempty"`
+	// Diagnostics holds structured diagnostics when the step ran with -json.
+	// When set, summaries are built from them instead of scraping Output.
+	Diagnostics []Diagnostic `json:"diagnostics,omitempty"`
+	// Timeout is the limit the step exceeded, such as "5m0s", when it was
+	// stopped for running too long.
+	Timeout string `json:"timeout,omitempty"`
 }
 
 func PrintWarningSummary(warningMessages []TofuMessage) {
 	if len(warningMessages) == 0 {
 		return
 	}
-	fmt.Println(EmojiColorText("⚠️", "Warning Summary:", Yellow))
+	PrintStatus("⚠️", "Warning Summary:", Yellow)
 	fmt.Println()
+	name := tofubin.Current().DisplayName()
 	for _, msg := range warningMessages {
-		fmt.Printf(EmojiColorText(Warning, "OpenTofu %s warning in: %s\n", Yellow), msg.Step, msg.RelPath)
+		fmt.Printf(renderer.EmojiColorText(Warning, "%s %s warning in: %s\n", Yellow), name, msg.Step, msg.RelPath)
+		if warnings := FilterDiagnostics(msg.Diagnostics, SeverityWarning); len(warnings) > 0 {
+			for _, line := range strings.Split(strings.TrimRight(FormatDiagnostics(warnings), "\n"), "\n") {
+				fmt.Printf("    %s\n", line)
+			}
+			continue
+		}
 		lines := strings.Split(msg.Output, "\n")
 		inWarning := false
 		for _, line := range lines {
@@ -37,10 +52,33 @@ func PrintErrorSummary(errorMessages []TofuMessage, printIndentedOutput func(str
 	if len(errorMessages) == 0 {
 		return
 	}
-	fmt.Println(EmojiColorText("❗", "Error Summary:", Red))
+	PrintStatus("❗", "Error Summary:", Red)
 	fmt.Println()
+	name := tofubin.Current().DisplayName()
 	for _, msg := range errorMessages {
-		fmt.Printf(EmojiColorText(Error, "OpenTofu %s failed in: %s\n", Red), msg.Step, msg.RelPath)
+		if msg.Timeout != "" {
+			fmt.Printf(renderer.EmojiColorText(Error, "%s %s timed out after %s in: %s\n", Red), name, msg.Step, msg.Timeout, msg.RelPath)
+		} else {
+			fmt.Printf(renderer.EmojiColorText(Error, "%s %s failed in: %s\n", Red), name, msg.Step, msg.RelPath)
+		}
+		if errs := FilterDiagnostics(msg.Diagnostics, SeverityError); len(errs) > 0 {
+			printIndentedOutput(FormatDiagnostics(errs), false)
+			continue
+		}
 		printIndentedOutput(msg.Output, false)
 	}
 }
+
+// PrintSkippedSummary lists the paths left out of a run, with the reason for
+// each in Output, so that nothing is dropped silently.
+func PrintSkippedSummary(skipped []TofuMessage) {
+	if len(skipped) == 0 {
+		return
+	}
+	PrintStatus("⚠️", "Skipped Summary:", Yellow)
+	fmt.Println()
+	for _, msg := range skipped {
+		fmt.Printf("    %s (%s)\n", msg.RelPath, msg.Output)
+	}
+	fmt.Println()
+}
@@ -5,6 +5,8 @@ import (
 	"os"
 	"strings"
 	"testing"
+
+	"pre-commit-hooks/internal/tofubin"
 )
 
 func TestPrintWarningSummary(t *testing.T) {
@@ -29,9 +31,27 @@ func TestPrintWarningSummary(t *testing.T) {
 	}
 }
 
+func TestPrintSkippedSummary(t *testing.T) {
+	defer SetRenderer(SetRenderer(Renderer{ASCII: true}))
+	r, w, _ := os.Pipe()
+	oldStdout := os.Stdout
+	os.Stdout = w
+	defer func() { os.Stdout = oldStdout }()
+
+	PrintSkippedSummary(nil)
+	PrintSkippedSummary([]TofuMessage{{Step: "validate", RelPath: "repo/examples", Output: "ignored by .tofuignore"}})
+	w.Close()
+	outBytes, _ := io.ReadAll(r)
+	want := "[warn] Skipped Summary:\n\n    repo/examples (ignored by .tofuignore)\n\n"
+	if string(outBytes) != want {
+		t.Errorf("PrintSkippedSummary() wrote %q, want %q", outBytes, want)
+	}
+}
+
 func TestPrintErrorSummary(t *testing.T) {
 	msgs := []TofuMessage{
 		{Step: "validate", RelPath: "dir3", Output: "Error: failed validation\nDetails"},
+		{Step: "init", RelPath: "dir5", Output: "Initializing provider plugins...", Timeout: "5m0s"},
 	}
 	r, w, _ := os.Pipe()
 	oldStdout := os.Stdout
@@ -48,4 +68,59 @@ func TestPrintErrorSummary(t *testing.T) {
 	if !strings.Contains(output, "OpenTofu validate failed in: dir3") {
 		t.Errorf("Expected error details, got: %s", output)
 	}
+	if !strings.Contains(output, "OpenTofu init timed out after 5m0s in: dir5") || strings.Contains(output, "init failed") {
+		t.Errorf("Expected the timeout to be reported distinctly, got: %s", output)
+	}
+}
+
+func TestSummaries_NameTheBinary(t *testing.T) {
+	defer tofubin.Set(tofubin.Set(tofubin.Binary{Name: "terraform", Path: "terraform"}))
+	r, w, _ := os.Pipe()
+	oldStdout := os.Stdout
+	os.Stdout = w
+	defer func() { os.Stdout = oldStdout }()
+
+	PrintWarningSummary([]TofuMessage{{Step: "init", RelPath: "dir1", Output: "Warning: deprecated"}})
+	PrintErrorSummary([]TofuMessage{{Step: "validate", RelPath: "dir2", Output: "Error: bad"}}, func(string, bool) {})
+	w.Close()
+	outBytes, _ := io.ReadAll(r)
+	output := string(outBytes)
+	for _, want := range []string{"Terraform init warning in: dir1", "Terraform validate failed in: dir2"} {
+		if !strings.Contains(output, want) {
+			t.Errorf("Expected %q, got: %s", want, output)
+		}
+	}
+	if strings.Contains(output, "OpenTofu") {
+		t.Errorf("Expected no mention of OpenTofu under terraform, got: %s", output)
+	}
+}
+
+func TestSummaries_FromDiagnostics(t *testing.T) {
+	msg := TofuMessage{
+		Step:    "validate",
+		RelPath: "dir4",
+		Output:  "raw json that should not be scraped",
+		Diagnostics: []Diagnostic{
+			{Severity: SeverityWarning, Summary: "Deprecated attribute", Range: &Range{Filename: "main.tf", Start: Pos{Line: 7}}},
+			{Severity: SeverityError, Summary: "Missing required argument", Range: &Range{Filename: "vars.tf", Start: Pos{Line: 2}}},
+		},
+	}
+	r, w, _ := os.Pipe()
+	oldStdout := os.Stdout
+	os.Stdout = w
+	defer func() { os.Stdout = oldStdout }()
+
+	PrintWarningSummary([]TofuMessage{msg})
+	PrintErrorSummary([]TofuMessage{msg}, func(out string, _ bool) { os.Stdout.Write([]byte(out)) })
+	w.Close()
+	outBytes, _ := io.ReadAll(r)
+	output := string(outBytes)
+	for _, want := range []string{"    Warning: Deprecated attribute", "on main.tf line 7", "Error: Missing required argument", "on vars.tf line 2"} {
+		if !strings.Contains(output, want) {
+			t.Errorf("Expected %q in summaries, got: %s", want, output)
+		}
+	}
+	if strings.Contains(output, "raw json") {
+		t.Errorf("Expected raw output to be ignored when diagnostics are present, got: %s", output)
+	}
 }
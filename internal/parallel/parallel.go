@@ -0,0 +1,43 @@
+package parallel
+
+import "sync"
+
+// Ordered runs work(i) for every i in [0, n) using at most jobs concurrent
+// goroutines, and calls done(i) in index order: done(i) runs as soon as item i
+// and every item before it have finished. done is always called from the
+// calling goroutine, so it may print or append to shared state without locking.
+// A jobs value below one is treated as one.
+func Ordered(n, jobs int, work func(i int), done func(i int)) {
+	if jobs < 1 {
+		jobs = 1
+	}
+	finished := make([]chan struct{}, n)
+	for i := range finished {
+		finished[i] = make(chan struct{})
+	}
+
+	indexes := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < min(jobs, n); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				work(i)
+				close(finished[i])
+			}
+		}()
+	}
+	go func() {
+		for i := 0; i < n; i++ {
+			indexes <- i
+		}
+		close(indexes)
+	}()
+
+	for i := 0; i < n; i++ {
+		<-finished[i]
+		done(i)
+	}
+	wg.Wait()
+}
@@ -0,0 +1,59 @@
+package parallel
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestOrdered_DoneInIndexOrder(t *testing.T) {
+	n := 20
+	var order []int
+	Ordered(n, 4, func(i int) {
+		// Later items finish first to exercise reordering
+		time.Sleep(time.Duration(n-i) * time.Millisecond)
+	}, func(i int) {
+		order = append(order, i)
+	})
+	if len(order) != n {
+		t.Fatalf("done called %d times, want %d", len(order), n)
+	}
+	for i, got := range order {
+		if got != i {
+			t.Fatalf("done order = %v, want ascending", order)
+		}
+	}
+}
+
+func TestOrdered_RespectsJobLimit(t *testing.T) {
+	var running, peak int32
+	var mu sync.Mutex
+	Ordered(12, 3, func(i int) {
+		now := atomic.AddInt32(&running, 1)
+		mu.Lock()
+		if now > peak {
+			peak = now
+		}
+		mu.Unlock()
+		time.Sleep(5 * time.Millisecond)
+		atomic.AddInt32(&running, -1)
+	}, func(int) {})
+	if peak > 3 {
+		t.Errorf("peak concurrency = %d, want at most 3", peak)
+	}
+}
+
+func TestOrdered_EdgeCases(t *testing.T) {
+	called := false
+	Ordered(0, 4, func(int) { called = true }, func(int) { called = true })
+	if called {
+		t.Error("Expected no calls for zero items")
+	}
+
+	count := 0
+	Ordered(3, 0, func(int) {}, func(int) { count++ })
+	if count != 3 {
+		t.Errorf("done called %d times with jobs=0, want 3", count)
+	}
+}
@@ -0,0 +1,21 @@
+//go:build !windows
+
+package runner
+
+import (
+	"os"
+	"os/exec"
+	"syscall"
+)
+
+// detach starts cmd in its own process group, so that a Ctrl-C in the
+// terminal reaches the hook only, which then interrupts cmd once
+func detach(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+}
+
+// kill kills the process group led by process, so that no child of the
+// command outlives the hook
+func kill(process *os.Process) error {
+	return syscall.Kill(-process.Pid, syscall.SIGKILL)
+}
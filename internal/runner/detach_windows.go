@@ -0,0 +1,16 @@
+//go:build windows
+
+package runner
+
+import (
+	"os"
+	"os/exec"
+)
+
+// detach is a no-op on Windows, where commands are killed when stopped
+func detach(cmd *exec.Cmd) {}
+
+// kill kills process
+func kill(process *os.Process) error {
+	return process.Kill()
+}
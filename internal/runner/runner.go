@@ -0,0 +1,215 @@
+// Package runner runs the tofu commands of the hooks with a per-step timeout,
+// and stops them when the hook is interrupted.
+package runner
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"slices"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"pre-commit-hooks/internal/output"
+	"pre-commit-hooks/internal/tofubin"
+)
+
+// Flag is the hook flag that sets timeouts: -timeout=DURATION for every
+// step, or -timeout=STEP=DURATION for one, repeated or comma-separated.
+const Flag = "timeout"
+
+// WaitDelay is how long a stopped command may take to exit after being
+// interrupted, for example to release a state lock, before it is killed.
+var WaitDelay = 10 * time.Second
+
+// ErrInterrupted is returned for commands stopped because the hook received
+// an interrupt or termination signal.
+var ErrInterrupted = errors.New("interrupted")
+
+// TimeoutError is returned for commands stopped because their step ran
+// longer than its timeout.
+type TimeoutError struct {
+	Step    string
+	Timeout time.Duration
+}
+
+func (e *TimeoutError) Error() string {
+	return fmt.Sprintf("%s timed out after %s", e.Step, e.Timeout)
+}
+
+// TimedOut returns the timeout exceeded when err is, or wraps, a
+// TimeoutError.
+func TimedOut(err error) (time.Duration, bool) {
+	var timeout *TimeoutError
+	if errors.As(err, &timeout) {
+		return timeout.Timeout, true
+	}
+	return 0, false
+}
+
+// Timeouts maps step names, such as "init" or "validate", to their timeout.
+// The "" entry applies to steps without one; zero means no timeout.
+type Timeouts map[string]time.Duration
+
+// ParseTimeouts parses -timeout values: a duration for every step, or
+// step=duration for one of steps.
+func ParseTimeouts(values []string, steps ...string) (Timeouts, error) {
+	timeouts := Timeouts{}
+	for _, value := range values {
+		step, duration, ok := strings.Cut(value, "=")
+		if !ok {
+			step, duration = "", value
+		} else if !slices.Contains(steps, step) {
+			return nil, fmt.Errorf("invalid -%s value %q: unknown step %q, want one of %s", Flag, value, step, strings.Join(steps, ", "))
+		}
+		d, err := time.ParseDuration(duration)
+		if err != nil || d < 0 {
+			return nil, fmt.Errorf("invalid -%s value %q: want a duration such as 5m, optionally prefixed with a step such as init=5m", Flag, value)
+		}
+		timeouts[step] = d
+	}
+	return timeouts, nil
+}
+
+// For returns the timeout of step, or zero when it has none.
+func (t Timeouts) For(step string) time.Duration {
+	if d, ok := t[step]; ok {
+		return d
+	}
+	return t[""]
+}
+
+var (
+	timeouts Timeouts
+	base     = context.Background()
+	// exit ends the hook on a second signal
+	exit = os.Exit
+
+	// running holds the processes of the commands started and not yet
+	// waited for, each the leader of its own process group
+	mu      sync.Mutex
+	running = map[*os.Process]bool{}
+)
+
+// SetTimeouts replaces the timeouts applied by Step and returns the previous
+// ones, so callers and tests can restore them.
+func SetTimeouts(t Timeouts) Timeouts {
+	previous := timeouts
+	timeouts = t
+	return previous
+}
+
+// HandleInterrupts makes the first interrupt or termination signal stop the
+// running commands instead of the hook: each is sent an interrupt, so that it
+// can clean up, and later steps fail with ErrInterrupted. A second signal
+// kills the running commands with their process groups and ends the hook.
+func HandleInterrupts() {
+	signals := make(chan os.Signal, 2)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	ctx, cancel := context.WithCancel(context.Background())
+	base = ctx
+	go handleSignals(signals, cancel)
+}
+
+// handleSignals cancels the running commands on the first signal, and kills
+// them and exits on the second
+func handleSignals(signals <-chan os.Signal, cancel context.CancelFunc) {
+	<-signals
+	cancel()
+	<-signals
+	mu.Lock()
+	for process := range running {
+		kill(process)
+	}
+	mu.Unlock()
+	exit(130)
+}
+
+// track adds or removes a started command's process from running
+func track(process *os.Process, started bool) {
+	mu.Lock()
+	defer mu.Unlock()
+	if started {
+		running[process] = true
+	} else {
+		delete(running, process)
+	}
+}
+
+// Step is one command run by a hook.
+type Step struct {
+	// Name names the step in errors, such as "init", and selects its timeout.
+	Name string
+	Dir  string
+	// Env entries (KEY=value) are added to the inherited environment.
+	Env  []string
+	Args []string
+}
+
+// Run runs the current binary with the step's args, writing to stdout and
+// stderr. -no-color is added after the subcommand when the output renderer
+// has color disabled, so tofu's output is as plain as the hook's. A step that
+// times out returns a TimeoutError and one stopped by an interrupt returns
+// ErrInterrupted.
+func (s Step) Run(stdout, stderr io.Writer) error {
+	ctx, cancel := base, context.CancelFunc(func() {})
+	limit := timeouts.For(s.Name)
+	if limit > 0 {
+		ctx, cancel = context.WithTimeout(ctx, limit)
+	}
+	defer cancel()
+
+	cmd := tofubin.Current().CommandContext(ctx, s.args()...)
+	cmd.Dir = s.Dir
+	if len(s.Env) > 0 {
+		cmd.Env = append(os.Environ(), s.Env...)
+	}
+	cmd.Stdout, cmd.Stderr = stdout, stderr
+	detach(cmd)
+	cmd.Cancel = func() error {
+		// Let tofu stop gracefully; interrupts are not supported on Windows
+		if err := cmd.Process.Signal(os.Interrupt); err != nil {
+			return cmd.Process.Kill()
+		}
+		return nil
+	}
+	cmd.WaitDelay = WaitDelay
+
+	err := cmd.Start()
+	if err == nil {
+		track(cmd.Process, true)
+		err = cmd.Wait()
+		track(cmd.Process, false)
+	}
+	switch {
+	case err == nil:
+		return nil
+	case base.Err() != nil:
+		return ErrInterrupted
+	case limit > 0 && errors.Is(ctx.Err(), context.DeadlineExceeded):
+		return &TimeoutError{Step: s.Name, Timeout: limit}
+	}
+	return err
+}
+
+// args returns the step's args, with -no-color inserted after the subcommand
+// when color is disabled and the args do not already include it
+func (s Step) args() []string {
+	if output.CurrentRenderer().Color || len(s.Args) == 0 || slices.Contains(s.Args, "-no-color") {
+		return s.Args
+	}
+	return slices.Insert(slices.Clone(s.Args), 1, "-no-color")
+}
+
+// CombinedOutput runs the step and returns its stdout and stderr together.
+func (s Step) CombinedOutput() (string, error) {
+	var out bytes.Buffer
+	err := s.Run(&out, &out)
+	return out.String(), err
+}
@@ -0,0 +1,179 @@
+package runner
+
+import (
+	"context"
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"pre-commit-hooks/internal/output"
+	"pre-commit-hooks/internal/tofubin"
+)
+
+// fakeTofu installs a tofu stand-in that sleeps, hangs ignoring interrupts
+// in a child, fails or echoes its args
+func fakeTofu(t *testing.T) {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("uses a shell script as the fake binary")
+	}
+	path := filepath.Join(t.TempDir(), "tofu")
+	script := `#!/bin/sh
+case "$1" in
+sleep) exec sleep "$2" ;;
+hang) trap '' INT; sleep "$2" & wait ;;
+fail) echo "boom" >&2; exit 3 ;;
+*) echo "args: $* env: $HOOK_TEST" ;;
+esac
+`
+	if err := os.WriteFile(path, []byte(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+	previous := tofubin.Set(tofubin.Binary{Name: "tofu", Path: path})
+	t.Cleanup(func() { tofubin.Set(previous) })
+	// With color on, the args are passed as given
+	renderer := output.SetRenderer(output.Renderer{Color: true})
+	t.Cleanup(func() { output.SetRenderer(renderer) })
+}
+
+func TestStep_CombinedOutput(t *testing.T) {
+	fakeTofu(t)
+	dir := t.TempDir()
+	out, err := Step{Name: "init", Dir: dir, Env: []string{"HOOK_TEST=1"}, Args: []string{"init", "-input=false"}}.CombinedOutput()
+	if err != nil || out != "args: init -input=false env: 1\n" {
+		t.Errorf("CombinedOutput() = %q, %v", out, err)
+	}
+
+	out, err = Step{Name: "validate", Args: []string{"fail"}}.CombinedOutput()
+	var exitErr *exec.ExitError
+	if !errors.As(err, &exitErr) || exitErr.ExitCode() != 3 || out != "boom\n" {
+		t.Errorf("CombinedOutput() = %q, %v; want the exit error and stderr", out, err)
+	}
+	if _, ok := TimedOut(err); ok {
+		t.Error("TimedOut() = true for a failed command")
+	}
+}
+
+func TestStep_NoColor(t *testing.T) {
+	fakeTofu(t)
+	output.SetRenderer(output.Renderer{})
+	out, err := Step{Name: "fmt", Args: []string{"fmt", "-check", "main.tf"}}.CombinedOutput()
+	if err != nil || out != "args: fmt -no-color -check main.tf env: \n" {
+		t.Errorf("CombinedOutput() = %q, %v; want -no-color after the subcommand", out, err)
+	}
+	out, err = Step{Name: "test", Args: []string{"test", "-no-color"}}.CombinedOutput()
+	if err != nil || out != "args: test -no-color env: \n" {
+		t.Errorf("CombinedOutput() = %q, %v; want -no-color once", out, err)
+	}
+}
+
+func TestStep_Timeout(t *testing.T) {
+	fakeTofu(t)
+	defer SetTimeouts(SetTimeouts(Timeouts{"init": 100 * time.Millisecond}))
+
+	started := time.Now()
+	_, err := Step{Name: "init", Args: []string{"sleep", "5"}}.CombinedOutput()
+	if limit, ok := TimedOut(err); !ok || limit != 100*time.Millisecond {
+		t.Fatalf("CombinedOutput() error = %v, want a timeout after 100ms", err)
+	}
+	if err.Error() != "init timed out after 100ms" {
+		t.Errorf("Error() = %q", err.Error())
+	}
+	if elapsed := time.Since(started); elapsed > 3*time.Second {
+		t.Errorf("command ran for %s, want it stopped at the timeout", elapsed)
+	}
+
+	// Other steps have no timeout
+	if _, err := (Step{Name: "validate", Args: []string{"sleep", "0.2"}}).CombinedOutput(); err != nil {
+		t.Errorf("CombinedOutput() error = %v, want no timeout for validate", err)
+	}
+}
+
+func TestStep_Interrupted(t *testing.T) {
+	fakeTofu(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	previous := base
+	base = ctx
+	defer func() { base = previous }()
+
+	time.AfterFunc(100*time.Millisecond, cancel)
+	if _, err := (Step{Name: "test", Args: []string{"sleep", "5"}}).CombinedOutput(); !errors.Is(err, ErrInterrupted) {
+		t.Errorf("CombinedOutput() error = %v, want ErrInterrupted", err)
+	}
+	if _, err := (Step{Name: "test", Args: []string{"version"}}).CombinedOutput(); !errors.Is(err, ErrInterrupted) {
+		t.Errorf("CombinedOutput() error = %v, want later steps to fail as interrupted", err)
+	}
+}
+
+func TestHandleSignals_SecondSignalKills(t *testing.T) {
+	fakeTofu(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	previous := base
+	base = ctx
+	defer func() { base = previous }()
+	exited := make(chan int, 1)
+	defer func(previous func(int)) { exit = previous }(exit)
+	exit = func(code int) { exited <- code }
+
+	signals := make(chan os.Signal, 2)
+	go handleSignals(signals, cancel)
+	done := make(chan error, 1)
+	go func() {
+		_, err := Step{Name: "test", Args: []string{"hang", "30"}}.CombinedOutput()
+		done <- err
+	}()
+
+	// The first signal is ignored by the command and its child
+	time.Sleep(200 * time.Millisecond)
+	signals <- os.Interrupt
+	time.Sleep(200 * time.Millisecond)
+	select {
+	case err := <-done:
+		t.Fatalf("command stopped after the first signal: %v", err)
+	default:
+	}
+
+	signals <- os.Interrupt
+	select {
+	case code := <-exited:
+		if code != 130 {
+			t.Errorf("exit code = %d, want 130", code)
+		}
+	case <-time.After(3 * time.Second):
+		t.Fatal("hook did not exit after the second signal")
+	}
+	// The child holding the output open is killed with the group
+	select {
+	case err := <-done:
+		if !errors.Is(err, ErrInterrupted) {
+			t.Errorf("CombinedOutput() error = %v, want ErrInterrupted", err)
+		}
+	case <-time.After(3 * time.Second):
+		t.Fatal("command still running after the second signal")
+	}
+}
+
+func TestParseTimeouts(t *testing.T) {
+	timeouts, err := ParseTimeouts([]string{"10m", "init=2m", "test=0"}, "init", "validate", "test")
+	if err != nil {
+		t.Fatalf("ParseTimeouts() error: %v", err)
+	}
+	for step, want := range map[string]time.Duration{"init": 2 * time.Minute, "validate": 10 * time.Minute, "test": 0} {
+		if got := timeouts.For(step); got != want {
+			t.Errorf("For(%q) = %s, want %s", step, got, want)
+		}
+	}
+	if got := Timeouts(nil).For("init"); got != 0 {
+		t.Errorf("nil Timeouts For(init) = %s, want 0", got)
+	}
+	for _, bad := range []string{"soon", "init=", "init=-1s", "plan=1m"} {
+		if _, err := ParseTimeouts([]string{bad}, "init"); err == nil || !strings.Contains(err.Error(), "invalid -timeout value") {
+			t.Errorf("ParseTimeouts(%q) error = %v, want invalid value", bad, err)
+		}
+	}
+}
@@ -0,0 +1,244 @@
+// Package semver parses versions and the version constraints used by
+// required_version, such as ">= 1.6.0, < 2.0.0" or "~> 1.8".
+package semver
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Version is a semantic version. Build metadata is dropped when parsing.
+type Version struct {
+	Major, Minor, Patch int
+	// Prerelease is the part after "-", such as "rc1"; empty for releases.
+	Prerelease string
+}
+
+// Parse parses a version such as "1.8.2", "v1.9.0-rc1" or "1.6". Missing
+// minor and patch numbers are zero.
+func Parse(s string) (Version, error) {
+	v, _, err := parse(s)
+	return v, err
+}
+
+// parse parses a version and also returns how many numeric segments were given
+func parse(s string) (Version, int, error) {
+	raw := strings.TrimPrefix(strings.TrimSpace(s), "v")
+	raw, _, _ = strings.Cut(raw, "+")
+	raw, pre, _ := strings.Cut(raw, "-")
+	parts := strings.Split(raw, ".")
+	if raw == "" || len(parts) > 3 {
+		return Version{}, 0, fmt.Errorf("invalid version %q", s)
+	}
+	nums := make([]int, 3)
+	for i, part := range parts {
+		n, err := strconv.Atoi(part)
+		if err != nil || n < 0 {
+			return Version{}, 0, fmt.Errorf("invalid version %q", s)
+		}
+		nums[i] = n
+	}
+	return Version{Major: nums[0], Minor: nums[1], Patch: nums[2], Prerelease: pre}, len(parts), nil
+}
+
+// String formats the version as "1.2.3" or "1.2.3-rc1".
+func (v Version) String() string {
+	s := fmt.Sprintf("%d.%d.%d", v.Major, v.Minor, v.Patch)
+	if v.Prerelease != "" {
+		s += "-" + v.Prerelease
+	}
+	return s
+}
+
+// Compare returns -1, 0 or 1 as v is older than, equal to or newer than o.
+// A prerelease is older than the release with the same numbers; prereleases
+// are ordered by comparePrerelease.
+func (v Version) Compare(o Version) int {
+	for _, d := range []int{v.Major - o.Major, v.Minor - o.Minor, v.Patch - o.Patch} {
+		if d != 0 {
+			return sign(d)
+		}
+	}
+	switch {
+	case v.Prerelease == o.Prerelease:
+		return 0
+	case v.Prerelease == "":
+		return 1
+	case o.Prerelease == "":
+		return -1
+	}
+	return comparePrerelease(v.Prerelease, o.Prerelease)
+}
+
+// comparePrerelease orders prereleases as SemVer 2.0 does: identifier by
+// identifier between the dots, numeric identifiers numerically and below
+// alphanumeric ones, and a prerelease that runs out of identifiers first is
+// older. Alphanumeric identifiers compare their runs of digits as numbers, so
+// that OpenTofu's undotted "rc2" is older than "rc10".
+func comparePrerelease(a, b string) int {
+	as, bs := strings.Split(a, "."), strings.Split(b, ".")
+	for i := 0; i < len(as) && i < len(bs); i++ {
+		an, aNum := numeric(as[i])
+		bn, bNum := numeric(bs[i])
+		var c int
+		switch {
+		case aNum && bNum:
+			c = compareInts(an, bn)
+		case aNum:
+			c = -1
+		case bNum:
+			c = 1
+		default:
+			c = compareIdentifiers(as[i], bs[i])
+		}
+		if c != 0 {
+			return c
+		}
+	}
+	return compareInts(len(as), len(bs))
+}
+
+// compareIdentifiers compares alphanumeric identifiers, runs of digits as
+// numbers and everything else in ASCII order
+func compareIdentifiers(a, b string) int {
+	for a != "" && b != "" {
+		ar, br := leadingRun(a), leadingRun(b)
+		an, aNum := numeric(ar)
+		bn, bNum := numeric(br)
+		c := strings.Compare(ar, br)
+		if aNum && bNum {
+			c = compareInts(an, bn)
+		}
+		if c != 0 {
+			return c
+		}
+		a, b = a[len(ar):], b[len(br):]
+	}
+	return strings.Compare(a, b)
+}
+
+// leadingRun returns the leading run of digits or of non-digits of s
+func leadingRun(s string) string {
+	digit := isDigit(s[0])
+	i := 1
+	for i < len(s) && isDigit(s[i]) == digit {
+		i++
+	}
+	return s[:i]
+}
+
+// numeric returns the value of an identifier made only of digits
+func numeric(s string) (int, bool) {
+	for i := 0; i < len(s); i++ {
+		if !isDigit(s[i]) {
+			return 0, false
+		}
+	}
+	n, err := strconv.Atoi(s)
+	return n, err == nil
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+func compareInts(a, b int) int {
+	if a == b {
+		return 0
+	}
+	return sign(a - b)
+}
+
+func sign(d int) int {
+	if d < 0 {
+		return -1
+	}
+	return 1
+}
+
+// Constraint is a comma-separated list of conditions that must all hold.
+type Constraint struct {
+	raw        string
+	conditions []condition
+}
+
+type condition struct {
+	op      string
+	version Version
+	// upper is the exclusive upper bound of a "~>" condition
+	upper Version
+}
+
+// ParseConstraint parses a constraint such as ">= 1.6, < 2.0", "1.8.0" or
+// "~> 1.8". The operators are =, !=, >, >=, <, <= and ~> (pessimistic: "~> 1.8"
+// allows 1.8 and later 1.x, "~> 1.8.2" allows later 1.8.x).
+func ParseConstraint(s string) (Constraint, error) {
+	c := Constraint{raw: strings.TrimSpace(s)}
+	for _, part := range strings.Split(s, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			return Constraint{}, fmt.Errorf("invalid version constraint %q", s)
+		}
+		op := "="
+		for _, candidate := range []string{"~>", ">=", "<=", "!=", ">", "<", "="} {
+			if rest, ok := strings.CutPrefix(part, candidate); ok {
+				op, part = candidate, strings.TrimSpace(rest)
+				break
+			}
+		}
+		version, segments, err := parse(part)
+		if err != nil {
+			return Constraint{}, fmt.Errorf("invalid version constraint %q: %w", s, err)
+		}
+		cond := condition{op: op, version: version}
+		if op == "~>" {
+			cond.upper = pessimisticUpper(version, segments)
+		}
+		c.conditions = append(c.conditions, cond)
+	}
+	return c, nil
+}
+
+// pessimisticUpper returns the exclusive upper bound of "~> v": the
+// second-to-last given segment is incremented, so ~> 1.8 allows < 2.0.0 and
+// ~> 1.8.2 allows < 1.9.0. A single segment, ~> 1, allows < 2.0.0.
+func pessimisticUpper(v Version, segments int) Version {
+	if segments == 3 {
+		return Version{Major: v.Major, Minor: v.Minor + 1}
+	}
+	return Version{Major: v.Major + 1}
+}
+
+// Check reports whether v satisfies every condition of the constraint.
+func (c Constraint) Check(v Version) bool {
+	for _, cond := range c.conditions {
+		cmp := v.Compare(cond.version)
+		ok := true
+		switch cond.op {
+		case "=":
+			ok = cmp == 0
+		case "!=":
+			ok = cmp != 0
+		case ">":
+			ok = cmp > 0
+		case ">=":
+			ok = cmp >= 0
+		case "<":
+			ok = cmp < 0
+		case "<=":
+			ok = cmp <= 0
+		case "~>":
+			ok = cmp >= 0 && v.Compare(cond.upper) < 0
+		}
+		if !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// String returns the constraint as written.
+func (c Constraint) String() string {
+	return c.raw
+}
@@ -0,0 +1,105 @@
+package semver
+
+import (
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	cases := []struct {
+		in   string
+		want Version
+	}{
+		{"1.8.2", Version{Major: 1, Minor: 8, Patch: 2}},
+		{"v1.9.0-rc1", Version{Major: 1, Minor: 9, Prerelease: "rc1"}},
+		{"1.6", Version{Major: 1, Minor: 6}},
+		{"1.10.0+build.5", Version{Major: 1, Minor: 10}},
+	}
+	for _, c := range cases {
+		got, err := Parse(c.in)
+		if err != nil || got != c.want {
+			t.Errorf("Parse(%q) = %+v, %v; want %+v", c.in, got, err, c.want)
+		}
+	}
+	for _, bad := range []string{"", "one.two", "1.2.3.4", "1.-2"} {
+		if _, err := Parse(bad); err == nil {
+			t.Errorf("Parse(%q) expected error", bad)
+		}
+	}
+	if got := (Version{Major: 1, Minor: 9, Prerelease: "rc1"}).String(); got != "1.9.0-rc1" {
+		t.Errorf("String() = %q", got)
+	}
+}
+
+func TestCompare(t *testing.T) {
+	cases := []struct {
+		a, b string
+		want int
+	}{
+		{"1.8.0", "1.8.0", 0},
+		{"1.8.0", "1.10.0", -1},
+		{"2.0.0", "1.99.99", 1},
+		{"1.9.0-rc1", "1.9.0", -1},
+		{"1.9.0-beta1", "1.9.0-rc1", -1},
+		{"1.9.0-rc2", "1.9.0-rc10", -1},
+		{"1.9.0-rc.10", "1.9.0-rc.2", 1},
+		{"1.9.0-alpha.1", "1.9.0-alpha.beta", -1},
+		{"1.9.0-alpha", "1.9.0-alpha.1", -1},
+		{"1.9.0-1", "1.9.0-alpha", -1},
+		{"1.9.0-rc1", "1.9.0-rc1", 0},
+	}
+	for _, c := range cases {
+		a, _ := Parse(c.a)
+		b, _ := Parse(c.b)
+		if got := a.Compare(b); got != c.want {
+			t.Errorf("Compare(%s, %s) = %d, want %d", c.a, c.b, got, c.want)
+		}
+	}
+}
+
+func TestConstraint_Check(t *testing.T) {
+	cases := []struct {
+		constraint string
+		version    string
+		want       bool
+	}{
+		{"1.8.0", "1.8.0", true},
+		{"= 1.8.0", "1.8.1", false},
+		{"!= 1.8.0", "1.8.1", true},
+		{">= 1.6.0", "1.6.0", true},
+		{">= 1.6.0", "1.5.7", false},
+		{"> 1.6", "1.6.1", true},
+		{"< 2.0.0", "2.0.0", false},
+		{"<= 1.9", "1.9.0", true},
+		{">= 1.6, < 1.9", "1.8.4", true},
+		{">= 1.6, < 1.9", "1.9.0", false},
+		{"~> 1.8", "1.11.0", true},
+		{"~> 1.8", "2.0.0", false},
+		{"~> 1.8", "1.7.9", false},
+		{"~> 1.8.2", "1.8.9", true},
+		{"~> 1.8.2", "1.9.0", false},
+		{"~> 1", "1.99.0", true},
+		{">=1.6.0", "1.7.0", true},
+		{">= 1.9.0-rc2", "1.9.0-rc10", true},
+	}
+	for _, c := range cases {
+		constraint, err := ParseConstraint(c.constraint)
+		if err != nil {
+			t.Fatalf("ParseConstraint(%q) error: %v", c.constraint, err)
+		}
+		v, _ := Parse(c.version)
+		if got := constraint.Check(v); got != c.want {
+			t.Errorf("%q.Check(%s) = %v, want %v", c.constraint, c.version, got, c.want)
+		}
+	}
+}
+
+func TestParseConstraint_Invalid(t *testing.T) {
+	for _, bad := range []string{"", ">= ", ">= 1.6,", "=> 1.6", "~> x"} {
+		if _, err := ParseConstraint(bad); err == nil {
+			t.Errorf("ParseConstraint(%q) expected error", bad)
+		}
+	}
+	if c, _ := ParseConstraint(" >= 1.6 "); c.String() != ">= 1.6" {
+		t.Errorf("String() = %q", c.String())
+	}
+}
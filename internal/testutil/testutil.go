@@ -1,20 +1,25 @@
 package testutil
 
 import (
+	"io"
 	"os"
 	"os/exec"
+	"path/filepath"
+	"runtime"
 	"testing"
+
+	"pre-commit-hooks/internal/tofubin"
 )
 
-// CheckOpenTofuInstalled returns true if the 'tofu' binary is found in PATH.
+// CheckOpenTofuInstalled returns true if the binary the hooks run (tofu unless
+// another was selected, see tofubin.Resolve) is found in PATH.
 func CheckOpenTofuInstalled() bool {
-	_, err := exec.LookPath("tofu")
-	return err == nil
+	return tofubin.Installed()
 }
 
-// SkipIfTofuNotInstalled skips the test if tofu is not installed
+// SkipIfTofuNotInstalled skips the test if the 'tofu' binary is not in PATH
 func SkipIfTofuNotInstalled(t *testing.T) {
-	if !CheckOpenTofuInstalled() {
+	if _, err := exec.LookPath("tofu"); err != nil {
 		t.Skip("Skipping test as tofu is not installed")
 	}
 }
@@ -45,6 +50,37 @@ func RestoreWorkingDir(t *testing.T, newDir string) func() {
 	}
 }
 
+// CaptureStdout returns everything fn writes to os.Stdout
+func CaptureStdout(t *testing.T, fn func()) string {
+	t.Helper()
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatalf("Failed to create pipe: %v", err)
+	}
+	oldStdout := os.Stdout
+	os.Stdout = w
+	defer func() { os.Stdout = oldStdout }()
+	fn()
+	w.Close()
+	out, _ := io.ReadAll(r)
+	return string(out)
+}
+
+// FakeTofu writes a tofu stand-in that reports version like tofu version
+// -json does and returns its path. The test is skipped on Windows.
+func FakeTofu(t *testing.T, version string) string {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("uses a shell script as the fake binary")
+	}
+	path := filepath.Join(t.TempDir(), "tofu")
+	script := "#!/bin/sh\necho '{\"terraform_version\":\"" + version + "\"}'\n"
+	if err := os.WriteFile(path, []byte(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
 // RunCmd runs a command and returns its combined output and error
 func RunCmd(name string, args ...string) (string, error) {
         // no-dd-sa:go-security/command-injection - https://github.com/osinfra-io/pt-techne-pre-commit-hooks/issues/8
@@ -0,0 +1,126 @@
+// Package tofubin resolves which binary the hooks run: OpenTofu's tofu or
+// HashiCorp's terraform.
+package tofubin
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// Env names the environment variable that selects the binary, either by
+// name ("terraform") or by path.
+const Env = "TOFU_BINARY"
+
+// Candidates lists the binaries tried by auto-detection, in preference order.
+var Candidates = []string{"tofu", "terraform"}
+
+// Binary is a resolved tofu or terraform executable.
+type Binary struct {
+	// Name is "tofu" or "terraform" and selects flag and file differences.
+	Name string
+	// Path is the executable to run: a name looked up in PATH or a path.
+	Path string
+}
+
+// IsTerraform reports whether the binary is HashiCorp Terraform.
+func (b Binary) IsTerraform() bool {
+	return b.Name == "terraform"
+}
+
+// DisplayName returns the product name for messages: OpenTofu or Terraform.
+func (b Binary) DisplayName() string {
+	if b.IsTerraform() {
+		return "Terraform"
+	}
+	return "OpenTofu"
+}
+
+// IsConfigFile reports whether the binary loads the named file as
+// configuration. Terraform ignores OpenTofu's .tofu files.
+func (b Binary) IsConfigFile(name string) bool {
+	if strings.HasSuffix(name, ".tofu") {
+		return !b.IsTerraform()
+	}
+	return strings.HasSuffix(name, ".tf")
+}
+
+// Supports reports whether the binary processes the named file at all, for
+// commands such as fmt that take file arguments.
+func (b Binary) Supports(name string) bool {
+	return !(b.IsTerraform() && (strings.HasSuffix(name, ".tofu") || strings.HasSuffix(name, ".tofutest.hcl")))
+}
+
+// Command returns an exec.Cmd running the binary with args.
+func (b Binary) Command(args ...string) *exec.Cmd {
+	// no-dd-sa:go-security/command-injection - the binary is chosen by the repository owner
+	return exec.Command(b.Path, args...)
+}
+
+// CommandContext returns an exec.Cmd running the binary with args, which is
+// stopped when ctx is done.
+func (b Binary) CommandContext(ctx context.Context, args ...string) *exec.Cmd {
+	// no-dd-sa:go-security/command-injection - the binary is chosen by the repository owner
+	return exec.CommandContext(ctx, b.Path, args...)
+}
+
+// Installed reports whether the binary can be found.
+func (b Binary) Installed() bool {
+	_, err := exec.LookPath(b.Path)
+	return err == nil
+}
+
+// Resolve chooses the binary: the explicit value (from a hook flag) if set,
+// then the TOFU_BINARY environment variable, then the first of Candidates
+// found in PATH. When none is found it returns tofu, so that the installed
+// check reports the problem.
+func Resolve(explicit string) Binary {
+	if explicit == "" {
+		explicit = os.Getenv(Env)
+	}
+	if explicit != "" {
+		return Binary{Name: nameOf(explicit), Path: explicit}
+	}
+	for _, name := range Candidates {
+		if _, err := exec.LookPath(name); err == nil {
+			return Binary{Name: name, Path: name}
+		}
+	}
+	return Binary{Name: "tofu", Path: "tofu"}
+}
+
+// nameOf infers whether a binary name or path is terraform or tofu
+func nameOf(path string) string {
+	if strings.Contains(strings.ToLower(filepath.Base(path)), "terraform") {
+		return "terraform"
+	}
+	return "tofu"
+}
+
+// current is the binary run by the hooks' packages.
+var current = Binary{Name: "tofu", Path: "tofu"}
+
+// Set replaces the binary run by Command and returns the previous one, so
+// callers and tests can restore it.
+func Set(b Binary) Binary {
+	previous := current
+	current = b
+	return previous
+}
+
+// Current returns the binary run by Command.
+func Current() Binary {
+	return current
+}
+
+// Command returns an exec.Cmd running the current binary with args.
+func Command(args ...string) *exec.Cmd {
+	return current.Command(args...)
+}
+
+// Installed reports whether the current binary can be found.
+func Installed() bool {
+	return current.Installed()
+}
@@ -0,0 +1,98 @@
+package tofubin
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+// fakePath returns a PATH holding empty executables with the given names
+func fakePath(t *testing.T, names ...string) string {
+	t.Helper()
+	dir := t.TempDir()
+	for _, name := range names {
+		if runtime.GOOS == "windows" {
+			name += ".exe"
+		}
+		if err := os.WriteFile(filepath.Join(dir, name), []byte("#!/bin/sh\n"), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func TestResolve(t *testing.T) {
+	cases := []struct {
+		name     string
+		path     []string
+		env      string
+		explicit string
+		want     Binary
+	}{
+		{"prefers tofu", []string{"tofu", "terraform"}, "", "", Binary{Name: "tofu", Path: "tofu"}},
+		{"falls back to terraform", []string{"terraform"}, "", "", Binary{Name: "terraform", Path: "terraform"}},
+		{"defaults to tofu when none found", nil, "", "", Binary{Name: "tofu", Path: "tofu"}},
+		{"environment variable", []string{"tofu", "terraform"}, "terraform", "", Binary{Name: "terraform", Path: "terraform"}},
+		{"flag beats environment", []string{"tofu", "terraform"}, "terraform", "tofu", Binary{Name: "tofu", Path: "tofu"}},
+		{"path to terraform", nil, "/opt/terraform-1.9/bin/terraform", "", Binary{Name: "terraform", Path: "/opt/terraform-1.9/bin/terraform"}},
+		{"path to tofu", nil, "", "/usr/local/bin/tofu", Binary{Name: "tofu", Path: "/usr/local/bin/tofu"}},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			t.Setenv("PATH", fakePath(t, c.path...))
+			t.Setenv(Env, c.env)
+			if got := Resolve(c.explicit); got != c.want {
+				t.Errorf("Resolve(%q) = %+v, want %+v", c.explicit, got, c.want)
+			}
+		})
+	}
+}
+
+func TestBinary_Files(t *testing.T) {
+	tofu := Binary{Name: "tofu", Path: "tofu"}
+	terraform := Binary{Name: "terraform", Path: "terraform"}
+	cases := []struct {
+		file                        string
+		tofuConfig, terraformConfig bool
+		terraformSupports           bool
+	}{
+		{"main.tf", true, true, true},
+		{"main.tofu", true, false, false},
+		{"vars.tfvars", false, false, true},
+		{"main.tftest.hcl", false, false, true},
+		{"main.tofutest.hcl", false, false, false},
+	}
+	for _, c := range cases {
+		if got := tofu.IsConfigFile(c.file); got != c.tofuConfig {
+			t.Errorf("tofu.IsConfigFile(%q) = %v, want %v", c.file, got, c.tofuConfig)
+		}
+		if got := terraform.IsConfigFile(c.file); got != c.terraformConfig {
+			t.Errorf("terraform.IsConfigFile(%q) = %v, want %v", c.file, got, c.terraformConfig)
+		}
+		if !tofu.Supports(c.file) {
+			t.Errorf("tofu.Supports(%q) = false, want true", c.file)
+		}
+		if got := terraform.Supports(c.file); got != c.terraformSupports {
+			t.Errorf("terraform.Supports(%q) = %v, want %v", c.file, got, c.terraformSupports)
+		}
+	}
+	if tofu.DisplayName() != "OpenTofu" || terraform.DisplayName() != "Terraform" {
+		t.Errorf("DisplayName() = %q, %q", tofu.DisplayName(), terraform.DisplayName())
+	}
+}
+
+func TestSetAndInstalled(t *testing.T) {
+	t.Setenv("PATH", fakePath(t, "terraform"))
+	defer Set(Set(Binary{Name: "terraform", Path: "terraform"}))
+	if Current().Name != "terraform" || !Installed() {
+		t.Errorf("Current() = %+v, Installed() = %v; want installed terraform", Current(), Installed())
+	}
+	if cmd := Command("version"); cmd.Args[0] != "terraform" || cmd.Args[1] != "version" {
+		t.Errorf("Command() = %v", cmd.Args)
+	}
+	Set(Binary{Name: "tofu", Path: "tofu"})
+	if Installed() {
+		t.Error("Installed() = true, want false when tofu is not in PATH")
+	}
+}
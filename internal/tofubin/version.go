@@ -0,0 +1,22 @@
+package tofubin
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// Version runs `version -json` and returns the reported version. OpenTofu
+// keeps Terraform's terraform_version key.
+func (b Binary) Version() (string, error) {
+	out, err := b.Command("version", "-json").Output()
+	if err != nil {
+		return "", fmt.Errorf("%s version -json failed: %w", b.Name, err)
+	}
+	var info struct {
+		Version string `json:"terraform_version"`
+	}
+	if err := json.Unmarshal(out, &info); err != nil || info.Version == "" {
+		return "", fmt.Errorf("%s version -json returned no version", b.Name)
+	}
+	return info.Version, nil
+}
@@ -0,0 +1,31 @@
+package tofubin
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+func TestBinary_Version(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses a shell script as the fake binary")
+	}
+	dir := t.TempDir()
+	script := filepath.Join(dir, "tofu")
+	body := "#!/bin/sh\necho '{\"terraform_version\":\"1.8.2\",\"platform\":\"linux_amd64\"}'\n"
+	if err := os.WriteFile(script, []byte(body), 0755); err != nil {
+		t.Fatal(err)
+	}
+	got, err := Binary{Name: "tofu", Path: script}.Version()
+	if err != nil || got != "1.8.2" {
+		t.Errorf("Version() = %q, %v; want 1.8.2", got, err)
+	}
+
+	if err := os.WriteFile(script, []byte("#!/bin/sh\necho 'Terraform v0.12.31'\n"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := (Binary{Name: "tofu", Path: script}).Version(); err == nil {
+		t.Error("Version() expected error for non-JSON output")
+	}
+}
@@ -0,0 +1,54 @@
+package tofuenv
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+
+	"pre-commit-hooks/internal/modules"
+)
+
+// DataDirEnv is the environment variable that moves tofu's working data
+// directory away from .terraform.
+const DataDirEnv = "TF_DATA_DIR"
+
+// defaultDataDir is the data directory tofu init creates inside the module.
+const defaultDataDir = ".terraform"
+
+// InitReason returns why dir must be initialized with tofu init before tofu
+// can test or validate it, or "" when it looks initialized: the data
+// directory exists, holds the module manifest when the configuration calls
+// modules, and holds providers when the lock file pins any. Installed but
+// outdated modules or providers are not detected.
+func InitReason(dir string) string {
+	dataDir := os.Getenv(DataDirEnv)
+	if dataDir == "" {
+		dataDir = defaultDataDir
+	}
+	if !filepath.IsAbs(dataDir) {
+		dataDir = filepath.Join(dir, dataDir)
+	}
+	if _, err := os.Stat(dataDir); err != nil {
+		return "no " + filepath.Base(dataDir) + " directory"
+	}
+
+	sources, err := modules.Sources(dir)
+	if err != nil {
+		return "could not read configuration: " + err.Error()
+	}
+	if len(sources) > 0 && !exists(filepath.Join(dataDir, "modules", "modules.json")) {
+		return "modules not installed"
+	}
+
+	lock, err := os.ReadFile(filepath.Join(dir, LockFile))
+	if err == nil && strings.Contains(string(lock), `provider "`) && !exists(filepath.Join(dataDir, "providers")) {
+		return "providers not installed"
+	}
+	return ""
+}
+
+// exists reports whether path can be stat'ed
+func exists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
@@ -0,0 +1,54 @@
+package tofuenv
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"pre-commit-hooks/internal/testutil"
+)
+
+func TestInitReason(t *testing.T) {
+	dir, cleanup := testutil.CreateTempDir(t, "tofuenv_init_reason")
+	defer cleanup()
+	t.Setenv(DataDirEnv, "")
+	write := func(rel, content string) {
+		t.Helper()
+		path := filepath.Join(dir, rel)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("Failed to create directory for %s: %v", rel, err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write %s: %v", rel, err)
+		}
+	}
+
+	write("main.tf", "module \"net\" {\n  source = \"./modules/net\"\n}\n")
+	write(LockFile, "provider \"registry.opentofu.org/hashicorp/null\" {\n  version = \"3.2.2\"\n}\n")
+	if got := InitReason(dir); got != "no .terraform directory" {
+		t.Errorf("InitReason() on a fresh clone = %q", got)
+	}
+
+	if err := os.Mkdir(filepath.Join(dir, ".terraform"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if got := InitReason(dir); got != "modules not installed" {
+		t.Errorf("InitReason() without modules.json = %q", got)
+	}
+	write(".terraform/modules/modules.json", "{}")
+	if got := InitReason(dir); got != "providers not installed" {
+		t.Errorf("InitReason() without providers = %q", got)
+	}
+	if err := os.Mkdir(filepath.Join(dir, ".terraform", "providers"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if got := InitReason(dir); got != "" {
+		t.Errorf("InitReason() of an initialized module = %q, want \"\"", got)
+	}
+
+	// A relative TF_DATA_DIR is resolved against the module
+	t.Setenv(DataDirEnv, "data")
+	if got := InitReason(dir); got != "no data directory" {
+		t.Errorf("InitReason() with TF_DATA_DIR=data = %q", got)
+	}
+}
@@ -0,0 +1,98 @@
+package tofuenv
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+)
+
+// LockFile is the dependency lock file tofu init creates or updates.
+const LockFile = ".terraform.lock.hcl"
+
+// Isolation gives a module directory its own temporary TF_DATA_DIR, so tofu
+// init does not leave a .terraform directory in the working tree, and keeps a
+// snapshot of the dependency lock file so it can be put back afterwards.
+type Isolation struct {
+	// DataDir is the temporary directory used as TF_DATA_DIR.
+	DataDir string
+
+	// lock is nil when the lock file is snapshotted apart, see IsolateData
+	lock *LockSnapshot
+}
+
+// Isolate creates a temporary data directory for dir and snapshots its lock file.
+func Isolate(dir string) (*Isolation, error) {
+	lock, err := SnapshotLock(dir)
+	if err != nil {
+		return nil, err
+	}
+	iso, err := IsolateData()
+	if err != nil {
+		return nil, err
+	}
+	iso.lock = lock
+	return iso, nil
+}
+
+// IsolateData creates a temporary data directory without snapshotting a
+// lock file, for tofu runs that share a module whose lock file was
+// snapshotted once with SnapshotLock, such as its concurrent test suites.
+func IsolateData() (*Isolation, error) {
+	dataDir, err := os.MkdirTemp("", "tofu-data-")
+	if err != nil {
+		return nil, err
+	}
+	return &Isolation{DataDir: dataDir}, nil
+}
+
+// Env returns the environment entries that point tofu at the isolated data dir.
+func (i *Isolation) Env() []string {
+	return []string{DataDirEnv + "=" + i.DataDir}
+}
+
+// Restore puts the lock file back the way it was before Isolate, if tofu
+// changed, created or removed it, and deletes the temporary data directory.
+func (i *Isolation) Restore() error {
+	var errs []error
+	if i.lock != nil {
+		errs = append(errs, i.lock.Restore())
+	}
+	errs = append(errs, os.RemoveAll(i.DataDir))
+	return errors.Join(errs...)
+}
+
+// LockSnapshot is a module's dependency lock file, or its absence, as it was
+// before tofu init could change it.
+type LockSnapshot struct {
+	path    string
+	data    []byte
+	existed bool
+}
+
+// SnapshotLock snapshots the lock file of dir.
+func SnapshotLock(dir string) (*LockSnapshot, error) {
+	snapshot := &LockSnapshot{path: filepath.Join(dir, LockFile)}
+	data, err := os.ReadFile(snapshot.path)
+	switch {
+	case err == nil:
+		snapshot.data, snapshot.existed = data, true
+	case !errors.Is(err, os.ErrNotExist):
+		return nil, err
+	}
+	return snapshot, nil
+}
+
+// Restore puts the lock file back the way it was snapshotted, if tofu
+// changed, created or removed it.
+func (s *LockSnapshot) Restore() error {
+	current, err := os.ReadFile(s.path)
+	exists := err == nil
+	switch {
+	case s.existed && (!exists || !bytes.Equal(current, s.data)):
+		return os.WriteFile(s.path, s.data, 0644)
+	case !s.existed && exists:
+		return os.Remove(s.path)
+	}
+	return nil
+}
@@ -0,0 +1,133 @@
+package tofuenv
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"pre-commit-hooks/internal/testutil"
+)
+
+func TestIsolate_RestoresChangedLockFile(t *testing.T) {
+	dir, cleanup := testutil.CreateTempDir(t, "tofuenv_isolate_changed")
+	defer cleanup()
+	lockPath := filepath.Join(dir, LockFile)
+	original := []byte("# original lock\n")
+	if err := os.WriteFile(lockPath, original, 0644); err != nil {
+		t.Fatalf("Failed to write lock file: %v", err)
+	}
+
+	iso, err := Isolate(dir)
+	if err != nil {
+		t.Fatalf("Isolate() error: %v", err)
+	}
+	if info, err := os.Stat(iso.DataDir); err != nil || !info.IsDir() {
+		t.Fatalf("Expected data dir %s to exist: %v", iso.DataDir, err)
+	}
+	if env := iso.Env(); len(env) != 1 || env[0] != "TF_DATA_DIR="+iso.DataDir {
+		t.Errorf("Env() = %v, want [TF_DATA_DIR=%s]", env, iso.DataDir)
+	}
+
+	// Simulate tofu init updating the lock file
+	if err := os.WriteFile(lockPath, []byte("# upgraded lock\n"), 0644); err != nil {
+		t.Fatalf("Failed to modify lock file: %v", err)
+	}
+	if err := iso.Restore(); err != nil {
+		t.Fatalf("Restore() error: %v", err)
+	}
+	got, err := os.ReadFile(lockPath)
+	if err != nil || string(got) != string(original) {
+		t.Errorf("lock file = %q, %v; want %q", got, err, original)
+	}
+	if _, err := os.Stat(iso.DataDir); !os.IsNotExist(err) {
+		t.Errorf("Expected data dir to be removed, stat error: %v", err)
+	}
+}
+
+func TestIsolate_RemovesCreatedLockFile(t *testing.T) {
+	dir, cleanup := testutil.CreateTempDir(t, "tofuenv_isolate_created")
+	defer cleanup()
+	lockPath := filepath.Join(dir, LockFile)
+
+	iso, err := Isolate(dir)
+	if err != nil {
+		t.Fatalf("Isolate() error: %v", err)
+	}
+	if err := os.WriteFile(lockPath, []byte("# new lock\n"), 0644); err != nil {
+		t.Fatalf("Failed to create lock file: %v", err)
+	}
+	if err := iso.Restore(); err != nil {
+		t.Fatalf("Restore() error: %v", err)
+	}
+	if _, err := os.Stat(lockPath); !os.IsNotExist(err) {
+		t.Errorf("Expected created lock file to be removed, stat error: %v", err)
+	}
+}
+
+func TestIsolate_UnchangedLockFileUntouched(t *testing.T) {
+	dir, cleanup := testutil.CreateTempDir(t, "tofuenv_isolate_unchanged")
+	defer cleanup()
+	lockPath := filepath.Join(dir, LockFile)
+	if err := os.WriteFile(lockPath, []byte("# lock\n"), 0600); err != nil {
+		t.Fatalf("Failed to write lock file: %v", err)
+	}
+
+	iso, err := Isolate(dir)
+	if err != nil {
+		t.Fatalf("Isolate() error: %v", err)
+	}
+	if err := iso.Restore(); err != nil {
+		t.Fatalf("Restore() error: %v", err)
+	}
+	info, err := os.Stat(lockPath)
+	if err != nil {
+		t.Fatalf("Expected lock file to still exist: %v", err)
+	}
+	if info.Mode().Perm() != 0600 {
+		t.Errorf("lock file mode = %v, want 0600 (file should not be rewritten)", info.Mode().Perm())
+	}
+}
+
+func TestIsolateData_SharedLockSnapshot(t *testing.T) {
+	dir, cleanup := testutil.CreateTempDir(t, "tofuenv_isolate_shared")
+	defer cleanup()
+	lockPath := filepath.Join(dir, LockFile)
+
+	lock, err := SnapshotLock(dir)
+	if err != nil {
+		t.Fatalf("SnapshotLock() error: %v", err)
+	}
+	first, err := IsolateData()
+	if err != nil {
+		t.Fatalf("IsolateData() error: %v", err)
+	}
+	second, err := IsolateData()
+	if err != nil {
+		t.Fatalf("IsolateData() error: %v", err)
+	}
+	if first.DataDir == second.DataDir {
+		t.Fatalf("IsolateData() returned the same data dir twice: %s", first.DataDir)
+	}
+
+	// An init creates the lock file, which only the module's snapshot removes
+	if err := os.WriteFile(lockPath, []byte("# new lock\n"), 0644); err != nil {
+		t.Fatalf("Failed to create lock file: %v", err)
+	}
+	for _, iso := range []*Isolation{first, second} {
+		if err := iso.Restore(); err != nil {
+			t.Fatalf("Restore() error: %v", err)
+		}
+		if _, err := os.Stat(iso.DataDir); !os.IsNotExist(err) {
+			t.Errorf("Expected data dir to be removed, stat error: %v", err)
+		}
+	}
+	if _, err := os.Stat(lockPath); err != nil {
+		t.Fatalf("Expected the data directories to leave the lock file alone: %v", err)
+	}
+	if err := lock.Restore(); err != nil {
+		t.Fatalf("LockSnapshot.Restore() error: %v", err)
+	}
+	if _, err := os.Stat(lockPath); !os.IsNotExist(err) {
+		t.Errorf("Expected created lock file to be removed, stat error: %v", err)
+	}
+}
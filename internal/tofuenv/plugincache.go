@@ -0,0 +1,139 @@
+package tofuenv
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"strings"
+	"sync"
+)
+
+// PluginCacheEnv is the environment variable tofu reads the shared provider
+// plugin cache directory from.
+const PluginCacheEnv = "TF_PLUGIN_CACHE_DIR"
+
+// lockedProvider matches a provider block of a dependency lock file and
+// captures its address and version.
+var lockedProvider = regexp.MustCompile(`(?m)^provider\s+"([^"]+)"\s*\{[^}]*?\bversion\s*=\s*"([^"]+)"`)
+
+// PluginCache is a provider plugin cache shared by every tofu init the hooks
+// run. It is safe for concurrent use, but the cache directory is not: neither
+// tofu nor terraform supports concurrent writers, so each init using the
+// cache must hold Acquire.
+type PluginCache struct {
+	// Dir is the absolute path of the cache directory.
+	Dir string
+
+	writing sync.RWMutex
+	mu      sync.Mutex
+	hits    int
+	misses  int
+}
+
+// DefaultPluginCacheDir returns the cache location used when neither a flag
+// nor TF_PLUGIN_CACHE_DIR selects one, under the user's cache directory.
+func DefaultPluginCacheDir() (string, error) {
+	base, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(base, "pre-commit-hooks", "plugin-cache"), nil
+}
+
+// NewPluginCache sets up the plugin cache directory, creating it if missing.
+// dir takes precedence; when empty, an existing TF_PLUGIN_CACHE_DIR is
+// respected, falling back to DefaultPluginCacheDir.
+func NewPluginCache(dir string) (*PluginCache, error) {
+	if dir == "" {
+		dir = os.Getenv(PluginCacheEnv)
+	}
+	if dir == "" {
+		var err error
+		if dir, err = DefaultPluginCacheDir(); err != nil {
+			return nil, err
+		}
+	}
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	return &PluginCache{Dir: dir}, nil
+}
+
+// Env returns the environment entries that point tofu at the cache.
+func (c *PluginCache) Env() []string {
+	return []string{PluginCacheEnv + "=" + c.Dir}
+}
+
+// Acquire waits until the init of dir may use the cache and returns the
+// function that releases it. When the cache already holds every provider the
+// lock file of dir pins, init only reads from it and runs alongside other
+// such inits. Otherwise init may download into the cache, so it waits until
+// no other init uses the cache; this includes every directory without a lock
+// file, as its providers cannot be known before init.
+func (c *PluginCache) Acquire(dir string) (release func()) {
+	c.writing.RLock()
+	if c.Holds(dir) {
+		return c.writing.RUnlock
+	}
+	c.writing.RUnlock()
+	c.writing.Lock()
+	return c.writing.Unlock
+}
+
+// Holds reports whether the cache holds every provider pinned by the lock
+// file of dir for this platform; false when there is no lock file or it pins
+// no provider.
+func (c *PluginCache) Holds(dir string) bool {
+	lock, err := os.ReadFile(filepath.Join(dir, LockFile))
+	if err != nil {
+		return false
+	}
+	providers := lockedProvider.FindAllStringSubmatch(string(lock), -1)
+	if len(providers) == 0 {
+		return false
+	}
+	platform := runtime.GOOS + "_" + runtime.GOARCH
+	for _, provider := range providers {
+		// The cache is laid out as HOSTNAME/NAMESPACE/TYPE/VERSION/OS_ARCH
+		if !exists(filepath.Join(c.Dir, filepath.FromSlash(provider[1]), provider[2], platform)) {
+			return false
+		}
+	}
+	return true
+}
+
+// Record counts the cache hits and misses reported in tofu init output.
+func (c *PluginCache) Record(initOutput string) {
+	hits, misses := CountCacheUsage(initOutput)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.hits += hits
+	c.misses += misses
+}
+
+// Stats returns the cache hits and misses recorded so far.
+func (c *PluginCache) Stats() (hits, misses int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.hits, c.misses
+}
+
+// CountCacheUsage counts providers tofu init linked from the shared cache
+// (hits) and providers it had to download (misses).
+func CountCacheUsage(initOutput string) (hits, misses int) {
+	for _, line := range strings.Split(initOutput, "\n") {
+		line = strings.TrimSpace(line)
+		switch {
+		case strings.HasPrefix(line, "- Using ") && strings.HasSuffix(line, "from the shared cache directory"):
+			hits++
+		case strings.HasPrefix(line, "- Installing "):
+			misses++
+		}
+	}
+	return hits, misses
+}
@@ -0,0 +1,170 @@
+package tofuenv
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"pre-commit-hooks/internal/testutil"
+)
+
+const sampleInitOutput = `Initializing the backend...
+
+Initializing provider plugins...
+- Finding hashicorp/null versions matching "~> 3.0"...
+- Finding latest version of hashicorp/local...
+- Using hashicorp/null v3.2.2 from the shared cache directory
+- Installing hashicorp/local v2.5.1...
+- Installed hashicorp/local v2.5.1 (signed, key ID 0C0AF313E5FD9F80)
+- Using previously-installed hashicorp/random v3.6.0
+
+OpenTofu has been successfully initialized!
+`
+
+func TestCountCacheUsage(t *testing.T) {
+	hits, misses := CountCacheUsage(sampleInitOutput)
+	if hits != 1 || misses != 1 {
+		t.Errorf("CountCacheUsage() = %d hits, %d misses; want 1, 1", hits, misses)
+	}
+	if hits, misses := CountCacheUsage(""); hits != 0 || misses != 0 {
+		t.Errorf("CountCacheUsage(\"\") = %d, %d; want 0, 0", hits, misses)
+	}
+}
+
+func TestNewPluginCache(t *testing.T) {
+	root, cleanup := testutil.CreateTempDir(t, "tofuenv_plugin_cache")
+	defer cleanup()
+
+	explicit := filepath.Join(root, "explicit", "cache")
+	t.Setenv(PluginCacheEnv, filepath.Join(root, "from-env"))
+	cache, err := NewPluginCache(explicit)
+	if err != nil {
+		t.Fatalf("NewPluginCache() error: %v", err)
+	}
+	if cache.Dir != explicit {
+		t.Errorf("Dir = %q, want explicit %q", cache.Dir, explicit)
+	}
+	if info, err := os.Stat(explicit); err != nil || !info.IsDir() {
+		t.Errorf("Expected cache dir to be created: %v", err)
+	}
+	if env := cache.Env(); len(env) != 1 || env[0] != PluginCacheEnv+"="+explicit {
+		t.Errorf("Env() = %v", env)
+	}
+
+	cache, err = NewPluginCache("")
+	if err != nil {
+		t.Fatalf("NewPluginCache(\"\") error: %v", err)
+	}
+	if cache.Dir != filepath.Join(root, "from-env") {
+		t.Errorf("Dir = %q, want value of %s", cache.Dir, PluginCacheEnv)
+	}
+
+	t.Setenv(PluginCacheEnv, "")
+	t.Setenv("XDG_CACHE_HOME", filepath.Join(root, "xdg"))
+	t.Setenv("HOME", root)
+	cache, err = NewPluginCache("")
+	if err != nil {
+		t.Fatalf("NewPluginCache(\"\") error: %v", err)
+	}
+	if !strings.HasPrefix(cache.Dir, root) || !strings.HasSuffix(cache.Dir, filepath.Join("pre-commit-hooks", "plugin-cache")) {
+		t.Errorf("Dir = %q, want default under the user cache dir", cache.Dir)
+	}
+}
+
+func TestPluginCache_RecordConcurrent(t *testing.T) {
+	cache := &PluginCache{Dir: "/cache"}
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			cache.Record(sampleInitOutput)
+		}()
+	}
+	wg.Wait()
+	if hits, misses := cache.Stats(); hits != 10 || misses != 10 {
+		t.Errorf("Stats() = %d, %d; want 10, 10", hits, misses)
+	}
+}
+
+// writeLockFile writes a lock file pinning null 3.2.2 in dir
+func writeLockFile(t *testing.T, dir string) {
+	t.Helper()
+	lock := "provider \"registry.opentofu.org/hashicorp/null\" {\n  version     = \"3.2.2\"\n  constraints = \"~> 3.0\"\n  hashes = [\n    \"h1:abc=\",\n  ]\n}\n"
+	if err := os.WriteFile(filepath.Join(dir, LockFile), []byte(lock), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestPluginCache_Holds(t *testing.T) {
+	root := t.TempDir()
+	cache := &PluginCache{Dir: filepath.Join(root, "cache")}
+	module := filepath.Join(root, "module")
+	if err := os.Mkdir(module, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if cache.Holds(module) {
+		t.Error("Holds() without a lock file = true, want false")
+	}
+	writeLockFile(t, module)
+	if cache.Holds(module) {
+		t.Error("Holds() with an empty cache = true, want false")
+	}
+	platform := filepath.Join(cache.Dir, "registry.opentofu.org", "hashicorp", "null", "3.2.2", runtime.GOOS+"_"+runtime.GOARCH)
+	if err := os.MkdirAll(platform, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if !cache.Holds(module) {
+		t.Error("Holds() with the provider cached = false, want true")
+	}
+}
+
+func TestPluginCache_Acquire(t *testing.T) {
+	root := t.TempDir()
+	cache := &PluginCache{Dir: filepath.Join(root, "cache")}
+	cached, uncached := filepath.Join(root, "cached"), filepath.Join(root, "uncached")
+	for _, dir := range []string{cached, uncached} {
+		if err := os.Mkdir(dir, 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	writeLockFile(t, cached)
+	if err := os.MkdirAll(filepath.Join(cache.Dir, "registry.opentofu.org", "hashicorp", "null", "3.2.2", runtime.GOOS+"_"+runtime.GOARCH), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	// Inits that only read from the cache share it
+	release := cache.Acquire(cached)
+	acquired := make(chan struct{})
+	go func() {
+		cache.Acquire(cached)()
+		close(acquired)
+	}()
+	select {
+	case <-acquired:
+	case <-time.After(time.Second):
+		t.Fatal("Acquire() of a cached directory waited for another reader")
+	}
+
+	// An init that may write waits until no other init uses the cache
+	written := make(chan struct{})
+	go func() {
+		cache.Acquire(uncached)()
+		close(written)
+	}()
+	select {
+	case <-written:
+		t.Fatal("Acquire() of an uncached directory did not wait for the reader")
+	case <-time.After(50 * time.Millisecond):
+	}
+	release()
+	select {
+	case <-written:
+	case <-time.After(time.Second):
+		t.Fatal("Acquire() of an uncached directory did not get the cache after the reader released it")
+	}
+}
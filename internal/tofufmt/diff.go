@@ -0,0 +1,192 @@
+package tofufmt
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"pre-commit-hooks/internal/output"
+)
+
+// LineKind identifies the role of a line within a diff hunk.
+type LineKind int
+
+const (
+	// Context is an unchanged line shown for context
+	Context LineKind = iota
+	// Added is a line present only in the formatted file
+	Added
+	// Removed is a line present only in the original file
+	Removed
+)
+
+// DiffLine is a single line of a unified diff hunk, without its prefix.
+type DiffLine struct {
+	Kind LineKind
+	Text string
+}
+
+// Hunk is a unified diff hunk. Line ranges are 1-based as in the
+// "@@ -OldStart,OldLines +NewStart,NewLines @@" header.
+type Hunk struct {
+	OldStart int
+	OldLines int
+	NewStart int
+	NewLines int
+	Lines    []DiffLine
+}
+
+// FileDiff holds the hunks tofu fmt reported for a single file. Files listed
+// by tofu without a diff (for example with -diff=false) have no hunks.
+type FileDiff struct {
+	Path  string
+	Hunks []Hunk
+}
+
+// Added returns the number of added lines across all hunks.
+func (f FileDiff) Added() int {
+	return f.count(Added)
+}
+
+// Removed returns the number of removed lines across all hunks.
+func (f FileDiff) Removed() int {
+	return f.count(Removed)
+}
+
+// Diagnostics returns one diagnostic per hunk with the given severity, located
+// at the hunk's lines in the original file. A file listed without a diff gets
+// a single diagnostic pointing at its first line.
+func (f FileDiff) Diagnostics(severity string) []output.Diagnostic {
+	if len(f.Hunks) == 0 {
+		return []output.Diagnostic{{
+			Severity: severity,
+			Summary:  "File is not formatted",
+			Detail:   "Run tofu fmt to rewrite this file in canonical format.",
+			Range:    &output.Range{Filename: f.Path, Start: output.Pos{Line: 1}, End: output.Pos{Line: 1}},
+		}}
+	}
+	diags := make([]output.Diagnostic, 0, len(f.Hunks))
+	for _, hunk := range f.Hunks {
+		start := max(hunk.OldStart, 1)
+		end := max(start+hunk.OldLines-1, start)
+		diags = append(diags, output.Diagnostic{
+			Severity: severity,
+			Summary:  "File is not formatted",
+			Detail:   fmt.Sprintf("tofu fmt would change lines %d-%d.", start, end),
+			Range:    &output.Range{Filename: f.Path, Start: output.Pos{Line: start}, End: output.Pos{Line: end}},
+		})
+	}
+	return diags
+}
+
+func (f FileDiff) count(kind LineKind) int {
+	total := 0
+	for _, hunk := range f.Hunks {
+		for _, line := range hunk.Lines {
+			if line.Kind == kind {
+				total++
+			}
+		}
+	}
+	return total
+}
+
+// ParseDiff parses the output of tofu fmt -check -diff into per-file diffs,
+// in the order files first appear. Both the bare file names tofu lists and the
+// "--- old/<path>" / "+++ new/<path>" headers are recognised; unrecognised
+// lines outside a hunk are ignored.
+func ParseDiff(output string) []FileDiff {
+	var files []FileDiff
+	index := map[string]int{}
+	current := -1
+	oldLeft, newLeft := 0, 0
+
+	fileFor := func(path string) int {
+		if i, ok := index[path]; ok {
+			return i
+		}
+		index[path] = len(files)
+		files = append(files, FileDiff{Path: path})
+		return index[path]
+	}
+
+	for _, line := range strings.Split(output, "\n") {
+		line = strings.TrimSuffix(line, "\r")
+		inHunk := current >= 0 && (oldLeft > 0 || newLeft > 0)
+		switch {
+		case strings.HasPrefix(line, "\\"):
+			// "\ No newline at end of file" annotates the previous line
+		case inHunk:
+			kind, text := Context, line
+			if line != "" {
+				text = line[1:]
+				switch line[0] {
+				case '+':
+					kind = Added
+				case '-':
+					kind = Removed
+				}
+			}
+			if kind != Added {
+				oldLeft--
+			}
+			if kind != Removed {
+				newLeft--
+			}
+			hunks := files[current].Hunks
+			hunks[len(hunks)-1].Lines = append(hunks[len(hunks)-1].Lines, DiffLine{Kind: kind, Text: text})
+		case strings.HasPrefix(line, "--- "):
+			current = fileFor(diffPath(line[4:], "old/"))
+		case strings.HasPrefix(line, "+++ "):
+			current = fileFor(diffPath(line[4:], "new/"))
+		case strings.HasPrefix(line, "@@ ") && current >= 0:
+			hunk, ok := parseHunkHeader(line)
+			if !ok {
+				continue
+			}
+			files[current].Hunks = append(files[current].Hunks, hunk)
+			oldLeft, newLeft = hunk.OldLines, hunk.NewLines
+		case IsFormattable(line):
+			current = fileFor(line)
+		}
+	}
+	return files
+}
+
+// diffPath strips the label prefix and any trailing timestamp from a diff
+// header path.
+func diffPath(header, prefix string) string {
+	if tab := strings.IndexByte(header, '\t'); tab >= 0 {
+		header = header[:tab]
+	}
+	return strings.TrimPrefix(header, prefix)
+}
+
+// parseHunkHeader parses "@@ -l[,s] +l[,s] @@". An omitted size means one line.
+func parseHunkHeader(line string) (Hunk, bool) {
+	fields := strings.Fields(line)
+	if len(fields) < 4 || fields[3] != "@@" || !strings.HasPrefix(fields[1], "-") || !strings.HasPrefix(fields[2], "+") {
+		return Hunk{}, false
+	}
+	oldStart, oldLines, ok1 := parseRange(fields[1][1:])
+	newStart, newLines, ok2 := parseRange(fields[2][1:])
+	if !ok1 || !ok2 {
+		return Hunk{}, false
+	}
+	return Hunk{OldStart: oldStart, OldLines: oldLines, NewStart: newStart, NewLines: newLines}, true
+}
+
+func parseRange(r string) (int, int, bool) {
+	startStr, sizeStr, hasSize := strings.Cut(r, ",")
+	start, err := strconv.Atoi(startStr)
+	if err != nil {
+		return 0, 0, false
+	}
+	size := 1
+	if hasSize {
+		if size, err = strconv.Atoi(sizeStr); err != nil {
+			return 0, 0, false
+		}
+	}
+	return start, size, true
+}
@@ -0,0 +1,121 @@
+package tofufmt
+
+import (
+	"reflect"
+	"testing"
+
+	"pre-commit-hooks/internal/output"
+)
+
+const sampleDiff = `main.tf
+--- old/main.tf
++++ new/main.tf
+@@ -1,4 +1,4 @@
+-variable   "foo"   {}
++variable "foo" {}
+ 
+ resource "null_resource" "a" {
+-  count=1
++  count = 1
+@@ -10 +10 @@
+-output "x" {value=1}
++output "x" { value = 1 }
+\ No newline at end of file
+modules/net/vars.tfvars
+--- old/modules/net/vars.tfvars
++++ new/modules/net/vars.tfvars
+@@ -1,2 +1,2 @@
+-name="a"
++name = "a"
+ region = "b"
+`
+
+func TestParseDiff(t *testing.T) {
+	got := ParseDiff(sampleDiff)
+	want := []FileDiff{
+		{
+			Path: "main.tf",
+			Hunks: []Hunk{
+				{OldStart: 1, OldLines: 4, NewStart: 1, NewLines: 4, Lines: []DiffLine{
+					{Removed, `variable   "foo"   {}`},
+					{Added, `variable "foo" {}`},
+					{Context, ""},
+					{Context, `resource "null_resource" "a" {`},
+					{Removed, "  count=1"},
+					{Added, "  count = 1"},
+				}},
+				{OldStart: 10, OldLines: 1, NewStart: 10, NewLines: 1, Lines: []DiffLine{
+					{Removed, `output "x" {value=1}`},
+					{Added, `output "x" { value = 1 }`},
+				}},
+			},
+		},
+		{
+			Path: "modules/net/vars.tfvars",
+			Hunks: []Hunk{
+				{OldStart: 1, OldLines: 2, NewStart: 1, NewLines: 2, Lines: []DiffLine{
+					{Removed, `name="a"`},
+					{Added, `name = "a"`},
+					{Context, `region = "b"`},
+				}},
+			},
+		},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ParseDiff() =\n%+v\nwant\n%+v", got, want)
+	}
+	if got[0].Added() != 3 || got[0].Removed() != 3 {
+		t.Errorf("main.tf counts = +%d/-%d, want +3/-3", got[0].Added(), got[0].Removed())
+	}
+}
+
+func TestParseDiff_ListOnlyAndNoise(t *testing.T) {
+	got := ParseDiff("a.tf\nb.tofu\n\nError: something\n")
+	want := []FileDiff{{Path: "a.tf"}, {Path: "b.tofu"}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ParseDiff() = %+v, want %+v", got, want)
+	}
+	if files := ParseDiff(""); len(files) != 0 {
+		t.Errorf("ParseDiff(\"\") = %+v, want empty", files)
+	}
+}
+
+func TestParseHunkHeader(t *testing.T) {
+	cases := []struct {
+		line string
+		want Hunk
+		ok   bool
+	}{
+		{"@@ -1,3 +1,4 @@", Hunk{OldStart: 1, OldLines: 3, NewStart: 1, NewLines: 4}, true},
+		{"@@ -7 +7 @@ resource", Hunk{OldStart: 7, OldLines: 1, NewStart: 7, NewLines: 1}, true},
+		{"@@ -0,0 +1 @@", Hunk{OldStart: 0, OldLines: 0, NewStart: 1, NewLines: 1}, true},
+		{"@@ bogus @@", Hunk{}, false},
+		{"@@ -x,1 +1 @@", Hunk{}, false},
+	}
+	for _, c := range cases {
+		got, ok := parseHunkHeader(c.line)
+		if ok != c.ok || !reflect.DeepEqual(got, c.want) {
+			t.Errorf("parseHunkHeader(%q) = %+v, %v; want %+v, %v", c.line, got, ok, c.want, c.ok)
+		}
+	}
+}
+
+func TestFileDiff_Diagnostics(t *testing.T) {
+	diffs := ParseDiff(sampleDiff)
+	diags := diffs[0].Diagnostics(output.SeverityError)
+	if len(diags) != 2 {
+		t.Fatalf("Diagnostics() returned %d, want one per hunk: %+v", len(diags), diags)
+	}
+	first := diags[0]
+	if first.Severity != output.SeverityError || first.Range.Filename != "main.tf" || first.Range.Start.Line != 1 || first.Range.End.Line != 4 {
+		t.Errorf("first diagnostic = %+v, range %+v", first, first.Range)
+	}
+	if second := diags[1]; second.Range.Start.Line != 10 || second.Range.End.Line != 10 || second.Detail != "tofu fmt would change lines 10-10." {
+		t.Errorf("second diagnostic = %+v, range %+v", second, second.Range)
+	}
+
+	listed := FileDiff{Path: "a.tf"}.Diagnostics(output.SeverityWarning)
+	if len(listed) != 1 || listed[0].Range.Start.Line != 1 || listed[0].Severity != output.SeverityWarning {
+		t.Errorf("Diagnostics() for listed file = %+v", listed)
+	}
+}
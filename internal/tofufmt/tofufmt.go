@@ -1,28 +1,109 @@
 package tofufmt
 
 import (
-	"os/exec"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
 
+	"pre-commit-hooks/internal/runner"
 	"pre-commit-hooks/internal/testutil"
 )
 
 // CheckOpenTofuInstalled delegates to shared testutil implementation.
 var CheckOpenTofuInstalled = testutil.CheckOpenTofuInstalled
 
-// RunTofuFmt runs tofu fmt recursively in the given directory with extra args.
+// FileGroup is a set of files that share a parent directory.
+type FileGroup struct {
+	Dir   string
+	Files []string
+}
+
+// RunTofuFmt runs tofu fmt in check mode in the given directory with extra args.
+// When files is empty the directory is checked recursively, otherwise only the
+// named files (relative to dir) are checked.
 // Returns output and error.
-func RunTofuFmt(dir string, extraArgs []string) (string, error) {
-	args := append([]string{"fmt", "-check", "-recursive", "--diff"}, extraArgs...)
-	cmd := exec.Command("tofu", args...)
-	cmd.Dir = dir
-	output, err := cmd.CombinedOutput()
-	return string(output), err
+func RunTofuFmt(dir string, files []string, extraArgs []string) (string, error) {
+	args := append([]string{"fmt", "-check", "--diff"}, extraArgs...)
+	args = appendTargets(args, files)
+	return runner.Step{Name: "fmt", Dir: dir, Args: args}.CombinedOutput()
 }
 
 // FormatFiles runs tofu fmt to format files in the given directory with extra args.
-func FormatFiles(dir string, extraArgs []string) error {
-	args := append([]string{"fmt", "-recursive"}, extraArgs...)
-	cmd := exec.Command("tofu", args...)
-	cmd.Dir = dir
-	return cmd.Run()
+// When files is empty the directory is formatted recursively.
+// Returns the files tofu rewrote, relative to dir, and error.
+func FormatFiles(dir string, files []string, extraArgs []string) ([]string, error) {
+	args := append([]string{"fmt"}, extraArgs...)
+	args = appendTargets(args, files)
+	output, err := runner.Step{Name: "fmt", Dir: dir, Args: args}.CombinedOutput()
+	if err != nil {
+		return ListedFiles(output), fmt.Errorf("%w: %s", err, strings.TrimSpace(output))
+	}
+	return ListedFiles(output), nil
+}
+
+// appendTargets adds the files to check as tofu fmt targets, or the
+// -recursive flag when there are none.
+func appendTargets(args []string, files []string) []string {
+	if len(files) == 0 {
+		return append(args, "-recursive")
+	}
+	return append(args, files...)
+}
+
+// ListedFiles extracts the file names listed by tofu fmt. tofu prints each
+// unformatted (or, when writing, rewritten) file name on its own line, followed
+// by its diff when -diff is set, so diff lines are skipped.
+func ListedFiles(output string) []string {
+	var files []string
+	for _, line := range strings.Split(output, "\n") {
+		line = strings.TrimRight(line, "\r")
+		if line == "" || strings.ContainsAny(line[:1], "+- @\\\t") {
+			continue
+		}
+		if IsFormattable(line) {
+			files = append(files, line)
+		}
+	}
+	return files
+}
+
+// IsFormattable reports whether tofu fmt can process the named file.
+func IsFormattable(name string) bool {
+	for _, ext := range []string{".tf", ".tofu", ".tfvars", ".tftest.hcl"} {
+		if strings.HasSuffix(name, ext) {
+			return true
+		}
+	}
+	return false
+}
+
+// GroupFilesByDir groups formattable files by parent directory. Groups are
+// sorted by directory and each group lists base names in sorted order, with
+// duplicates removed.
+func GroupFilesByDir(files []string) []FileGroup {
+	byDir := map[string]map[string]bool{}
+	for _, file := range files {
+		if !IsFormattable(file) {
+			continue
+		}
+		dir, name := filepath.Split(filepath.Clean(file))
+		dir = filepath.Clean(dir)
+		if byDir[dir] == nil {
+			byDir[dir] = map[string]bool{}
+		}
+		byDir[dir][name] = true
+	}
+
+	groups := make([]FileGroup, 0, len(byDir))
+	for dir, names := range byDir {
+		group := FileGroup{Dir: dir}
+		for name := range names {
+			group.Files = append(group.Files, name)
+		}
+		sort.Strings(group.Files)
+		groups = append(groups, group)
+	}
+	sort.Slice(groups, func(i, j int) bool { return groups[i].Dir < groups[j].Dir })
+	return groups
 }
@@ -3,6 +3,7 @@ package tofufmt
 import (
 	"os"
 	"path/filepath"
+	"reflect"
 	"strings"
 	"testing"
 
@@ -47,15 +48,20 @@ func TestRunTofuFmt_MultiFileAndNested(t *testing.T) {
 	}
 
 	// Run tofu fmt check
-	output, err := RunTofuFmt(tempDir, nil)
+	output, err := RunTofuFmt(tempDir, nil, nil)
 	if err == nil {
 		t.Fatalf("Expected tofu fmt to report unformatted files, but got no error. Output: %s", output)
 	}
 
 	// Format all files
-	if err := FormatFiles(tempDir, nil); err != nil {
+	rewritten, err := FormatFiles(tempDir, nil, nil)
+	if err != nil {
 		t.Fatalf("Failed to format files: %v", err)
 	}
+	wantRewritten := []string{"main.tf", filepath.Join("subdir", "nested.tf")}
+	if !reflect.DeepEqual(rewritten, wantRewritten) {
+		t.Errorf("FormatFiles() rewritten = %v, want %v", rewritten, wantRewritten)
+	}
 
 	// Check that all .tf files are formatted
 	for _, f := range files {
@@ -86,12 +92,12 @@ func TestRunTofuFmt_UnformattedFile(t *testing.T) {
 		t.Fatalf("Failed to write test file: %v", err)
 	}
 
-	output, err := RunTofuFmt(tempDir, nil)
+	output, err := RunTofuFmt(tempDir, nil, nil)
 	if err == nil {
 		t.Fatalf("Expected tofu fmt to report unformatted file, but got no error. Output: %s", output)
 	}
 	// Now format the file
-	if err := FormatFiles(tempDir, nil); err != nil {
+	if _, err := FormatFiles(tempDir, nil, nil); err != nil {
 		t.Fatalf("Failed to format file: %v", err)
 	}
 	// Check that file is now formatted
@@ -103,3 +109,93 @@ func TestRunTofuFmt_UnformattedFile(t *testing.T) {
 		t.Errorf("File was not formatted correctly. Got: %q, Want: %q", string(result), formatted)
 	}
 }
+
+func TestRunTofuFmt_OnlyNamedFiles(t *testing.T) {
+	testutil.SkipIfTofuNotInstalled(t)
+	tempDir, cleanup := testutil.CreateTempDir(t, "fmt_test_named")
+	defer cleanup()
+
+	unformatted := "variable   \"foo\"   {}"
+	for _, name := range []string{"staged.tf", "untouched.tf"} {
+		if err := os.WriteFile(filepath.Join(tempDir, name), []byte(unformatted), 0644); err != nil {
+			t.Fatalf("Failed to write test file %s: %v", name, err)
+		}
+	}
+
+	rewritten, err := FormatFiles(tempDir, []string{"staged.tf"}, nil)
+	if err != nil {
+		t.Fatalf("Failed to format file: %v", err)
+	}
+	if !reflect.DeepEqual(rewritten, []string{"staged.tf"}) {
+		t.Errorf("FormatFiles() rewritten = %v, want [staged.tf]", rewritten)
+	}
+	staged, _ := os.ReadFile(filepath.Join(tempDir, "staged.tf"))
+	if string(staged) != "variable \"foo\" {}" {
+		t.Errorf("staged.tf was not formatted. Got: %q", string(staged))
+	}
+	untouched, _ := os.ReadFile(filepath.Join(tempDir, "untouched.tf"))
+	if string(untouched) != unformatted {
+		t.Errorf("untouched.tf should not have been formatted. Got: %q", string(untouched))
+	}
+}
+
+func TestGroupFilesByDir(t *testing.T) {
+	files := []string{
+		"modules/b/main.tf",
+		"main.tf",
+		"modules/a/variables.tf",
+		"README.md",
+		"modules/b/terraform.tfvars",
+		"./main.tf",
+		"modules/a/main.tofu",
+	}
+	want := []FileGroup{
+		{Dir: ".", Files: []string{"main.tf"}},
+		{Dir: filepath.Join("modules", "a"), Files: []string{"main.tofu", "variables.tf"}},
+		{Dir: filepath.Join("modules", "b"), Files: []string{"main.tf", "terraform.tfvars"}},
+	}
+	got := GroupFilesByDir(files)
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("GroupFilesByDir() = %v, want %v", got, want)
+	}
+	if groups := GroupFilesByDir(nil); len(groups) != 0 {
+		t.Errorf("GroupFilesByDir(nil) = %v, want empty", groups)
+	}
+}
+
+func TestIsFormattable(t *testing.T) {
+	cases := map[string]bool{
+		"main.tf":             true,
+		"main.tofu":           true,
+		"prod.tfvars":         true,
+		"unit.tftest.hcl":     true,
+		"README.md":           false,
+		"main.tf.json":        false,
+		".terraform.lock.hcl": false,
+	}
+	for name, want := range cases {
+		if got := IsFormattable(name); got != want {
+			t.Errorf("IsFormattable(%q) = %v, want %v", name, got, want)
+		}
+	}
+}
+
+func TestListedFiles(t *testing.T) {
+	out := `main.tf
+--- old/main.tf
++++ new/main.tf
+@@ -1 +1 @@
+-variable   "foo"   {}
++variable "foo" {}
+\ No newline at end of file
+vars.tfvars
+`
+	got := ListedFiles(out)
+	want := []string{"main.tf", "vars.tfvars"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ListedFiles() = %v, want %v", got, want)
+	}
+	if files := ListedFiles(""); len(files) != 0 {
+		t.Errorf("ListedFiles(\"\") = %v, want empty", files)
+	}
+}
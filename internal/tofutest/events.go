@@ -0,0 +1,267 @@
+package tofutest
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"pre-commit-hooks/internal/output"
+)
+
+// Types of the machine-readable messages printed by tofu test -json.
+const (
+	EventTestFile    = "test_file"
+	EventTestRun     = "test_run"
+	EventDiagnostic  = "diagnostic"
+	EventTestSummary = "test_summary"
+)
+
+// Progress of a test file or run block in test_file and test_run messages.
+const (
+	ProgressStarting = "starting"
+	ProgressRunning  = "running"
+	ProgressTeardown = "teardown"
+	ProgressComplete = "complete"
+)
+
+// assertionFailed is the summary of the diagnostic tofu reports for a failed
+// assert block.
+const assertionFailed = "Test assertion failed"
+
+// Event is one message printed by tofu test -json. Only the field matching
+// Type is set; messages of other types only carry the common fields.
+type Event struct {
+	Level   string `json:"@level"`
+	Message string `json:"@message"`
+	Type    string `json:"type"`
+	// TestFile and TestRun locate diagnostics reported for a file or run.
+	TestFile string `json:"@testfile,omitempty"`
+	TestRun  string `json:"@testrun,omitempty"`
+
+	File       *FileEvent         `json:"test_file,omitempty"`
+	Run        *RunEvent          `json:"test_run,omitempty"`
+	Diagnostic *output.Diagnostic `json:"diagnostic,omitempty"`
+	Summary    *SummaryEvent      `json:"test_summary,omitempty"`
+}
+
+// FileEvent reports a test file starting, tearing down or completing.
+type FileEvent struct {
+	Path     string `json:"path"`
+	Progress string `json:"progress"`
+	// Status is set once the file is complete.
+	Status string `json:"status,omitempty"`
+}
+
+// RunEvent reports a run block starting, running or completing.
+type RunEvent struct {
+	Path     string `json:"path"`
+	Run      string `json:"run"`
+	Progress string `json:"progress"`
+	// Status is set once the run is complete.
+	Status string `json:"status,omitempty"`
+}
+
+// SummaryEvent is the final count of run blocks.
+type SummaryEvent struct {
+	Status  string `json:"status"`
+	Passed  int    `json:"passed"`
+	Failed  int    `json:"failed"`
+	Errored int    `json:"errored"`
+	Skipped int    `json:"skipped"`
+}
+
+// AssertionFailed reports whether the event is the diagnostic of a failed
+// assert block.
+func (e Event) AssertionFailed() bool {
+	return e.Type == EventDiagnostic && e.Diagnostic != nil && e.Diagnostic.Summary == assertionFailed
+}
+
+// Decoder reads the events streamed by tofu test -json, one per line.
+type Decoder struct {
+	scanner *bufio.Scanner
+}
+
+// NewDecoder returns a decoder reading from r.
+func NewDecoder(r io.Reader) *Decoder {
+	scanner := bufio.NewScanner(r)
+	// Diagnostics with large values can make for long lines
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	return &Decoder{scanner: scanner}
+}
+
+// Decode returns the next event, or io.EOF after the last one. Lines that
+// are not JSON objects, such as plain text tofu prints for some errors, are
+// skipped.
+func (d *Decoder) Decode() (Event, error) {
+	for d.scanner.Scan() {
+		line := bytes.TrimSpace(d.scanner.Bytes())
+		if len(line) == 0 || line[0] != '{' {
+			continue
+		}
+		var event Event
+		if err := json.Unmarshal(line, &event); err != nil {
+			return Event{}, fmt.Errorf("could not decode tofu test -json message: %w", err)
+		}
+		return event, nil
+	}
+	if err := d.scanner.Err(); err != nil {
+		return Event{}, err
+	}
+	return Event{}, io.EOF
+}
+
+// ParseTestJSON decodes the output of tofu test -json into its events. Output
+// without any JSON message is an error.
+func ParseTestJSON(data []byte) ([]Event, error) {
+	decoder := NewDecoder(bytes.NewReader(data))
+	var events []Event
+	for {
+		event, err := decoder.Decode()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return events, err
+		}
+		events = append(events, event)
+	}
+	if len(events) == 0 {
+		return nil, fmt.Errorf("could not decode tofu test -json output: no JSON messages")
+	}
+	return events, nil
+}
+
+// BuildResults folds events into results per test file, in the order files
+// were started, and returns them with the diagnostics not reported for any
+// file, such as errors loading the configuration. The Output of each run and
+// file holds its diagnostics formatted as text.
+func BuildResults(events []Event) ([]FileResult, []output.Diagnostic) {
+	var files []FileResult
+	var other []output.Diagnostic
+	fileIndex := func(path string) int {
+		for i := range files {
+			if files[i].Path == path {
+				return i
+			}
+		}
+		files = append(files, FileResult{Path: path})
+		return len(files) - 1
+	}
+	runIndex := func(file *FileResult, name string) int {
+		for i := range file.Runs {
+			if file.Runs[i].Name == name {
+				return i
+			}
+		}
+		file.Runs = append(file.Runs, RunResult{Name: name})
+		return len(file.Runs) - 1
+	}
+
+	for _, event := range events {
+		switch {
+		case event.Type == EventTestFile && event.File != nil:
+			file := &files[fileIndex(event.File.Path)]
+			if event.File.Status != "" {
+				file.Status = event.File.Status
+			}
+		case event.Type == EventTestRun && event.Run != nil:
+			file := &files[fileIndex(event.Run.Path)]
+			run := &file.Runs[runIndex(file, event.Run.Run)]
+			if event.Run.Status != "" {
+				run.Status = event.Run.Status
+			}
+		case event.Type == EventDiagnostic && event.Diagnostic != nil:
+			if event.TestFile == "" {
+				other = append(other, *event.Diagnostic)
+				continue
+			}
+			file := &files[fileIndex(event.TestFile)]
+			if event.TestRun == "" {
+				file.Diagnostics = append(file.Diagnostics, *event.Diagnostic)
+				continue
+			}
+			run := &file.Runs[runIndex(file, event.TestRun)]
+			run.Diagnostics = append(run.Diagnostics, *event.Diagnostic)
+			if event.AssertionFailed() {
+				run.Assertions = append(run.Assertions, *event.Diagnostic)
+			}
+		}
+	}
+
+	for i := range files {
+		file := &files[i]
+		file.Output = strings.TrimSpace(output.FormatDiagnostics(file.Diagnostics))
+		for r := range file.Runs {
+			file.Runs[r].Output = strings.TrimSpace(output.FormatDiagnostics(file.Runs[r].Diagnostics))
+		}
+	}
+	return files, other
+}
+
+// RenderTree renders results as a tree of files and their run blocks with
+// their status. The diagnostics of failed runs, and any reported for a file
+// outside its runs, are listed below them; a run's failed assertions come
+// first, each with its error message, apart from its other errors.
+func RenderTree(files []FileResult) string {
+	var b strings.Builder
+	for _, file := range files {
+		fmt.Fprintf(&b, "%s: %s\n", file.Path, statusText(file.Status))
+		for _, run := range file.Runs {
+			fmt.Fprintf(&b, "  run %q: %s\n", run.Name, statusText(run.Status))
+			if run.Status != StatusFail && run.Status != StatusError {
+				continue
+			}
+			if len(run.Assertions) == 0 {
+				writeIndented(&b, run.Output, "      ")
+				continue
+			}
+			var others []output.Diagnostic
+			for _, diag := range run.Diagnostics {
+				if diag.Summary != assertionFailed {
+					others = append(others, diag)
+				}
+			}
+			for _, assertion := range run.Assertions {
+				writeIndented(&b, formatAssertion(assertion), "      ")
+			}
+			writeIndented(&b, output.FormatDiagnostics(others), "      ")
+		}
+		writeIndented(&b, file.Output, "    ")
+	}
+	return b.String()
+}
+
+// formatAssertion formats a failed assertion as its error message and the
+// location of the condition
+func formatAssertion(diag output.Diagnostic) string {
+	text := "Assertion failed"
+	if diag.Detail != "" {
+		text += ": " + diag.Detail
+	}
+	if diag.Range != nil {
+		text += fmt.Sprintf("\n  on %s line %d", diag.Range.Filename, diag.Range.Start.Line)
+	}
+	return text
+}
+
+// statusText returns status, or "incomplete" for a file or run that never
+// finished, e.g. because tofu test was stopped
+func statusText(status string) string {
+	if status == "" {
+		return "incomplete"
+	}
+	return status
+}
+
+// writeIndented writes each non-empty line of text with the given indent
+func writeIndented(b *strings.Builder, text, indent string) {
+	for _, line := range strings.Split(text, "\n") {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		b.WriteString(indent + line + "\n")
+	}
+}
@@ -0,0 +1,152 @@
+package tofutest
+
+import (
+	"io"
+	"strings"
+	"testing"
+
+	"pre-commit-hooks/internal/output"
+)
+
+const sampleJSON = `{"@level":"info","@message":"OpenTofu 1.8.0","type":"version","tofu":"1.8.0","ui":"1.2"}
+{"@level":"info","@message":"Found 2 files and 4 run blocks","type":"test_abstract","test_abstract":{"tests/main.tftest.hcl":["setup","check_name","after"],"tests/other.tftest.hcl":["plan"]}}
+{"@level":"info","@message":"tests/main.tftest.hcl... in progress","@testfile":"tests/main.tftest.hcl","type":"test_file","test_file":{"path":"tests/main.tftest.hcl","progress":"starting"}}
+{"@level":"info","@message":"  \"setup\"... in progress","@testfile":"tests/main.tftest.hcl","@testrun":"setup","type":"test_run","test_run":{"path":"tests/main.tftest.hcl","run":"setup","progress":"starting"}}
+{"@level":"info","@message":"  \"setup\"... pass","@testfile":"tests/main.tftest.hcl","@testrun":"setup","type":"test_run","test_run":{"path":"tests/main.tftest.hcl","run":"setup","progress":"complete","status":"pass"}}
+{"@level":"info","@message":"  \"check_name\"... fail","@testfile":"tests/main.tftest.hcl","@testrun":"check_name","type":"test_run","test_run":{"path":"tests/main.tftest.hcl","run":"check_name","progress":"complete","status":"fail"}}
+{"@level":"error","@message":"Error: Test assertion failed","@testfile":"tests/main.tftest.hcl","@testrun":"check_name","type":"diagnostic","diagnostic":{"severity":"error","summary":"Test assertion failed","detail":"Name did not match.","range":{"filename":"tests/main.tftest.hcl","start":{"line":12,"column":21,"byte":200},"end":{"line":12,"column":50,"byte":229}}}}
+{"@level":"info","@message":"  \"after\"... skip","@testfile":"tests/main.tftest.hcl","@testrun":"after","type":"test_run","test_run":{"path":"tests/main.tftest.hcl","run":"after","progress":"complete","status":"skip"}}
+{"@level":"info","@message":"tests/main.tftest.hcl... tearing down","@testfile":"tests/main.tftest.hcl","type":"test_file","test_file":{"path":"tests/main.tftest.hcl","progress":"teardown"}}
+{"@level":"info","@message":"tests/main.tftest.hcl... fail","@testfile":"tests/main.tftest.hcl","type":"test_file","test_file":{"path":"tests/main.tftest.hcl","progress":"complete","status":"fail"}}
+{"@level":"info","@message":"tests/other.tftest.hcl... in progress","@testfile":"tests/other.tftest.hcl","type":"test_file","test_file":{"path":"tests/other.tftest.hcl","progress":"starting"}}
+{"@level":"info","@message":"  \"plan\"... pass","@testfile":"tests/other.tftest.hcl","@testrun":"plan","type":"test_run","test_run":{"path":"tests/other.tftest.hcl","run":"plan","progress":"complete","status":"pass"}}
+{"@level":"warn","@message":"Warning: Deprecated variable","@testfile":"tests/other.tftest.hcl","type":"diagnostic","diagnostic":{"severity":"warning","summary":"Deprecated variable"}}
+{"@level":"info","@message":"tests/other.tftest.hcl... pass","@testfile":"tests/other.tftest.hcl","type":"test_file","test_file":{"path":"tests/other.tftest.hcl","progress":"complete","status":"pass"}}
+{"@level":"info","@message":"Failure! 2 passed, 1 failed, 1 skipped.","type":"test_summary","test_summary":{"status":"fail","passed":2,"failed":1,"errored":0,"skipped":1}}
+`
+
+func TestParseTestJSON(t *testing.T) {
+	events, err := ParseTestJSON([]byte("plain text before the stream\n" + sampleJSON))
+	if err != nil {
+		t.Fatalf("ParseTestJSON() error: %v", err)
+	}
+	if len(events) != 15 {
+		t.Fatalf("got %d events, want 15", len(events))
+	}
+	if file := events[2]; file.Type != EventTestFile || file.File.Path != "tests/main.tftest.hcl" || file.File.Progress != ProgressStarting {
+		t.Errorf("events[2] = %+v, want the main file starting", file)
+	}
+	var assertions int
+	for _, event := range events {
+		if event.AssertionFailed() {
+			assertions++
+			if event.TestRun != "check_name" || event.Diagnostic.Range.Start.Line != 12 {
+				t.Errorf("assertion failure = %+v", event)
+			}
+		}
+	}
+	if assertions != 1 {
+		t.Errorf("got %d assertion failures, want 1", assertions)
+	}
+	if summary := events[len(events)-1].Summary; summary == nil || summary.Passed != 2 || summary.Failed != 1 || summary.Skipped != 1 {
+		t.Errorf("summary = %+v", summary)
+	}
+
+	if _, err := ParseTestJSON([]byte("Error: Invalid flag\n")); err == nil {
+		t.Error("ParseTestJSON() of text output succeeded, want an error")
+	}
+	if _, err := ParseTestJSON([]byte("{\"type\":\n")); err == nil {
+		t.Error("ParseTestJSON() of a broken message succeeded, want an error")
+	}
+}
+
+func TestDecoder_Stream(t *testing.T) {
+	decoder := NewDecoder(strings.NewReader(sampleJSON))
+	count := 0
+	for {
+		_, err := decoder.Decode()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("Decode() error: %v", err)
+		}
+		count++
+	}
+	if count != 15 {
+		t.Errorf("decoded %d events, want 15", count)
+	}
+}
+
+func TestBuildResults(t *testing.T) {
+	events, err := ParseTestJSON([]byte(sampleJSON + `{"@level":"error","@message":"Error: Invalid configuration","type":"diagnostic","diagnostic":{"severity":"error","summary":"Invalid configuration"}}` + "\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	files, other := BuildResults(events)
+	if len(files) != 2 || len(other) != 1 || other[0].Summary != "Invalid configuration" {
+		t.Fatalf("BuildResults() = %+v, %+v", files, other)
+	}
+
+	main := files[0]
+	if main.Path != "tests/main.tftest.hcl" || main.Status != StatusFail || len(main.Runs) != 3 {
+		t.Fatalf("main = %+v", main)
+	}
+	failed := main.Runs[1]
+	if failed.Name != "check_name" || failed.Status != StatusFail || len(failed.Diagnostics) != 1 {
+		t.Errorf("check_name = %+v", failed)
+	}
+	if len(failed.Assertions) != 1 || failed.Assertions[0].Detail != "Name did not match." {
+		t.Errorf("check_name assertions = %+v, want the failed assert block", failed.Assertions)
+	}
+	if Summary(failed.Output) != "Test assertion failed" {
+		t.Errorf("Summary(%q) is not the assertion", failed.Output)
+	}
+	if file, line := Location(failed.Output); file != "tests/main.tftest.hcl" || line != 12 {
+		t.Errorf("Location() = %s:%d, want tests/main.tftest.hcl:12", file, line)
+	}
+
+	second := files[1]
+	if second.Status != StatusPass || len(second.Runs) != 1 || second.Output != "Warning: Deprecated variable" {
+		t.Errorf("other = %+v", second)
+	}
+
+	// The results feed the JUnit report like those scraped from text
+	report := NewJUnitReport(files)
+	if report.Tests != 4 || report.Failures != 1 || report.Skipped != 1 {
+		t.Errorf("JUnit report = %+v", report)
+	}
+}
+
+func TestRenderTree(t *testing.T) {
+	events, err := ParseTestJSON([]byte(sampleJSON))
+	if err != nil {
+		t.Fatal(err)
+	}
+	files, _ := BuildResults(events)
+	assertion := output.Diagnostic{Severity: "error", Summary: "Test assertion failed", Detail: "Too many zones."}
+	other := output.Diagnostic{Severity: "error", Summary: "Invalid value"}
+	files = append(files,
+		FileResult{Path: "tests/mixed.tftest.hcl", Status: StatusFail, Runs: []RunResult{{Name: "zones", Status: StatusFail, Diagnostics: []output.Diagnostic{assertion, other}, Assertions: []output.Diagnostic{assertion}}}},
+		FileResult{Path: "tests/stopped.tftest.hcl", Runs: []RunResult{{Name: "slow"}}},
+	)
+	want := `tests/main.tftest.hcl: fail
+  run "setup": pass
+  run "check_name": fail
+      Assertion failed: Name did not match.
+        on tests/main.tftest.hcl line 12
+  run "after": skip
+tests/other.tftest.hcl: pass
+  run "plan": pass
+    Warning: Deprecated variable
+tests/mixed.tftest.hcl: fail
+  run "zones": fail
+      Assertion failed: Too many zones.
+      Error: Invalid value
+tests/stopped.tftest.hcl: incomplete
+  run "slow": incomplete
+`
+	if got := RenderTree(files); got != want {
+		t.Errorf("RenderTree() =\n%s\nwant\n%s", got, want)
+	}
+}
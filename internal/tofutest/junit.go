@@ -0,0 +1,108 @@
+package tofutest
+
+import (
+	"encoding/xml"
+	"os"
+)
+
+// JUnitTestSuites is the root element of a JUnit XML report.
+type JUnitTestSuites struct {
+	XMLName  xml.Name         `xml:"testsuites"`
+	Name     string           `xml:"name,attr"`
+	Tests    int              `xml:"tests,attr"`
+	Failures int              `xml:"failures,attr"`
+	Errors   int              `xml:"errors,attr"`
+	Skipped  int              `xml:"skipped,attr"`
+	Suites   []JUnitTestSuite `xml:"testsuite"`
+}
+
+// JUnitTestSuite holds the run blocks of one test file.
+type JUnitTestSuite struct {
+	Name      string          `xml:"name,attr"`
+	Tests     int             `xml:"tests,attr"`
+	Failures  int             `xml:"failures,attr"`
+	Errors    int             `xml:"errors,attr"`
+	Skipped   int             `xml:"skipped,attr"`
+	TestCases []JUnitTestCase `xml:"testcase"`
+	SystemErr string          `xml:"system-err,omitempty"`
+}
+
+// JUnitTestCase is one run block.
+type JUnitTestCase struct {
+	Name      string        `xml:"name,attr"`
+	ClassName string        `xml:"classname,attr"`
+	Failure   *JUnitProblem `xml:"failure,omitempty"`
+	Error     *JUnitProblem `xml:"error,omitempty"`
+	Skipped   *struct{}     `xml:"skipped,omitempty"`
+}
+
+// JUnitProblem describes a failed or errored test case.
+type JUnitProblem struct {
+	Message string `xml:"message,attr,omitempty"`
+	Text    string `xml:",chardata"`
+}
+
+// NewJUnitReport builds a JUnit report with one testsuite per test file and
+// one testcase per run block. Diagnostics printed for a file outside its run
+// blocks are kept in the suite's system-err, and a file that failed without a
+// failing run block gets an extra errored testcase named after the file.
+func NewJUnitReport(files []FileResult) JUnitTestSuites {
+	report := JUnitTestSuites{Name: "tofu test", Suites: []JUnitTestSuite{}}
+	for _, file := range files {
+		suite := JUnitTestSuite{Name: file.Path, SystemErr: file.Output}
+		for _, run := range file.Runs {
+			testCase := JUnitTestCase{Name: run.Name, ClassName: file.Path}
+			switch run.Status {
+			case StatusFail:
+				testCase.Failure = &JUnitProblem{Message: failureMessage(run), Text: run.Output}
+				suite.Failures++
+			case StatusError:
+				testCase.Error = &JUnitProblem{Message: failureMessage(run), Text: run.Output}
+				suite.Errors++
+			case StatusSkip:
+				testCase.Skipped = &struct{}{}
+				suite.Skipped++
+			}
+			suite.TestCases = append(suite.TestCases, testCase)
+		}
+		if (file.Status == StatusFail || file.Status == StatusError) && suite.Failures+suite.Errors == 0 {
+			// The file failed outside its run blocks, e.g. while parsing or
+			// tearing down; record it so the suite does not appear to pass
+			message := Summary(file.Output)
+			if message == "" {
+				message = "test file " + file.Status
+			}
+			suite.TestCases = append(suite.TestCases, JUnitTestCase{
+				Name:      file.Path,
+				ClassName: file.Path,
+				Error:     &JUnitProblem{Message: message, Text: file.Output},
+			})
+			suite.Errors++
+		}
+		suite.Tests = len(suite.TestCases)
+		report.Tests += suite.Tests
+		report.Failures += suite.Failures
+		report.Errors += suite.Errors
+		report.Skipped += suite.Skipped
+		report.Suites = append(report.Suites, suite)
+	}
+	return report
+}
+
+// WriteJUnit writes the report as indented XML to path.
+func WriteJUnit(path string, report JUnitTestSuites) error {
+	data, err := xml.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return err
+	}
+	data = append([]byte(xml.Header), data...)
+	return os.WriteFile(path, append(data, '\n'), 0644)
+}
+
+// failureMessage returns the diagnostic summary of a failed run, or its status
+func failureMessage(run RunResult) string {
+	if summary := Summary(run.Output); summary != "" {
+		return summary
+	}
+	return "run " + run.Status
+}
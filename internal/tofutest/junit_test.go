@@ -0,0 +1,67 @@
+package tofutest
+
+import (
+	"encoding/xml"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestNewJUnitReport(t *testing.T) {
+	report := NewJUnitReport(ParseTestOutput(sampleOutput))
+	if report.Tests != 4 || report.Failures != 1 || report.Errors != 0 || report.Skipped != 1 {
+		t.Errorf("totals = %d tests, %d failures, %d errors, %d skipped", report.Tests, report.Failures, report.Errors, report.Skipped)
+	}
+	if len(report.Suites) != 2 || report.Suites[0].Name != "tests/main.tftest.hcl" {
+		t.Fatalf("suites = %+v", report.Suites)
+	}
+	cases := report.Suites[0].TestCases
+	if len(cases) != 3 || cases[1].Name != "check_name" || cases[1].ClassName != "tests/main.tftest.hcl" {
+		t.Fatalf("testcases = %+v", cases)
+	}
+	if cases[0].Failure != nil || cases[0].Skipped != nil {
+		t.Errorf("passing run = %+v", cases[0])
+	}
+	if cases[1].Failure == nil || cases[1].Failure.Message != "Test assertion failed" || !strings.Contains(cases[1].Failure.Text, "Name did not match.") {
+		t.Errorf("failed run = %+v", cases[1])
+	}
+	if cases[2].Skipped == nil {
+		t.Errorf("skipped run = %+v", cases[2])
+	}
+}
+
+func TestNewJUnitReport_FileLevelError(t *testing.T) {
+	report := NewJUnitReport([]FileResult{{Path: "broken.tftest.hcl", Status: StatusError, Output: "Error: Invalid run block"}})
+	suite := report.Suites[0]
+	if suite.Tests != 1 || suite.Errors != 1 || suite.SystemErr != "Error: Invalid run block" {
+		t.Fatalf("suite = %+v", suite)
+	}
+	if problem := suite.TestCases[0].Error; problem == nil || problem.Message != "Invalid run block" {
+		t.Errorf("testcase = %+v", suite.TestCases[0])
+	}
+}
+
+func TestWriteJUnit(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "junit.xml")
+	if err := WriteJUnit(path, NewJUnitReport(ParseTestOutput(sampleOutput))); err != nil {
+		t.Fatalf("WriteJUnit() error: %v", err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(string(data), "<?xml") {
+		t.Errorf("missing XML header: %s", data)
+	}
+	var decoded JUnitTestSuites
+	if err := xml.Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("invalid XML: %v", err)
+	}
+	if decoded.Tests != 4 || len(decoded.Suites) != 2 || len(decoded.Suites[1].TestCases) != 1 {
+		t.Errorf("decoded = %+v", decoded)
+	}
+	if !strings.Contains(string(data), `<failure message="Test assertion failed">`) || !strings.Contains(string(data), "<skipped></skipped>") {
+		t.Errorf("unexpected XML:\n%s", data)
+	}
+}
@@ -0,0 +1,149 @@
+package tofutest
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+
+	"pre-commit-hooks/internal/output"
+)
+
+// Statuses reported by tofu test for test files and run blocks.
+const (
+	StatusPass  = "pass"
+	StatusFail  = "fail"
+	StatusSkip  = "skip"
+	StatusError = "error"
+)
+
+// RunResult is the outcome of one run block.
+type RunResult struct {
+	Name   string
+	Status string
+	// Output holds the diagnostics printed for the run, without box drawing.
+	Output string
+	// Diagnostics holds the run's diagnostics when tofu test ran with -json.
+	Diagnostics []output.Diagnostic
+	// Assertions holds the diagnostics of the run's failed assert blocks,
+	// which are among Diagnostics too, when tofu test ran with -json.
+	Assertions []output.Diagnostic
+}
+
+// FileResult is the outcome of one .tftest.hcl file and its run blocks.
+type FileResult struct {
+	Path   string
+	Status string
+	Runs   []RunResult
+	// Output holds diagnostics printed for the file outside any run block,
+	// such as errors while tearing down.
+	Output string
+	// Diagnostics holds the file's diagnostics when tofu test ran with -json.
+	Diagnostics []output.Diagnostic
+}
+
+var (
+	// fileLine matches "tests/main.tftest.hcl... pass" and "... in progress".
+	fileLine = regexp.MustCompile(`^(\S+\.tftest\.(?:hcl|json))\.\.\. (in progress|tearing down|pass|fail|skip|error)$`)
+	// runLine matches `  run "name"... pass`.
+	runLine = regexp.MustCompile(`^\s+run "([^"]+)"\.\.\. (pass|fail|skip|error)$`)
+	// locationLine matches `  on tests/main.tftest.hcl line 12, in run "x":`.
+	locationLine = regexp.MustCompile(`^\s*on (\S+) line (\d+)`)
+	// summaryLine matches the final "Success! 2 passed, 0 failed." line.
+	summaryLine = regexp.MustCompile(`^(?:Success|Failure)! \d+ passed`)
+)
+
+// ParseTestOutput parses the human-readable output of tofu test into results
+// per test file, in the order files were run. Diagnostics printed after a run
+// block's status line are attached to that run; diagnostics printed before
+// the first run or after the file finished are attached to the file. Colors
+// are stripped, as tofu prints them even when its output is not a terminal.
+func ParseTestOutput(text string) []FileResult {
+	var files []FileResult
+	var details []string
+	runIndex := -1
+
+	flush := func() {
+		if len(files) == 0 {
+			details = nil
+			return
+		}
+		current := &files[len(files)-1]
+		text := strings.TrimSpace(strings.Join(details, "\n"))
+		details = nil
+		if text == "" {
+			return
+		}
+		target := &current.Output
+		if runIndex >= 0 {
+			target = &current.Runs[runIndex].Output
+		}
+		if *target != "" {
+			*target += "\n"
+		}
+		*target += text
+	}
+
+	for _, line := range strings.Split(output.StripANSI(text), "\n") {
+		line = strings.TrimRight(line, "\r")
+		if m := fileLine.FindStringSubmatch(line); m != nil {
+			flush()
+			if len(files) == 0 || files[len(files)-1].Path != m[1] {
+				files = append(files, FileResult{Path: m[1]})
+			}
+			if m[2] != "in progress" && m[2] != "tearing down" {
+				files[len(files)-1].Status = m[2]
+			}
+			// Diagnostics from here on, e.g. during teardown, belong to the file
+			runIndex = -1
+			continue
+		}
+		if m := runLine.FindStringSubmatch(line); m != nil && len(files) > 0 {
+			flush()
+			current := &files[len(files)-1]
+			current.Runs = append(current.Runs, RunResult{Name: m[1], Status: m[2]})
+			runIndex = len(current.Runs) - 1
+			continue
+		}
+		if summaryLine.MatchString(line) {
+			continue
+		}
+		details = append(details, stripBox(line))
+	}
+	flush()
+	return files
+}
+
+// stripBox removes the box drawing tofu puts around diagnostics
+func stripBox(line string) string {
+	trimmed := strings.TrimSpace(line)
+	if trimmed == "╷" || trimmed == "╵" {
+		return ""
+	}
+	if rest, ok := strings.CutPrefix(trimmed, "│"); ok {
+		return strings.TrimPrefix(rest, " ")
+	}
+	return line
+}
+
+// Summary returns the first "Error: ..." summary in a run or file output,
+// for use as a short failure message, or "" when there is none.
+func Summary(output string) string {
+	for _, line := range strings.Split(output, "\n") {
+		if summary, ok := strings.CutPrefix(strings.TrimSpace(line), "Error: "); ok {
+			return summary
+		}
+	}
+	return ""
+}
+
+// Location returns the file and line of the first diagnostic in a run or file
+// output, or "" and 0 when there is none.
+func Location(output string) (string, int) {
+	for _, line := range strings.Split(output, "\n") {
+		if m := locationLine.FindStringSubmatch(line); m != nil {
+			n, _ := strconv.Atoi(m[2])
+			return m[1], n
+		}
+	}
+	return "", 0
+}
@@ -0,0 +1,104 @@
+package tofutest
+
+import (
+	"testing"
+)
+
+const sampleOutput = `tests/main.tftest.hcl... in progress
+  run "setup"... pass
+  run "check_name"... fail
+╷
+│ Error: Test assertion failed
+│
+│   on tests/main.tftest.hcl line 12, in run "check_name":
+│   12:     condition     = output.name == "expected"
+│
+│ Name did not match.
+╵
+  run "after"... skip
+tests/main.tftest.hcl... tearing down
+tests/main.tftest.hcl... fail
+tests/other.tftest.hcl... in progress
+  run "plan"... pass
+tests/other.tftest.hcl... tearing down
+tests/other.tftest.hcl... pass
+
+Failure! 2 passed, 1 failed, 1 skipped.
+`
+
+func TestParseTestOutput(t *testing.T) {
+	files := ParseTestOutput(sampleOutput)
+	if len(files) != 2 {
+		t.Fatalf("got %d files, want 2: %+v", len(files), files)
+	}
+
+	main := files[0]
+	if main.Path != "tests/main.tftest.hcl" || main.Status != StatusFail || main.Output != "" {
+		t.Errorf("main = %+v", main)
+	}
+	wantRuns := []RunResult{{Name: "setup", Status: StatusPass}, {Name: "check_name", Status: StatusFail}, {Name: "after", Status: StatusSkip}}
+	if len(main.Runs) != len(wantRuns) {
+		t.Fatalf("main runs = %+v, want %+v", main.Runs, wantRuns)
+	}
+	for i, want := range wantRuns {
+		if main.Runs[i].Name != want.Name || main.Runs[i].Status != want.Status {
+			t.Errorf("run %d = %+v, want %+v", i, main.Runs[i], want)
+		}
+	}
+	failed := main.Runs[1].Output
+	if Summary(failed) != "Test assertion failed" {
+		t.Errorf("Summary(%q) = %q, want the assertion summary", failed, Summary(failed))
+	}
+	if want := "Error: Test assertion failed\n\n  on tests/main.tftest.hcl line 12, in run \"check_name\":\n  12:     condition     = output.name == \"expected\"\n\nName did not match."; failed != want {
+		t.Errorf("run output = %q, want %q", failed, want)
+	}
+	if file, line := Location(failed); file != "tests/main.tftest.hcl" || line != 12 {
+		t.Errorf("Location() = %s:%d, want tests/main.tftest.hcl:12", file, line)
+	}
+	if main.Runs[0].Output != "" || main.Runs[2].Output != "" {
+		t.Errorf("passing and skipped runs should have no output: %+v", main.Runs)
+	}
+
+	other := files[1]
+	if other.Path != "tests/other.tftest.hcl" || other.Status != StatusPass || len(other.Runs) != 1 || other.Output != "" {
+		t.Errorf("other = %+v", other)
+	}
+}
+
+func TestParseTestOutput_Colored(t *testing.T) {
+	colored := "tests/main.tftest.hcl... in progress\n" +
+		"  run \"setup\"... \x1b[32mpass\x1b[0m\n" +
+		"  run \"check_name\"... \x1b[31mfail\x1b[0m\n" +
+		"\x1b[31m╷\x1b[0m\x1b[0m\n\x1b[31m│\x1b[0m \x1b[0m\x1b[1m\x1b[31mError: \x1b[0m\x1b[0m\x1b[1mTest assertion failed\x1b[0m\n\x1b[31m╵\x1b[0m\x1b[0m\n" +
+		"tests/main.tftest.hcl... tearing down\n" +
+		"tests/main.tftest.hcl... \x1b[31mfail\x1b[0m\n" +
+		"\n\x1b[31mFailure!\x1b[0m 1 passed, 1 failed.\n"
+	files := ParseTestOutput(colored)
+	if len(files) != 1 || files[0].Status != StatusFail || len(files[0].Runs) != 2 {
+		t.Fatalf("files = %+v", files)
+	}
+	failed := files[0].Runs[1]
+	if failed.Status != StatusFail || Summary(failed.Output) != "Test assertion failed" {
+		t.Errorf("check_name = %+v", failed)
+	}
+	if report := NewJUnitReport(files); report.Tests != 2 || report.Failures != 1 {
+		t.Errorf("JUnit report = %+v", report)
+	}
+}
+
+func TestParseTestOutput_FileLevelError(t *testing.T) {
+	output := "broken.tftest.hcl... in progress\n\nError: Invalid run block\n\n  on broken.tftest.hcl line 1\nbroken.tftest.hcl... error\n"
+	files := ParseTestOutput(output)
+	if len(files) != 1 || files[0].Status != StatusError || len(files[0].Runs) != 0 {
+		t.Fatalf("files = %+v", files)
+	}
+	if Summary(files[0].Output) != "Invalid run block" {
+		t.Errorf("file output = %q", files[0].Output)
+	}
+}
+
+func TestParseTestOutput_NoTests(t *testing.T) {
+	if files := ParseTestOutput("Success! 0 passed, 0 failed.\n"); len(files) != 0 {
+		t.Errorf("files = %+v, want none", files)
+	}
+}
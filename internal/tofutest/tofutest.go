@@ -1,53 +1,110 @@
 package tofutest
 
 import (
-"os"
-"os/exec"
+"bytes"
+"fmt"
 "path/filepath"
+"sort"
 "strings"
 
+"pre-commit-hooks/internal/discover"
+"pre-commit-hooks/internal/output"
+"pre-commit-hooks/internal/runner"
 "pre-commit-hooks/internal/testutil"
 )
 
 // CheckOpenTofuInstalled delegates to shared testutil implementation.
 var CheckOpenTofuInstalled = testutil.CheckOpenTofuInstalled
 
+// TestFileSuffix is the extension of OpenTofu test files.
+const TestFileSuffix = ".tftest.hcl"
+
+// FindTestFiles recursively searches for test files in the given directory,
+// skipping hidden directories and the paths left out by opts or .tofuignore
+// files. The result lists the skipped paths.
+func FindTestFiles(rootDir string, opts discover.Options) (discover.Result, error) {
+return discover.Walk(rootDir, opts, func(name string) bool {
+return strings.HasSuffix(name, TestFileSuffix)
+})
+}
+
 // HasTestFiles recursively searches for .tftest.hcl files in the given directory.
 // Returns true if any test files are found, false otherwise.
 func HasTestFiles(rootDir string) (bool, error) {
-found := false
-err := filepath.Walk(rootDir, func(path string, info os.FileInfo, err error) error {
-if err != nil {
-return err
+found, err := FindTestFiles(rootDir, discover.Options{})
+return len(found.Files) > 0, err
+}
+
+// DefaultTestDirectory is where tofu test looks for test files besides the
+// module directory itself, unless -test-directory says otherwise.
+const DefaultTestDirectory = "tests"
+
+// Module is a module directory and the test files it owns.
+type Module struct {
+Dir string
+// Files are the module's test files, relative to Dir with forward slashes.
+Files []string
 }
 
-// Skip hidden directories and .terraform
-if info.IsDir() {
-name := info.Name()
-if strings.HasPrefix(name, ".") || name == ".terraform" {
-return filepath.SkipDir
+// Modules groups test files by the module directory that owns them: the
+// directory holding the file or, for a file in a testDir directory (such as
+// "tests"), the directory above it. Modules are sorted by directory.
+func Modules(files []string, testDir string) []Module {
+testDir = filepath.Clean(filepath.FromSlash(testDir))
+index := map[string]int{}
+var modules []Module
+for _, file := range files {
+dir := filepath.Dir(file)
+if owner, ok := strings.CutSuffix(dir, string(filepath.Separator)+testDir); ok {
+dir = owner
 }
-return nil
+rel, err := filepath.Rel(dir, file)
+if err != nil {
+rel = filepath.Base(file)
+}
+i, ok := index[dir]
+if !ok {
+i = len(modules)
+index[dir] = i
+modules = append(modules, Module{Dir: dir})
+}
+modules[i].Files = append(modules[i].Files, filepath.ToSlash(rel))
+}
+sort.Slice(modules, func(a, b int) bool { return modules[a].Dir < modules[b].Dir })
+return modules
 }
 
-// Check if file has .tftest.hcl extension
-if strings.HasSuffix(info.Name(), ".tftest.hcl") {
-found = true
-return filepath.SkipAll // Stop searching once we find one
+// TestResult is the outcome of tofu test -json in one module.
+type TestResult struct {
+// Events are the decoded messages, in the order tofu printed them.
+Events []Event
+// Files are the results per test file built from Events.
+Files []FileResult
+// Diagnostics were reported outside any test file, such as errors loading
+// the configuration.
+Diagnostics []output.Diagnostic
+// Output is the raw stdout and stderr of the command.
+Output string
 }
 
-return nil
-})
+// RunTofuTest runs tofu test -json in the given directory with extra args.
+// env entries (KEY=value) are added to the inherited environment.
+// Returns the decoded result and error. When the output cannot be decoded the
+// error says so and only the raw Output of the result is set.
+func RunTofuTest(dir string, env []string, extraArgs []string) (TestResult, error) {
+args := append([]string{"test", "-json"}, extraArgs...)
+var stdout, stderr bytes.Buffer
+runErr := runner.Step{Name: "test", Dir: dir, Env: env, Args: args}.Run(&stdout, &stderr)
 
-return found, err
+result := TestResult{Output: stdout.String() + stderr.String()}
+events, err := ParseTestJSON(stdout.Bytes())
+if err != nil {
+if runErr != nil {
+return result, fmt.Errorf("%w (%v)", runErr, err)
 }
-
-// RunTofuTest runs tofu test in the given directory with extra args.
-// Returns output and error.
-func RunTofuTest(dir string, extraArgs []string) (string, error) {
-args := append([]string{"test"}, extraArgs...)
-cmd := exec.Command("tofu", args...)
-cmd.Dir = dir
-output, err := cmd.CombinedOutput()
-return string(output), err
+return result, err
+}
+result.Events = events
+result.Files, result.Diagnostics = BuildResults(events)
+return result, runErr
 }
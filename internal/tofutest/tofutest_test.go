@@ -4,8 +4,10 @@ import (
 "os"
 "os/exec"
 "path/filepath"
+"reflect"
 "testing"
 
+"pre-commit-hooks/internal/discover"
 "pre-commit-hooks/internal/testutil"
 )
 
@@ -111,6 +113,61 @@ t.Error("HasTestFiles() = true, want false when test files only in hidden direct
 }
 }
 
+func TestFindTestFiles_IgnoredAndExcluded(t *testing.T) {
+tempDir, cleanup := testutil.CreateTempDir(t, "tofutest_find")
+defer cleanup()
+
+for _, rel := range []string{"tests/main.tftest.hcl", "tests/slow.tftest.hcl", "examples/basic/basic.tftest.hcl", "vendor/mod/mod.tftest.hcl"} {
+path := filepath.Join(tempDir, rel)
+if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+t.Fatalf("Failed to create directory: %v", err)
+}
+if err := os.WriteFile(path, []byte("# test file"), 0644); err != nil {
+t.Fatalf("Failed to create test file: %v", err)
+}
+}
+if err := os.WriteFile(filepath.Join(tempDir, ".tofuignore"), []byte("slow.tftest.hcl\n/examples/\n"), 0644); err != nil {
+t.Fatalf("Failed to create ignore file: %v", err)
+}
+
+found, err := FindTestFiles(tempDir, discover.Options{Exclude: []string{"vendor/**"}})
+if err != nil {
+t.Fatalf("FindTestFiles() returned error: %v", err)
+}
+if len(found.Files) != 1 || found.Files[0] != filepath.Join(tempDir, "tests", "main.tftest.hcl") {
+t.Errorf("FindTestFiles() files = %v, want only tests/main.tftest.hcl", found.Files)
+}
+if len(found.Skipped) != 3 {
+t.Errorf("FindTestFiles() skipped = %v, want examples, tests/slow.tftest.hcl and vendor", found.Skipped)
+}
+}
+
+func TestModules(t *testing.T) {
+root := filepath.FromSlash("/repo")
+files := []string{
+filepath.Join(root, "tests", "main.tftest.hcl"),
+filepath.Join(root, "modules", "net", "net.tftest.hcl"),
+filepath.Join(root, "modules", "net", "tests", "routes.tftest.hcl"),
+filepath.Join(root, "main.tftest.hcl"),
+filepath.Join(root, "modules", "dns", "unit", "dns.tftest.hcl"),
+}
+
+got := Modules(files, DefaultTestDirectory)
+want := []Module{
+{Dir: root, Files: []string{"tests/main.tftest.hcl", "main.tftest.hcl"}},
+{Dir: filepath.Join(root, "modules", "dns", "unit"), Files: []string{"dns.tftest.hcl"}},
+{Dir: filepath.Join(root, "modules", "net"), Files: []string{"net.tftest.hcl", "tests/routes.tftest.hcl"}},
+}
+if !reflect.DeepEqual(got, want) {
+t.Errorf("Modules() = %+v, want %+v", got, want)
+}
+
+got = Modules(files[4:], "unit")
+if len(got) != 1 || got[0].Dir != filepath.Join(root, "modules", "dns") || got[0].Files[0] != "unit/dns.tftest.hcl" {
+t.Errorf("Modules() with -test-directory=unit = %+v", got)
+}
+}
+
 func TestRunTofuTest_NoTestFiles(t *testing.T) {
 testutil.SkipIfTofuNotInstalled(t)
 tempDir, cleanup := testutil.CreateTempDir(t, "tofutest_run_no_files")
@@ -128,10 +185,10 @@ t.Fatalf("Failed to write test file: %v", err)
 }
 
 // Running tofu test with no test files should still work (it will report no tests found)
-output, err := RunTofuTest(tempDir, nil)
+result, err := RunTofuTest(tempDir, nil, nil)
 // tofu test exits with code 0 even when no tests are found
 if err != nil {
-t.Logf("tofu test output: %s", output)
+t.Logf("tofu test output: %s", result.Output)
 // This is expected behavior - tofu test may error if no tests found
 // Don't fail the test
 }
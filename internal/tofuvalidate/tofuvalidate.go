@@ -1,20 +1,52 @@
 package tofuvalidate
 
 import (
-	"os/exec"
+	"bytes"
+	"encoding/json"
+	"fmt"
 
+	"pre-commit-hooks/internal/output"
+	"pre-commit-hooks/internal/runner"
 	"pre-commit-hooks/internal/testutil"
 )
 
 // CheckOpenTofuInstalled delegates to shared testutil implementation.
 var CheckOpenTofuInstalled = testutil.CheckOpenTofuInstalled
 
-// RunTofuValidate runs tofu validate in the given directory with extra args.
-// Returns output and error.
-func RunTofuValidate(dir string, extraArgs []string) (string, error) {
-	args := append([]string{"validate"}, extraArgs...)
-	cmd := exec.Command("tofu", args...)
-	cmd.Dir = dir
-	output, err := cmd.CombinedOutput()
-	return string(output), err
+// ValidateResult is the document printed by tofu validate -json.
+type ValidateResult struct {
+	FormatVersion string              `json:"format_version"`
+	Valid         bool                `json:"valid"`
+	ErrorCount    int                 `json:"error_count"`
+	WarningCount  int                 `json:"warning_count"`
+	Diagnostics   []output.Diagnostic `json:"diagnostics"`
+	// Output is the raw stdout and stderr of the command.
+	Output string `json:"-"`
+}
+
+// RunTofuValidate runs tofu validate -json in the given directory with extra args.
+// env entries (KEY=value) are added to the inherited environment.
+// Returns the decoded result and error. When the output cannot be decoded
+// only the raw Output of the result is set, for callers to fall back to the
+// text; the error is then only set when the command failed, and says so.
+func RunTofuValidate(dir string, env []string, extraArgs []string) (ValidateResult, error) {
+	args := append([]string{"validate", "-json"}, extraArgs...)
+	var stdout, stderr bytes.Buffer
+	runErr := runner.Step{Name: "validate", Dir: dir, Env: env, Args: args}.Run(&stdout, &stderr)
+
+	result, err := ParseValidateJSON(stdout.Bytes())
+	result.Output = stdout.String() + stderr.String()
+	if err != nil && runErr != nil {
+		return result, fmt.Errorf("%w (%v)", runErr, err)
+	}
+	return result, runErr
+}
+
+// ParseValidateJSON decodes the output of tofu validate -json.
+func ParseValidateJSON(data []byte) (ValidateResult, error) {
+	var result ValidateResult
+	if err := json.Unmarshal(data, &result); err != nil {
+		return ValidateResult{}, fmt.Errorf("could not decode tofu validate -json output: %w", err)
+	}
+	return result, nil
 }
@@ -4,9 +4,14 @@ import (
 	"os"
 	"os/exec"
 	"path/filepath"
+	"reflect"
+	"runtime"
+	"strings"
 	"testing"
 
+	"pre-commit-hooks/internal/output"
 	"pre-commit-hooks/internal/testutil"
+	"pre-commit-hooks/internal/tofubin"
 )
 
 func TestCheckOpenTofuInstalled(t *testing.T) {
@@ -50,9 +55,12 @@ resource "null_resource" "example" {}`
 		t.Fatalf("Expected tofu init to succeed for valid config, but got error: %v, output: %s", err, out)
 	}
 
-	output, err := RunTofuValidate(tempDir, nil)
+	result, err := RunTofuValidate(tempDir, nil, nil)
 	if err != nil {
-		t.Fatalf("Expected valid config to pass validation, but got error: %v, output: %s", err, output)
+		t.Fatalf("Expected valid config to pass validation, but got error: %v, output: %s", err, result.Output)
+	}
+	if !result.Valid {
+		t.Errorf("Expected result to be valid, got: %+v", result)
 	}
 }
 
@@ -79,8 +87,90 @@ func TestRunTofuValidate_InvalidConfig(t *testing.T) {
 	}
 	// If tofu init fails as expected, test passes
 
-	output, err := RunTofuValidate(tempDir, nil)
+	result, err := RunTofuValidate(tempDir, nil, nil)
 	if err == nil {
-		t.Fatalf("Expected invalid config to fail validation, but got no error. Output: %s", output)
+		t.Fatalf("Expected invalid config to fail validation, but got no error. Output: %s", result.Output)
+	}
+	if len(output.FilterDiagnostics(result.Diagnostics, output.SeverityError)) == 0 {
+		t.Errorf("Expected error diagnostics, got: %+v", result)
+	}
+}
+
+func TestRunTofuValidate_UndecodedOutput(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses a shell script as the fake binary")
+	}
+	path := filepath.Join(t.TempDir(), "tofu")
+	script := "#!/bin/sh\necho 'Success! The configuration is valid.'\nexit \"${FAKE_EXIT:-0}\"\n"
+	if err := os.WriteFile(path, []byte(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+	defer tofubin.Set(tofubin.Set(tofubin.Binary{Name: "tofu", Path: path}))
+
+	// A successful run whose output is not JSON falls back to the text
+	result, err := RunTofuValidate(t.TempDir(), nil, nil)
+	if err != nil || result.FormatVersion != "" || !strings.Contains(result.Output, "Success!") {
+		t.Errorf("RunTofuValidate() = %+v, %v; want the text output and no error", result, err)
+	}
+
+	result, err = RunTofuValidate(t.TempDir(), []string{"FAKE_EXIT=1"}, nil)
+	if err == nil || !strings.Contains(err.Error(), "could not decode") {
+		t.Errorf("RunTofuValidate() error = %v, want the exit and decode errors", err)
+	}
+}
+
+func TestParseValidateJSON(t *testing.T) {
+	data := []byte(`{
+  "format_version": "1.0",
+  "valid": false,
+  "error_count": 1,
+  "warning_count": 1,
+  "diagnostics": [
+    {
+      "severity": "warning",
+      "summary": "Deprecated attribute",
+      "detail": "Use something else.",
+      "range": {
+        "filename": "main.tf",
+        "start": {"line": 4, "column": 3, "byte": 40},
+        "end": {"line": 4, "column": 12, "byte": 49}
+      }
+    },
+    {
+      "severity": "error",
+      "summary": "Unsupported argument",
+      "detail": "An argument named \"foo\" is not expected here.",
+      "range": {
+        "filename": "modules/net/main.tf",
+        "start": {"line": 2, "column": 1, "byte": 10},
+        "end": {"line": 2, "column": 4, "byte": 13}
+      },
+      "snippet": {"context": "resource \"x\" \"y\"", "code": "foo = 1"}
+    }
+  ]
+}`)
+	result, err := ParseValidateJSON(data)
+	if err != nil {
+		t.Fatalf("ParseValidateJSON() error: %v", err)
+	}
+	if result.Valid || result.ErrorCount != 1 || result.WarningCount != 1 || len(result.Diagnostics) != 2 {
+		t.Fatalf("ParseValidateJSON() = %+v", result)
+	}
+	want := output.Diagnostic{
+		Severity: "error",
+		Summary:  "Unsupported argument",
+		Detail:   "An argument named \"foo\" is not expected here.",
+		Range: &output.Range{
+			Filename: "modules/net/main.tf",
+			Start:    output.Pos{Line: 2, Column: 1, Byte: 10},
+			End:      output.Pos{Line: 2, Column: 4, Byte: 13},
+		},
+	}
+	if !reflect.DeepEqual(result.Diagnostics[1], want) {
+		t.Errorf("Diagnostics[1] = %+v, want %+v", result.Diagnostics[1], want)
+	}
+
+	if _, err := ParseValidateJSON([]byte("Error: not json")); err == nil {
+		t.Error("Expected error for non-JSON output")
 	}
 }
@@ -0,0 +1,95 @@
+// Package tofuversion checks that the installed tofu or terraform satisfies
+// the version constraints of the repository.
+package tofuversion
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"pre-commit-hooks/internal/modules"
+	"pre-commit-hooks/internal/semver"
+	"pre-commit-hooks/internal/tofubin"
+)
+
+// Flag names the hook flag holding an explicit version constraint.
+const Flag = "tofu-version"
+
+// versionOf probes a binary's version; tests replace it.
+var versionOf = tofubin.Binary.Version
+
+// requirement is a constraint and where it came from
+type requirement struct {
+	constraint semver.Constraint
+	source     string
+}
+
+// Error reports an installed version that does not satisfy a constraint.
+type Error struct {
+	Binary     tofubin.Binary
+	Installed  string
+	Constraint string
+	// Source is where the constraint came from: the flag or a module directory.
+	Source string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("%s %s is installed, but %s requires %s", e.Binary.DisplayName(), e.Installed, e.Source, e.Constraint)
+}
+
+// Check checks the current binary against the explicit constraint, if set,
+// and the required_version of every module in dirs. The binary is only
+// probed when there is something to check.
+func Check(constraint string, dirs []string) error {
+	var reqs []requirement
+	if constraint != "" {
+		c, err := semver.ParseConstraint(constraint)
+		if err != nil {
+			return fmt.Errorf("--%s: %w", Flag, err)
+		}
+		reqs = append(reqs, requirement{c, "--" + Flag})
+	}
+	for _, dir := range dirs {
+		found, err := modules.RequiredVersions(dir)
+		if err != nil {
+			continue
+		}
+		for _, raw := range found {
+			c, err := semver.ParseConstraint(raw)
+			if err != nil {
+				return fmt.Errorf("required_version in %s: %w", displayDir(dir), err)
+			}
+			reqs = append(reqs, requirement{c, displayDir(dir)})
+		}
+	}
+	if len(reqs) == 0 {
+		return nil
+	}
+
+	raw, err := versionOf(tofubin.Current())
+	if err != nil {
+		return fmt.Errorf("could not determine the %s version: %w", tofubin.Current().DisplayName(), err)
+	}
+	installed, err := semver.Parse(raw)
+	if err != nil {
+		return fmt.Errorf("could not determine the %s version: %w", tofubin.Current().DisplayName(), err)
+	}
+	for _, req := range reqs {
+		if !req.constraint.Check(installed) {
+			return &Error{Binary: tofubin.Current(), Installed: raw, Constraint: req.constraint.String(), Source: req.source}
+		}
+	}
+	return nil
+}
+
+// displayDir returns dir relative to the working directory when possible
+func displayDir(dir string) string {
+	wd, err := os.Getwd()
+	if err != nil {
+		return dir
+	}
+	if rel, err := filepath.Rel(wd, dir); err == nil && filepath.IsLocal(rel) {
+		return rel
+	}
+	return dir
+}
@@ -0,0 +1,86 @@
+package tofuversion
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"pre-commit-hooks/internal/tofubin"
+)
+
+// fakeVersion makes versionOf report version, or fail when version is empty
+func fakeVersion(t *testing.T, version string) *int {
+	t.Helper()
+	calls := 0
+	previous := versionOf
+	versionOf = func(tofubin.Binary) (string, error) {
+		calls++
+		if version == "" {
+			return "", errors.New("probe failed")
+		}
+		return version, nil
+	}
+	t.Cleanup(func() { versionOf = previous })
+	return &calls
+}
+
+func TestCheck(t *testing.T) {
+	defer tofubin.Set(tofubin.Set(tofubin.Binary{Name: "tofu", Path: "tofu"}))
+	module := t.TempDir()
+	if err := os.WriteFile(filepath.Join(module, "versions.tf"), []byte("terraform {\n  required_version = \">= 1.6.0\"\n}\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	plain := t.TempDir()
+
+	t.Run("nothing to check skips the probe", func(t *testing.T) {
+		calls := fakeVersion(t, "")
+		if err := Check("", []string{plain, filepath.Join(plain, "missing")}); err != nil || *calls != 0 {
+			t.Errorf("Check() = %v with %d probes, want nil without probing", err, *calls)
+		}
+	})
+
+	t.Run("required_version satisfied", func(t *testing.T) {
+		fakeVersion(t, "1.8.2")
+		if err := Check("", []string{module}); err != nil {
+			t.Errorf("Check() = %v, want nil", err)
+		}
+	})
+
+	t.Run("required_version not satisfied", func(t *testing.T) {
+		fakeVersion(t, "1.5.7")
+		err := Check("", []string{plain, module})
+		var checkErr *Error
+		if !errors.As(err, &checkErr) {
+			t.Fatalf("Check() = %v, want *Error", err)
+		}
+		want := "OpenTofu 1.5.7 is installed, but " + module + " requires >= 1.6.0"
+		if err.Error() != want {
+			t.Errorf("Error() = %q, want %q", err.Error(), want)
+		}
+	})
+
+	t.Run("explicit constraint", func(t *testing.T) {
+		fakeVersion(t, "1.8.2")
+		err := Check("~> 1.9", []string{module})
+		if err == nil || !strings.Contains(err.Error(), "--tofu-version requires ~> 1.9") {
+			t.Errorf("Check() = %v, want flag constraint failure", err)
+		}
+	})
+
+	t.Run("invalid constraint", func(t *testing.T) {
+		fakeVersion(t, "1.8.2")
+		if err := Check(">= banana", nil); err == nil {
+			t.Error("Check() expected error for invalid constraint")
+		}
+	})
+
+	t.Run("probe failure", func(t *testing.T) {
+		fakeVersion(t, "")
+		err := Check("", []string{module})
+		if err == nil || !strings.Contains(err.Error(), "could not determine the OpenTofu version") {
+			t.Errorf("Check() = %v, want probe failure", err)
+		}
+	})
+}